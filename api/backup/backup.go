@@ -0,0 +1,221 @@
+// Package backup writes models.Backup documents as a zip of CSV files, one per
+// table, and reads them back.
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"dinero/api/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// manifestName is the file in a zip holding everything about a backup that
+// isn't a table
+const manifestName = "manifest.json"
+
+// MaxFileSize is the most a file in a backup zip can hold once uncompressed, so
+// a small zip can't expand to fill memory
+const MaxFileSize = 256 << 20
+
+var (
+	// ErrMalformed is returned for zips that aren't a backup written by WriteZip
+	ErrMalformed = errors.New("backup: malformed backup")
+	// ErrTooLarge is returned for zips holding a file larger than MaxFileSize
+	ErrTooLarge = errors.New("backup: a file in the backup is too large")
+)
+
+// table is how one table of a Backup is written as CSV. Columns are named after
+// the JSON fields of the table's records, and numeric columns are written as JSON
+// numbers rather than strings.
+type table struct {
+	name    string
+	columns []string
+	numeric map[string]bool
+}
+
+var (
+	users = table{
+		name:    "users.csv",
+		columns: []string{"ID", "firstName", "lastName", "fullName", "email", "biweeklyIncome", "currency", "payday"},
+		numeric: map[string]bool{"ID": true, "biweeklyIncome": true},
+	}
+	accounts = table{
+		name:    "accounts.csv",
+		columns: []string{"ID", "userID", "name", "accountType", "minimumPayment", "currentPayment", "fullAmount", "currency", "apr", "dueDate", "anchorDate", "URL"},
+		numeric: map[string]bool{"ID": true, "userID": true, "minimumPayment": true, "currentPayment": true, "fullAmount": true, "apr": true},
+	}
+	transactions = table{
+		name:    "transactions.csv",
+		columns: []string{"ID", "accountID", "amount", "postedDate", "payee", "memo", "status", "importID"},
+		numeric: map[string]bool{"ID": true, "accountID": true, "amount": true},
+	}
+)
+
+// manifest is the part of a Backup that isn't a table
+type manifest struct {
+	Version    int    `json:"version"`
+	ExportedAt string `json:"exportedAt"`
+}
+
+// WriteZip writes a Backup to w as a zip of CSV files
+func WriteZip(w io.Writer, b *models.Backup) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(file).Encode(manifest{Version: b.Version, ExportedAt: b.ExportedAt}); err != nil {
+		return err
+	}
+
+	if err = users.write(archive, b.Users); err != nil {
+		return err
+	}
+	if err = accounts.write(archive, b.Accounts); err != nil {
+		return err
+	}
+	if err = transactions.write(archive, b.Transactions); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// write writes records, a slice of one of the models, as the table's CSV file
+func (t table) write(archive *zip.Writer, records interface{}) error {
+	// Going through JSON writes every field the way the API does
+	recordsJSON, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]json.RawMessage
+	if err = json.Unmarshal(recordsJSON, &rows); err != nil {
+		return err
+	}
+
+	file, err := archive.Create(t.name)
+	if err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(file)
+	out.Write(t.columns)
+	for _, row := range rows {
+		cells := make([]string, len(t.columns))
+		for i, column := range t.columns {
+			var s string
+			if json.Unmarshal(row[column], &s) != nil {
+				s = string(row[column])
+			}
+			cells[i] = s
+		}
+		out.Write(cells)
+	}
+	out.Flush()
+
+	return out.Error()
+}
+
+// ReadZip reads a Backup written by WriteZip. The Backup isn't validated.
+func ReadZip(data []byte) (*models.Backup, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var m manifest
+	if err = readFile(files[manifestName], func(data []byte) error { return json.Unmarshal(data, &m) }); err != nil {
+		return nil, err
+	}
+
+	b := &models.Backup{Version: m.Version, ExportedAt: m.ExportedAt}
+	if err = users.read(files, &b.Users); err != nil {
+		return nil, err
+	}
+	if err = accounts.read(files, &b.Accounts); err != nil {
+		return nil, err
+	}
+	if err = transactions.read(files, &b.Transactions); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// readFile reads a file out of a zip and hands its contents to decode. Files
+// over MaxFileSize are refused by the size in their header, and cut off at it in
+// case the header understates it.
+func readFile(file *zip.File, decode func([]byte) error) error {
+	if file == nil {
+		return ErrMalformed
+	}
+	if file.UncompressedSize64 > MaxFileSize {
+		return ErrTooLarge
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return ErrMalformed
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, MaxFileSize+1))
+	if err != nil {
+		return ErrMalformed
+	}
+	if len(data) > MaxFileSize {
+		return ErrTooLarge
+	}
+	if decode(data) != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// read reads the table's CSV file into records, a pointer to a slice of one of the models
+func (t table) read(files map[string]*zip.File, records interface{}) error {
+	return readFile(files[t.name], func(data []byte) error {
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil || len(rows) == 0 {
+			return ErrMalformed
+		}
+
+		// Rebuild the JSON the table was written from, so the models parse it
+		header := rows[0]
+		objects := make([]string, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			fields := make([]string, 0, len(row))
+			for i, cell := range row {
+				if i >= len(header) {
+					return ErrMalformed
+				}
+				name, _ := json.Marshal(header[i])
+				value, _ := json.Marshal(cell)
+				if t.numeric[header[i]] {
+					// Checking it's a number keeps a cell from adding JSON of its own
+					if _, err := strconv.ParseFloat(cell, 64); err != nil {
+						return ErrMalformed
+					}
+					value = []byte(cell)
+				}
+				fields = append(fields, string(name)+":"+string(value))
+			}
+			objects = append(objects, "{"+strings.Join(fields, ",")+"}")
+		}
+
+		return json.Unmarshal([]byte("["+strings.Join(objects, ",")+"]"), records)
+	})
+}
@@ -0,0 +1,87 @@
+package backup_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"dinero/api/backup"
+	"dinero/api/models"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	t.Parallel()
+
+	b := &models.Backup{
+		Version:      models.BackupVersion,
+		ExportedAt:   "2020-01-01T00:00:00Z",
+		Users:        []*models.User{{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD", Payday: "2020-01-03"}},
+		Accounts:     []*models.Account{{ID: 3, UserID: 1, Name: "Card, \"Rewards\"", AccountType: "monthly", MinimumPayment: models.NewMoney(2500, "EUR"), CurrentPayment: models.NewMoney(5000, "EUR"), FullAmount: models.NewMoney(123456, "EUR"), Currency: "EUR", APR: 2499, DueDate: "15", URL: "https://example.com"}},
+		Transactions: []*models.Transaction{{ID: 7, AccountID: 3, Amount: models.USD(-1999), PostedDate: "2020-01-04", Payee: "Grocer", Memo: "line one\nline two", Status: models.StatusPending, ImportID: "fitid:A1"}},
+	}
+
+	var archive bytes.Buffer
+	if err := backup.WriteZip(&archive, b); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := backup.ReadZip(archive.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("\nBackup:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", got, b)
+	}
+}
+
+func TestReadZipErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := backup.ReadZip([]byte("not a zip")); err != backup.ErrMalformed {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, backup.ErrMalformed)
+	}
+
+	// A number column holding more than a number
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	files := map[string]string{
+		"manifest.json":    `{"version":1}`,
+		"users.csv":        "ID,email\n\"1,\"\"email\"\":\"\"x\"\"\",a@b.com\n",
+		"accounts.csv":     "ID\n",
+		"transactions.csv": "ID\n",
+	}
+	for name, contents := range files {
+		f, _ := w.Create(name)
+		f.Write([]byte(contents))
+	}
+	w.Close()
+
+	if _, err := backup.ReadZip(archive.Bytes()); err != backup.ErrMalformed {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, backup.ErrMalformed)
+	}
+}
+
+// TestReadZipTooLarge checks a file claiming to be over MaxFileSize is refused
+// before any of it is decompressed
+func TestReadZipTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for _, name := range []string{"manifest.json", "users.csv", "accounts.csv", "transactions.csv"} {
+		f, _ := w.Create(name)
+		f.Write([]byte("{}"))
+	}
+	w.Close()
+
+	// Rewrite the uncompressed size in the manifest's central directory entry
+	data := archive.Bytes()
+	entry := bytes.Index(data, []byte("PK\x01\x02"))
+	binary.LittleEndian.PutUint32(data[entry+24:], backup.MaxFileSize+1)
+
+	if _, err := backup.ReadZip(data); err != backup.ErrTooLarge {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, backup.ErrTooLarge)
+	}
+}
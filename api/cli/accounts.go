@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/models"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// accountTable lays accounts out one per row
+func accountTable(accounts ...*models.Account) table {
+	t := table{header: []string{"ID", "USER", "NAME", "TYPE", "DUE", "FULL AMOUNT", "MINIMUM", "PAYMENT", "APR"}}
+	for _, a := range accounts {
+		t.add(strconv.Itoa(a.ID), strconv.Itoa(a.UserID), a.Name, a.AccountType, a.DueDate,
+			a.FullAmount.String(), a.MinimumPayment.String(), a.CurrentPayment.String(), a.APR.String()+"%")
+	}
+	return t
+}
+
+// accountFlags adds a flag for each field of account to fs
+func accountFlags(fs *flag.FlagSet, account *models.Account) {
+	fs.IntVar(&account.UserID, "user", 0, "ID of the user the account belongs to; the session's user on a server")
+	fs.StringVar(&account.Name, "name", account.Name, "name of the account")
+	fs.StringVar(&account.AccountType, "type", account.AccountType, "how often it's due: daily, weekly, biweekly, monthly or yearly")
+	fs.Var(moneyValue{&account.FullAmount}, "amount", "full amount owed, like 1234.56")
+	fs.Var(moneyValue{&account.MinimumPayment}, "minimum", "minimum payment")
+	fs.Var(moneyValue{&account.CurrentPayment}, "payment", "payment being made")
+	fs.StringVar(&account.Currency, "currency", account.Currency, "ISO 4217 code of the currency the amounts are in, USD by default")
+	fs.Var(rateValue{&account.APR}, "apr", "annual percentage rate, like 24.99")
+	fs.StringVar(&account.DueDate, "due", account.DueDate, "day of the month it's due, from 1 to 31")
+	fs.StringVar(&account.AnchorDate, "anchor", account.AnchorDate, "date of any due date of a weekly or biweekly account, formatted as YYYY-MM-DD")
+	fs.StringVar(&account.URL, "url", account.URL, "where the account is paid")
+}
+
+// listAccounts lists the accounts matching the filter flags
+func listAccounts(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("accounts list")
+	var filter models.AccountFilter
+	var min, max models.Money
+	fs.IntVar(&filter.UserID, "user", 0, "only accounts of the user with this ID")
+	fs.StringVar(&filter.AccountType, "type", "", "only accounts of this type")
+	fs.StringVar(&filter.DueDate, "due", "", "only accounts due on this day of the month")
+	fs.Var(moneyValue{&min}, "min-amount", "only accounts owing at least this much")
+	fs.Var(moneyValue{&max}, "max-amount", "only accounts owing at most this much")
+	opts := listFlags(fs)
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "min-amount":
+			filter.MinFullAmount = &min
+		case "max-amount":
+			filter.MaxFullAmount = &max
+		}
+	})
+
+	accounts, _, err := b.ListAccounts(ctx, filter, opts())
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, accounts, func() table { return accountTable(accounts...) })
+}
+
+// addAccount creates an account
+func addAccount(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("accounts add")
+	account := models.Account{AccountType: "monthly"}
+	accountFlags(fs, &account)
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	account.SetCurrency(account.Currency)
+	created, err := b.CreateAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, created, func() table { return accountTable(created) })
+}
+
+// editAccount changes the fields of an account that are given as flags, failing
+// if someone else changes the account at the same time
+func editAccount(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("accounts edit")
+	var changes models.Account
+	accountFlags(fs, &changes)
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	ids, err := c.ids(fs, fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return c.usageError(fs, "Only one account can be edited at a time")
+	}
+
+	account, err := b.GetAccount(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "user":
+			account.UserID = changes.UserID
+		case "name":
+			account.Name = changes.Name
+		case "type":
+			account.AccountType = changes.AccountType
+		case "amount":
+			account.FullAmount = changes.FullAmount
+		case "minimum":
+			account.MinimumPayment = changes.MinimumPayment
+		case "payment":
+			account.CurrentPayment = changes.CurrentPayment
+		case "currency":
+			account.Currency = changes.Currency
+		case "apr":
+			account.APR = changes.APR
+		case "due":
+			account.DueDate = changes.DueDate
+		case "anchor":
+			account.AnchorDate = changes.AnchorDate
+		case "url":
+			account.URL = changes.URL
+		}
+	})
+	account.SetCurrency(account.Currency)
+
+	if err = b.UpdateAccount(ctx, account); err != nil {
+		return err
+	}
+
+	return c.print(*out, account, func() table { return accountTable(account) })
+}
+
+// removeAccounts deletes accounts
+func removeAccounts(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("accounts rm")
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	ids, err := c.ids(fs, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err = b.DeleteAccount(ctx, id); err != nil {
+			return fmt.Errorf("account %d: %v", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/client"
+	"dinero/api/models"
+	"dinero/api/plan"
+	"dinero/api/schedule"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backend is what commands read and write records through: a database, or a
+// server acting for the user whose session it is
+type backend interface {
+	ListUsers(ctx context.Context, opts models.ListOptions) ([]*models.User, int, error)
+	GetUser(ctx context.Context, userID int) (*models.User, error)
+	CreateUser(ctx context.Context, user models.User, password string) (*models.User, error)
+	DeleteUser(ctx context.Context, userID int) error
+	ListAccounts(ctx context.Context, filter models.AccountFilter, opts models.ListOptions) ([]*models.Account, int, error)
+	GetAccount(ctx context.Context, accountID int) (*models.Account, error)
+	CreateAccount(ctx context.Context, account models.Account) (*models.Account, error)
+	UpdateAccount(ctx context.Context, account *models.Account) error
+	DeleteAccount(ctx context.Context, accountID int) error
+	Export(ctx context.Context, userID int) (*models.Backup, error)
+	Import(ctx context.Context, b *models.Backup, onConflict string) (*models.RestoreReport, error)
+	Plan(ctx context.Context, userID int, from time.Time, count int) (*plan.Plan, error)
+}
+
+// remote is a backend on a Dinero server, which only shows the records of the
+// user whose session the client has
+type remote struct {
+	*client.Client
+}
+
+// Export exports the session's user, whichever userID is asked for
+func (r remote) Export(ctx context.Context, userID int) (*models.Backup, error) {
+	return r.Client.Export(ctx)
+}
+
+// local is a backend working directly on a Store. Nothing is checked against
+// an owner, so every user and account can be read and changed.
+type local struct {
+	store models.Store
+}
+
+func (l local) ListUsers(ctx context.Context, opts models.ListOptions) ([]*models.User, int, error) {
+	return l.store.ListUsers(ctx, models.UserFilter{}, opts)
+}
+
+func (l local) GetUser(ctx context.Context, userID int) (*models.User, error) {
+	return l.store.GetUser(ctx, userID)
+}
+
+func (l local) CreateUser(ctx context.Context, user models.User, password string) (*models.User, error) {
+	if err := user.ValidateRegistration(password); err != nil {
+		return nil, err
+	}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+
+	return l.store.CreateUser(ctx, user)
+}
+
+func (l local) DeleteUser(ctx context.Context, userID int) error {
+	return l.store.DeleteUser(ctx, userID, 0)
+}
+
+func (l local) ListAccounts(ctx context.Context, filter models.AccountFilter, opts models.ListOptions) ([]*models.Account, int, error) {
+	return l.store.ListAccounts(ctx, filter, opts)
+}
+
+func (l local) GetAccount(ctx context.Context, accountID int) (*models.Account, error) {
+	return l.store.GetAccount(ctx, accountID)
+}
+
+func (l local) CreateAccount(ctx context.Context, account models.Account) (*models.Account, error) {
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+
+	return l.store.CreateAccount(ctx, account)
+}
+
+// UpdateAccount replaces an account, conditional on it still being at
+// account.Version when that's non-zero, and then moves account.Version on
+func (l local) UpdateAccount(ctx context.Context, account *models.Account) error {
+	if err := account.Validate(); err != nil {
+		return err
+	}
+
+	if err := l.store.UpdateAccount(ctx, account.ID, account); err != nil {
+		return err
+	}
+	if account.Version > 0 {
+		account.Version++
+	}
+	return nil
+}
+
+func (l local) DeleteAccount(ctx context.Context, accountID int) error {
+	return l.store.DeleteAccount(ctx, accountID, 0)
+}
+
+func (l local) Export(ctx context.Context, userID int) (*models.Backup, error) {
+	return l.store.Export(ctx, userID)
+}
+
+// Import restores a backup, listing the rows that conflict in the error when
+// onConflict is models.ConflictFail and any do
+func (l local) Import(ctx context.Context, b *models.Backup, onConflict string) (*models.RestoreReport, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	report, err := l.store.Restore(ctx, b, onConflict)
+	if err == models.ErrRestoreConflict {
+		conflicts := make([]string, 0, len(report.Conflicts))
+		for _, conflict := range report.Conflicts {
+			conflicts = append(conflicts, conflict.Table+"/"+strconv.Itoa(conflict.ID)+" "+conflict.Reason)
+		}
+		return nil, fmt.Errorf("%v: %s", err, strings.Join(conflicts, ", "))
+	}
+
+	return report, err
+}
+
+// Plan builds a plan the way the server does, moving bills off weekends and US
+// federal holidays
+func (l local) Plan(ctx context.Context, userID int, from time.Time, count int) (*plan.Plan, error) {
+	user, err := l.store.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := l.store.UserAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := schedule.Options{Adjustment: schedule.NextBusinessDay, Holidays: schedule.USFederal}
+	return plan.Build(user, accounts, from, count, opts)
+}
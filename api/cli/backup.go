@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/backup"
+	"dinero/api/models"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// export writes a user's records as JSON, or with -format csv as a zip of CSV
+// files, to stdout or the file named by -o. With -all every user in a database
+// is exported; a server only ever exports the session's user.
+func export(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("export")
+	userID := fs.Int("user", 0, "ID of the user to export; the session's user on a server")
+	all := fs.Bool("all", false, "export every user, when exporting from a database")
+	format := fs.String("format", "json", "json, or csv for a zip of CSV files")
+	path := fs.String("o", "", "file to write the export to instead of stdout")
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	if *format != "json" && *format != "csv" {
+		return c.usageError(fs, "-format must be json or csv")
+	}
+	l, isLocal := b.(local)
+	if *all && !isLocal {
+		return c.usageError(fs, "-all only works when exporting from a database")
+	}
+	if *all && *userID != 0 {
+		return c.usageError(fs, "-user and -all can't be used together")
+	}
+	if isLocal && !*all && *userID < 1 {
+		return c.usageError(fs, "-user or -all is required when exporting from a database")
+	}
+
+	var exported *models.Backup
+	if *all {
+		exported, err = l.store.ExportAll(ctx)
+	} else {
+		exported, err = b.Export(ctx, *userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	if *format == "csv" {
+		err = backup.WriteZip(&data, exported)
+	} else {
+		enc := json.NewEncoder(&data)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(exported)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *path != "" {
+		return ioutil.WriteFile(*path, data.Bytes(), 0600)
+	}
+	_, err = data.WriteTo(c.Stdout)
+	return err
+}
+
+// readBackup reads the JSON document or zip of CSV files export writes
+func readBackup(r io.Reader) (*models.Backup, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return backup.ReadZip(data)
+	}
+
+	b := new(models.Backup)
+	if err = json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// restore restores an export in a single database transaction, and prints how
+// many records it created, updated and left unchanged
+func restore(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("import")
+	onConflict := fs.String("on-conflict", models.ConflictFail, "what to do with rows that already exist and differ: fail to restore nothing, skip them or overwrite them")
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "A file to import is required")
+	}
+
+	in := c.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	restored, err := readBackup(in)
+	if err != nil {
+		return err
+	}
+
+	report, err := b.Import(ctx, restored, *onConflict)
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, report, func() table {
+		t := table{header: []string{"", "USERS", "ACCOUNTS", "TRANSACTIONS"}}
+		for _, row := range []struct {
+			name   string
+			counts models.RestoreCounts
+		}{
+			{"created", report.Created},
+			{"updated", report.Updated},
+			{"unchanged", report.Unchanged},
+		} {
+			t.add(row.name, strconv.Itoa(row.counts.Users), strconv.Itoa(row.counts.Accounts), strconv.Itoa(row.counts.Transactions))
+		}
+		return t
+	})
+}
@@ -0,0 +1,253 @@
+// Package cli is the dinero command: it serves the API, and administers Dinero
+// either directly on its database or through a running server.
+package cli
+
+import (
+	"context"
+	"dinero/api/client"
+	"dinero/api/config"
+	"dinero/api/models"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const usage = `Usage:
+  dinero [command] [flags] [arguments]
+
+Commands:
+%s
+Without a command, dinero serves the API.
+
+Commands that work on records use the database named by the settings, or with
+-server or DINERO_SERVER a running Dinero server, logged in with the session
+token in -token or DINERO_TOKEN. Settings are read from, in increasing priority,
+a TOML file named by -config or DINERO_CONFIG, DINERO_* environment variables
+such as DINERO_LOG_LEVEL, and flags.
+
+Run "dinero [command] -h" for the flags of a command.
+`
+
+// Console is what commands read from and write to, so they can run without a terminal
+type Console struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Getenv func(string) string
+
+	// usageShown is set once a usage message has been written, so a mistake on
+	// the command line isn't reported twice
+	usageShown bool
+}
+
+// command is a dinero subcommand
+type command struct {
+	// name is the words that run the command, like "accounts edit"
+	name string
+	// args describes the arguments after the flags
+	args    string
+	summary string
+	run     func(ctx context.Context, c *Console, args []string) error
+}
+
+// commands are listed in the order usage shows them
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "serve", summary: "serve the API", run: serve},
+		{name: "config print", summary: "print the effective configuration as TOML", run: printConfig},
+		{name: "migrate", summary: "migrate the database to the latest schema", run: migrate},
+		{name: "login", summary: "log in to a server and print the session token", run: login},
+		{name: "users list", summary: "list users", run: listUsers},
+		{name: "users add", summary: "register a user", run: addUser},
+		{name: "users rm", args: "ID...", summary: "delete users", run: removeUsers},
+		{name: "accounts list", summary: "list accounts", run: listAccounts},
+		{name: "accounts add", summary: "create an account", run: addAccount},
+		{name: "accounts edit", args: "ID", summary: "change an account", run: editAccount},
+		{name: "accounts rm", args: "ID...", summary: "delete accounts", run: removeAccounts},
+		{name: "export", summary: "export a user's records, or with -all every user's, as JSON or a zip of CSV files", run: export},
+		{name: "import", args: "FILE", summary: "restore an export, read from stdin when FILE is -", run: restore},
+		{name: "plan", summary: "plan a user's upcoming paychecks and the bills they cover", run: showPlan},
+		{name: "tui", summary: "browse users, accounts and due dates full-screen, and edit accounts", run: dashboard},
+	}
+}
+
+// find returns the command args start with and the arguments after its name
+func find(args []string) (*command, []string) {
+	// Flags without a command are for serve
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return &commands[0], args
+	}
+
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):]
+		}
+	}
+
+	return nil, args
+}
+
+// Run runs the dinero command line args, not including the program name, and
+// returns the status to exit with: 0 on success, 1 when the command fails and
+// 2 when the command line is wrong
+func Run(ctx context.Context, c *Console, args []string) int {
+	cmd, rest := find(args)
+	if cmd == nil {
+		if len(args) > 0 && args[0] != "help" {
+			fmt.Fprintf(c.Stderr, "dinero: unknown command %q\n\n", strings.Join(args, " "))
+		}
+		c.printUsage()
+		if len(args) > 0 && args[0] == "help" {
+			return 0
+		}
+		return 2
+	}
+
+	err := cmd.run(ctx, c, rest)
+	switch {
+	case err == nil:
+		return 0
+	case err == flag.ErrHelp:
+		return 0
+	case c.usageShown:
+		return 2
+	}
+
+	fmt.Fprintf(c.Stderr, "dinero: %v\n", err)
+	return 1
+}
+
+// printUsage lists the commands
+func (c *Console) printUsage() {
+	var list strings.Builder
+	for _, cmd := range commands {
+		fmt.Fprintf(&list, "  %-18s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	fmt.Fprintf(c.Stderr, usage, list.String())
+}
+
+// flags returns the flag set of the command called name
+func (c *Console) flags(name string) *flag.FlagSet {
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+
+	fs := flag.NewFlagSet("dinero "+name, flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.Usage = func() {
+		c.usageShown = true
+		line := strings.TrimSpace("dinero " + name + " [flags] " + cmd.args)
+		fmt.Fprintf(c.Stderr, "Usage:\n  %s\n\n%s.\n\nFlags:\n", line, strings.ToUpper(cmd.summary[:1])+cmd.summary[1:])
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// errUsage is returned for a mistake on the command line, once the usage has been shown
+var errUsage = errors.New("usage")
+
+// usageError reports a mistake on the command line of fs along with its usage
+func (c *Console) usageError(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fmt.Fprintf(c.Stderr, format+"\n", args...)
+	fs.Usage()
+	return errUsage
+}
+
+// databaseSettings are the settings taken as flags by commands that only open
+// the database. The rest matter to serve alone, though they're still read from
+// the file and environment and have to be valid.
+var databaseSettings = []string{"config", "db", "user-delete-policy", "reassign-to"}
+
+// databaseFlags adds the flags of databaseSettings to fs, returning a func that
+// loads the configuration once fs is parsed
+func (c *Console) databaseFlags(fs *flag.FlagSet) func() (*config.Config, error) {
+	all := flag.NewFlagSet("", flag.ContinueOnError)
+	config.Load(all, nil, func(string) string { return "" })
+	for _, name := range databaseSettings {
+		f := all.Lookup(name)
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+
+	return func() (*config.Config, error) {
+		var args []string
+		fs.Visit(func(f *flag.Flag) {
+			for _, name := range databaseSettings {
+				if f.Name == name {
+					args = append(args, "-"+name+"="+f.Value.String())
+				}
+			}
+		})
+		return config.Load(flag.NewFlagSet(fs.Name(), flag.ContinueOnError), args, c.Getenv)
+	}
+}
+
+// connect parses the command line and returns the backend the command works on:
+// the server named by -server or DINERO_SERVER, or else the database in the
+// settings. The returned func closes the backend.
+func (c *Console) connect(fs *flag.FlagSet, args []string) (backend, func() error, error) {
+	server := fs.String("server", "", "URL of a Dinero server to use instead of the database; also read from DINERO_SERVER")
+	token := fs.String("token", "", "session token for the server, as printed by dinero login; also read from DINERO_TOKEN")
+	load := c.databaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *server == "" {
+		*server = c.Getenv("DINERO_SERVER")
+	}
+	if *token == "" {
+		*token = c.Getenv("DINERO_TOKEN")
+	}
+	if *server != "" {
+		cl := client.New(*server)
+		cl.Token = *token
+		return remote{cl}, func() error { return nil }, nil
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := cfg.DeletePolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := models.InitDB(cfg.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.UserDeletePolicy = policy
+
+	return local{db}, db.Close, nil
+}
+
+// ids parses the record IDs on a command line
+func (c *Console) ids(fs *flag.FlagSet, args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, c.usageError(fs, "An ID is required")
+	}
+
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id < 1 {
+			return nil, c.usageError(fs, "%q is not an ID", arg)
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,284 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/cli"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCase is one dinero command line and what it should print
+type TestCase struct {
+	name           string
+	args           []string
+	stdin          string
+	expectedCode   int
+	expectedStdout string
+	// expectedStderr is a part of what's printed to stderr
+	expectedStderr string
+}
+
+// run runs the test cases in order with env as the environment
+func run(t *testing.T, tests []TestCase, env map[string]string) {
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		console := &cli.Console{
+			Stdin:  strings.NewReader(test.stdin),
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Getenv: func(key string) string { return env[key] },
+		}
+
+		code := cli.Run(context.Background(), console, test.args)
+		if code != test.expectedCode {
+			t.Errorf("\n%s\nCode:\n\tGot: \t\t%d\n\tExpected: \t%d\n\tStderr: \t%s\n", test.name, code, test.expectedCode, stderr.String())
+		}
+		if test.expectedStdout != "" && stdout.String() != test.expectedStdout {
+			t.Errorf("\n%s\nStdout:\n\tGot: \t\t%q\n\tExpected: \t%q\n", test.name, stdout.String(), test.expectedStdout)
+		}
+		if !strings.Contains(stderr.String(), test.expectedStderr) {
+			t.Errorf("\n%s\nStderr:\n\tGot: \t\t%q\n\tExpected: \t%q in it\n", test.name, stderr.String(), test.expectedStderr)
+		}
+	}
+}
+
+// tempDir creates a temporary directory for a test to remove when it ends
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "dinero-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDatabase(t *testing.T) {
+	t.Parallel()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	export := filepath.Join(dir, "export.json")
+	exportAll := filepath.Join(dir, "export-all.json")
+	env := map[string]string{"DINERO_DB": filepath.Join(dir, "dinero.db"), "DINERO_PASSWORD": "correct horse"}
+
+	db, err := models.OpenDB(filepath.Join(dir, "latest.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := db.Migrator().Latest()
+	db.Close()
+
+	tests := []TestCase{
+		{
+			name:           "MIGRATE",
+			args:           []string{"migrate"},
+			expectedStdout: fmt.Sprintf("Migrated from version 0 to %d\n", latest),
+		},
+		{
+			name:           "MIGRATE_AGAIN",
+			args:           []string{"migrate"},
+			expectedStdout: fmt.Sprintf("Already at version %d\n", latest),
+		},
+		{
+			name:           "ADD_USER",
+			args:           []string{"users", "add", "-first", "Luke", "-last", "Toth", "-email", "lptoth55@gmail.com", "-income", "1400", "-payday", "2020-01-03"},
+			expectedStdout: "ID  NAME       EMAIL               INCOME   PAYDAY\n1   Luke Toth  lptoth55@gmail.com  1400.00  2020-01-03\n",
+		},
+		{
+			name:           "ADD_USER_DUPLICATE",
+			args:           []string{"users", "add", "-first", "Luke", "-last", "Toth", "-email", "lptoth55@gmail.com"},
+			expectedCode:   1,
+			expectedStderr: models.ErrConflict.Error(),
+		},
+		{
+			name:           "ADD_ACCOUNT",
+			args:           []string{"accounts", "add", "-user", "1", "-name", "Phone Payment", "-amount", "728", "-minimum", "42.83", "-due", "10"},
+			expectedStdout: "ID  USER  NAME           TYPE     DUE  FULL AMOUNT  MINIMUM  PAYMENT  APR\n1   1     Phone Payment  monthly  10   728.00       42.83    0.00     0.00%\n",
+		},
+		{
+			name:           "ADD_ACCOUNT_INVALID",
+			args:           []string{"accounts", "add", "-user", "1", "-name", "Gym", "-type", "fortnightly", "-due", "1"},
+			expectedCode:   1,
+			expectedStderr: "accountType must be one of",
+		},
+		{
+			name:           "ADD_ACCOUNT_BAD_AMOUNT",
+			args:           []string{"accounts", "add", "-amount", "12.345"},
+			expectedCode:   2,
+			expectedStderr: "must be an amount like 1234.56",
+		},
+		{
+			name:           "EDIT_ACCOUNT",
+			args:           []string{"accounts", "edit", "-payment", "100", "-apr", "5", "1"},
+			expectedStdout: "ID  USER  NAME           TYPE     DUE  FULL AMOUNT  MINIMUM  PAYMENT  APR\n1   1     Phone Payment  monthly  10   728.00       42.83    100.00   5.00%\n",
+		},
+		{
+			name:           "LIST_ACCOUNTS_JSON",
+			args:           []string{"accounts", "list", "-format", "json", "-max-amount", "1000"},
+			expectedStdout: "[\n  {\n    \"ID\": 1,\n    \"userID\": 1,\n    \"name\": \"Phone Payment\",\n    \"accountType\": \"monthly\",\n    \"minimumPayment\": 42.83,\n    \"currentPayment\": 100,\n    \"fullAmount\": 728,\n    \"currency\": \"USD\",\n    \"apr\": 5,\n    \"dueDate\": \"10\",\n    \"anchorDate\": \"\",\n    \"URL\": \"\"\n  }\n]\n",
+		},
+		{
+			name:           "LIST_ACCOUNTS_NONE",
+			args:           []string{"accounts", "list", "-min-amount", "1000"},
+			expectedStdout: "ID  USER  NAME  TYPE  DUE  FULL AMOUNT  MINIMUM  PAYMENT  APR\n",
+		},
+		{
+			name:           "PLAN",
+			args:           []string{"plan", "-user", "1", "-from", "2020-02-01", "-paychecks", "2"},
+			expectedStdout: "PAYDAY      INCOME   BILLS  TOTAL   LEFTOVER\n2020-01-31  1400.00  1      100.00  1300.00\n2020-02-14  1400.00  0      0.00    1400.00\n            2800.00         100.00  2700.00\n",
+		},
+		{
+			name: "EXPORT",
+			args: []string{"export", "-user", "1", "-o", export},
+		},
+		{
+			name:           "IMPORT",
+			args:           []string{"import", export},
+			expectedStdout: "           USERS  ACCOUNTS  TRANSACTIONS\ncreated    0      0         0\nupdated    0      0         0\nunchanged  1      1         0\n",
+		},
+		{
+			name:           "EXPORT_WITHOUT_USER",
+			args:           []string{"export"},
+			expectedCode:   2,
+			expectedStderr: "-user or -all is required",
+		},
+		{
+			name:           "EXPORT_ALL_AND_USER",
+			args:           []string{"export", "-all", "-user", "1"},
+			expectedCode:   2,
+			expectedStderr: "-user and -all can't be used together",
+		},
+		{
+			name:           "ADD_SECOND_USER",
+			args:           []string{"users", "add", "-first", "John", "-last", "Ide", "-email", "ide.johnc@gmail.com"},
+			expectedStdout: "ID  NAME      EMAIL                INCOME  PAYDAY\n2   John Ide  ide.johnc@gmail.com  0.00    \n",
+		},
+		{
+			name: "EXPORT_ALL",
+			args: []string{"export", "-all", "-o", exportAll},
+		},
+		{
+			name:           "IMPORT_ALL",
+			args:           []string{"import", exportAll},
+			expectedStdout: "           USERS  ACCOUNTS  TRANSACTIONS\ncreated    0      0         0\nupdated    0      0         0\nunchanged  2      1         0\n",
+		},
+		{
+			name:           "REMOVE_SECOND_USER",
+			args:           []string{"users", "rm", "2"},
+			expectedStdout: "",
+		},
+		{
+			name:           "REMOVE_ACCOUNT",
+			args:           []string{"accounts", "rm", "1"},
+			expectedStdout: "",
+		},
+		{
+			name:           "REMOVE_ACCOUNT_MISSING",
+			args:           []string{"accounts", "rm", "1"},
+			expectedCode:   1,
+			expectedStderr: "account 1: " + models.ErrNotFound.Error(),
+		},
+		{
+			name:           "REMOVE_USER",
+			args:           []string{"users", "rm", "1"},
+			expectedStdout: "",
+		},
+		{
+			name:           "LIST_USERS",
+			args:           []string{"users", "list"},
+			expectedStdout: "ID  NAME  EMAIL  INCOME  PAYDAY\n",
+		},
+	}
+
+	run(t, tests, env)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	store := models.NewMemoryStore()
+	ts := httptest.NewServer(routes.NewRouter(&config.Env{DB: store, Log: config.Log}))
+	defer ts.Close()
+
+	// Register and log in through the server, taking the password from stdin
+	var stdout bytes.Buffer
+	console := &cli.Console{Stdin: strings.NewReader("correct horse\n"), Stdout: &stdout, Stderr: ioutil.Discard, Getenv: func(string) string { return "" }}
+	if code := cli.Run(context.Background(), console, []string{"users", "add", "-server", ts.URL, "-first", "John", "-last", "Ide", "-email", "ide.johnc@gmail.com"}); code != 0 {
+		t.Fatalf("\nusers add:\n\tGot: \t\t%d\n\tExpected: \t0\n", code)
+	}
+	stdout.Reset()
+	console.Stdin = strings.NewReader("correct horse\n")
+	if code := cli.Run(context.Background(), console, []string{"login", "-server", ts.URL, "-email", "ide.johnc@gmail.com"}); code != 0 {
+		t.Fatalf("\nlogin:\n\tGot: \t\t%d\n\tExpected: \t0\n", code)
+	}
+	token := strings.TrimSpace(stdout.String())
+
+	env := map[string]string{"DINERO_SERVER": ts.URL, "DINERO_TOKEN": token}
+	tests := []TestCase{
+		{
+			name:           "ADD_ACCOUNT",
+			args:           []string{"accounts", "add", "-name", "Gym", "-amount", "30", "-due", "1"},
+			expectedStdout: "ID  USER  NAME  TYPE     DUE  FULL AMOUNT  MINIMUM  PAYMENT  APR\n1   1     Gym   monthly  1    30.00        0.00     0.00     0.00%\n",
+		},
+		{
+			name:           "EDIT_ACCOUNT_INVALID",
+			args:           []string{"accounts", "edit", "-minimum", "50", "1"},
+			expectedCode:   1,
+			expectedStderr: "minimumPayment must not exceed fullAmount",
+		},
+		{
+			name:           "LIST_USERS",
+			args:           []string{"users", "list"},
+			expectedStdout: "ID  NAME      EMAIL                INCOME  PAYDAY\n1   John Ide  ide.johnc@gmail.com  0.00    \n",
+		},
+		{
+			name:           "PLAN_WITHOUT_PAYDAY",
+			args:           []string{"plan", "-user", "1"},
+			expectedCode:   1,
+			expectedStderr: "payday is required",
+		},
+		{
+			name:  "IMPORT_STDIN",
+			args:  []string{"import", "-format", "json", "-on-conflict", "skip", "-"},
+			stdin: `{"version":1,"users":[{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com"}]}`,
+		},
+		{
+			// a server only exports the session's user
+			name:           "EXPORT_ALL",
+			args:           []string{"export", "-all"},
+			expectedCode:   2,
+			expectedStderr: "-all only works when exporting from a database",
+		},
+		{
+			name:           "BAD_TOKEN",
+			args:           []string{"accounts", "list", "-token", "nope"},
+			expectedCode:   1,
+			expectedStderr: "401 Unauthorized",
+		},
+	}
+
+	run(t, tests, env)
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{name: "HELP", args: []string{"help"}, expectedStderr: "accounts edit ID"},
+		{name: "UNKNOWN", args: []string{"accounts", "show"}, expectedCode: 2, expectedStderr: `unknown command "accounts show"`},
+		{name: "COMMAND_HELP", args: []string{"accounts", "edit", "-h"}, expectedStderr: "dinero accounts edit [flags] ID"},
+		{name: "BAD_FLAG", args: []string{"users", "list", "-nope"}, expectedCode: 2, expectedStderr: "flag provided but not defined: -nope"},
+		{name: "BAD_ID", args: []string{"accounts", "rm", "-server", "http://127.0.0.1:1", "one"}, expectedCode: 2, expectedStderr: `"one" is not an ID`},
+		{name: "BAD_FORMAT", args: []string{"users", "list", "-format", "yaml"}, expectedCode: 2, expectedStderr: "must be table or json"},
+	}
+
+	run(t, tests, map[string]string{})
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/models"
+	"dinero/api/tui"
+	"os"
+)
+
+// dashboard shows the full-screen dashboard of the database's users and accounts
+func dashboard(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("tui")
+	load := c.databaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	in, ok := c.Stdin.(*os.File)
+	if !ok {
+		return tui.ErrNotTerminal
+	}
+
+	db, err := models.InitDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return tui.Run(ctx, db, in, c.Stdout)
+}
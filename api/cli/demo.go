@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/models"
+	"fmt"
+	"strconv"
+)
+
+// The demo user's credentials, logged when the API starts in demo mode
+const (
+	demoEmail    = "demo@dinero.dev"
+	demoPassword = "dinero-demo"
+)
+
+// seedDemo fills a store with a demo user and a few months of their bills
+func seedDemo(ctx context.Context, store models.Store) error {
+	user := models.User{
+		FirstName:      "Demo",
+		LastName:       "User",
+		FullName:       "Demo User",
+		Email:          demoEmail,
+		BiweeklyIncome: models.USD(185000),
+		Payday:         "2020-01-03",
+	}
+	if err := user.SetPassword(demoPassword); err != nil {
+		return err
+	}
+	created, err := store.CreateUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	accounts := []models.Account{
+		{Name: "Rent", AccountType: "monthly", MinimumPayment: models.USD(120000), CurrentPayment: models.USD(120000), FullAmount: models.USD(120000), DueDate: "1"},
+		{Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), DueDate: "10"},
+		{Name: "Credit Card", AccountType: "monthly", MinimumPayment: models.USD(3500), CurrentPayment: models.USD(15000), FullAmount: models.USD(240000), APR: 2199, DueDate: "22"},
+		{Name: "Gym", AccountType: "weekly", MinimumPayment: models.USD(1200), CurrentPayment: models.USD(1200), FullAmount: models.USD(1200), DueDate: "5", AnchorDate: "2020-01-06"},
+	}
+	for _, account := range accounts {
+		account.UserID = created.ID
+		a, err := store.CreateAccount(ctx, account)
+		if err != nil {
+			return err
+		}
+
+		day, _ := strconv.Atoi(a.DueDate)
+		for month := 1; month <= 3; month++ {
+			_, err = store.CreateTransaction(ctx, models.Transaction{
+				AccountID:  a.ID,
+				Amount:     a.CurrentPayment.Neg(),
+				PostedDate: fmt.Sprintf("2020-%02d-%02d", month, day),
+				Payee:      a.Name,
+				Memo:       "Demo payment",
+				Status:     models.StatusCleared,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"dinero/api/models"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// format is how a command prints records: an aligned table or JSON
+type format string
+
+const (
+	formatTable format = "table"
+	formatJSON  format = "json"
+)
+
+func (f *format) String() string {
+	return string(*f)
+}
+
+func (f *format) Set(s string) error {
+	if s != string(formatTable) && s != string(formatJSON) {
+		return fmt.Errorf("must be %s or %s", formatTable, formatJSON)
+	}
+	*f = format(s)
+	return nil
+}
+
+// formatFlag adds the -format flag to fs
+func formatFlag(fs *flag.FlagSet) *format {
+	f := formatTable
+	fs.Var(&f, "format", "output format, table or json")
+	return &f
+}
+
+// table is records laid out in rows under a header
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// add appends a row to t
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// print writes v as indented JSON, or the table of it t returns
+func (c *Console) print(f format, v interface{}, t func() table) error {
+	if f == formatJSON {
+		enc := json.NewEncoder(c.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tab := t()
+	w := tabwriter.NewWriter(c.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(tab.header, "\t"))
+	for _, row := range tab.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// moneyValue is a flag holding an amount like 1234.56
+type moneyValue struct {
+	m *models.Money
+}
+
+func (v moneyValue) String() string {
+	if v.m == nil {
+		return ""
+	}
+	return v.m.String()
+}
+
+func (v moneyValue) Set(s string) error {
+	m, err := models.ParseMoney(s)
+	if err != nil {
+		return errors.New("must be an amount like 1234.56")
+	}
+	*v.m = m
+	return nil
+}
+
+// rateValue is a flag holding a percentage like 24.99
+type rateValue struct {
+	r *models.Rate
+}
+
+func (v rateValue) String() string {
+	if v.r == nil {
+		return ""
+	}
+	return v.r.String()
+}
+
+func (v rateValue) Set(s string) error {
+	r, err := models.ParseRate(s)
+	if err != nil {
+		return errors.New("must be a percentage like 24.99")
+	}
+	*v.r = r
+	return nil
+}
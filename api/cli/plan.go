@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/schedule"
+	"strconv"
+	"time"
+)
+
+// planFrom is a flag holding the date a plan starts from
+type planFrom struct {
+	t *time.Time
+}
+
+func (v planFrom) String() string {
+	if v.t == nil {
+		return ""
+	}
+	return v.t.Format(schedule.DateFormat)
+}
+
+func (v planFrom) Set(s string) error {
+	t, err := schedule.ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*v.t = t
+	return nil
+}
+
+// showPlan prints a user's upcoming paychecks, the bills each one covers and
+// what's left over
+func showPlan(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("plan")
+	userID := fs.Int("user", 0, "ID of the user to plan for")
+	from := schedule.Day(time.Now())
+	fs.Var(planFrom{&from}, "from", "date to start from, formatted as YYYY-MM-DD; today when not set")
+	paychecks := fs.Int("paychecks", 6, "number of paychecks to plan")
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	if *userID < 1 {
+		return c.usageError(fs, "-user is required")
+	}
+
+	p, err := b.Plan(ctx, *userID, from, *paychecks)
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, p, func() table {
+		t := table{header: []string{"PAYDAY", "INCOME", "BILLS", "TOTAL", "LEFTOVER"}}
+		for _, paycheck := range p.Paychecks {
+			t.add(paycheck.Date, paycheck.Income.String(), strconv.Itoa(len(paycheck.Bills)), paycheck.Total.String(), paycheck.Leftover.String())
+		}
+		t.add("", p.Income.String(), "", p.Total.String(), p.Leftover.String())
+		return t
+	})
+}
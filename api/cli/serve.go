@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"dinero/api/client"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"dinero/api/server"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// serve serves the API until SIGINT or SIGTERM
+func serve(ctx context.Context, c *Console, args []string) error {
+	logger := config.Log
+
+	fs := c.flags("serve")
+	dryRun := fs.Bool("migrate-dry-run", false, "print the SQL of pending schema migrations and exit")
+	cfg, err := config.Load(fs, args, c.Getenv)
+	if err != nil {
+		return err
+	}
+	cfg.ConfigureLogger(logger)
+
+	if *dryRun {
+		return runMigrations(c, cfg, true)
+	}
+
+	policy, err := cfg.DeletePolicy()
+	if err != nil {
+		return err
+	}
+
+	// Set up environment
+	env := &config.Env{Log: logger, MaxBodyBytes: cfg.MaxBodyBytes}
+	closeDB := func() error { return nil }
+	if cfg.Demo {
+		store := models.NewMemoryStore()
+		store.UserDeletePolicy = policy
+		if err = seedDemo(ctx, store); err != nil {
+			return err
+		}
+		logger.WithField("email", demoEmail).WithField("password", demoPassword).Info("Serving demo data, which is lost on exit")
+		env.DB = store
+	} else {
+		// Get database reference, migrating it to the latest schema
+		db, err := models.InitDB(cfg.DB)
+		if err != nil {
+			return err
+		}
+		db.UserDeletePolicy = policy
+		env.DB = db
+		closeDB = db.Close
+	}
+
+	// Register chi router
+	r := routes.NewRouter(env)
+
+	srv, err := server.New(cfg, r, logger)
+	if err != nil {
+		closeDB()
+		return err
+	}
+
+	// Stop serving on SIGINT or SIGTERM, letting requests in flight finish. A
+	// second signal kills Dinero straight away.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.WithField("signal", sig).Info("Shutting down...")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	// Serve
+	logger.WithField("addr", cfg.Addr).WithField("tls", cfg.TLS()).Info("Serving...")
+	err = srv.Run(ctx)
+	if err != nil {
+		logger.WithError(err).Error("Stopped serving")
+	}
+	if closeErr := closeDB(); closeErr != nil {
+		logger.WithError(closeErr).Error("Failed to close the database")
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Stopped")
+
+	return nil
+}
+
+// printConfig prints the settings serve would run with, along with where each
+// one came from
+func printConfig(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("config print")
+	cfg, err := config.Load(fs, args, c.Getenv)
+	if cfg != nil {
+		if printErr := cfg.Print(c.Stdout); printErr != nil {
+			return printErr
+		}
+	}
+
+	return err
+}
+
+// migrate migrates the database to the latest schema, or with -dry-run prints
+// the SQL that would run
+func migrate(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("migrate")
+	dryRun := fs.Bool("dry-run", false, "print the SQL of pending migrations instead of running them")
+	load := c.databaseFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	return runMigrations(c, cfg, *dryRun)
+}
+
+// runMigrations applies the migrations the database is missing, or prints their SQL
+func runMigrations(c *Console, cfg *config.Config, dryRun bool) error {
+	db, err := models.OpenDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := db.Migrator()
+	migrator.DryRun = dryRun
+	migrator.Out = c.Stdout
+
+	from, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if err = migrator.Up(); err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if from == migrator.Latest() {
+			fmt.Fprintf(c.Stdout, "Already at version %d\n", from)
+		} else {
+			fmt.Fprintf(c.Stdout, "Migrated from version %d to %d\n", from, migrator.Latest())
+		}
+	}
+	return nil
+}
+
+// login logs in to a server and prints the session token, for -token or
+// DINERO_TOKEN
+func login(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("login")
+	server := fs.String("server", "", "URL of the Dinero server; also read from DINERO_SERVER")
+	email := fs.String("email", "", "email address of the user")
+	out := formatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *server == "" {
+		*server = c.Getenv("DINERO_SERVER")
+	}
+	if *server == "" || *email == "" {
+		return c.usageError(fs, "-server and -email are required")
+	}
+
+	password, err := c.password(fs)
+	if err != nil {
+		return err
+	}
+
+	session, err := client.New(*server).Login(ctx, *email, password)
+	if err != nil {
+		return err
+	}
+
+	// The table is just the token, so it can be captured by a script
+	if *out == formatTable {
+		_, err = fmt.Fprintln(c.Stdout, session.Token)
+		return err
+	}
+	return c.print(*out, session, nil)
+}
+
+// password reads a password from DINERO_PASSWORD, or else the first line of
+// stdin, so it never has to be on the command line
+func (c *Console) password(fs *flag.FlagSet) (string, error) {
+	if password := c.Getenv("DINERO_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	line, err := bufio.NewReader(c.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", c.usageError(fs, "A password is required in DINERO_PASSWORD or on stdin")
+	}
+
+	return password, nil
+}
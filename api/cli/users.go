@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"dinero/api/models"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// listFlags adds the paging and sorting flags of a list command to fs, returning
+// a func that reads them once fs is parsed
+func listFlags(fs *flag.FlagSet) func() models.ListOptions {
+	limit := fs.Int("limit", 0, "most records to list; every one from a database and 50 from a server when 0")
+	offset := fs.Int("offset", 0, "number of records to skip")
+	sort := fs.String("sort", "", `fields to sort by, like "dueDate,-fullAmount" to sort fullAmount descending`)
+
+	return func() models.ListOptions {
+		return models.ListOptions{Limit: *limit, Offset: *offset, Sort: models.ParseSort(*sort)}
+	}
+}
+
+// userTable lays users out one per row
+func userTable(users ...*models.User) table {
+	t := table{header: []string{"ID", "NAME", "EMAIL", "INCOME", "PAYDAY"}}
+	for _, user := range users {
+		t.add(strconv.Itoa(user.ID), user.FullName, user.Email, user.BiweeklyIncome.String(), user.Payday)
+	}
+	return t
+}
+
+// listUsers lists users: every one on a database, and the session's own user on
+// a server
+func listUsers(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("users list")
+	opts := listFlags(fs)
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	users, _, err := b.ListUsers(ctx, opts())
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, users, func() table { return userTable(users...) })
+}
+
+// addUser registers a user with the password in DINERO_PASSWORD or on stdin
+func addUser(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("users add")
+	var user models.User
+	fs.StringVar(&user.FirstName, "first", "", "first name")
+	fs.StringVar(&user.LastName, "last", "", "last name")
+	fs.StringVar(&user.FullName, "name", "", "full name, the first and last names when not set")
+	fs.StringVar(&user.Email, "email", "", "email address the user logs in with")
+	fs.Var(moneyValue{&user.BiweeklyIncome}, "income", "take-home pay every two weeks, like 1850.00")
+	fs.StringVar(&user.Currency, "currency", "", "ISO 4217 code of the currency the income is in, USD by default")
+	fs.StringVar(&user.Payday, "payday", "", "date of any payday, formatted as YYYY-MM-DD")
+	out := formatFlag(fs)
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	if user.FullName == "" {
+		user.FullName = user.FirstName + " " + user.LastName
+	}
+	user.SetCurrency(user.Currency)
+
+	password, err := c.password(fs)
+	if err != nil {
+		return err
+	}
+
+	created, err := b.CreateUser(ctx, user, password)
+	if err != nil {
+		return err
+	}
+
+	return c.print(*out, created, func() table { return userTable(created) })
+}
+
+// removeUsers deletes users, doing with their accounts what the user delete
+// policy says
+func removeUsers(ctx context.Context, c *Console, args []string) error {
+	fs := c.flags("users rm")
+	b, closeBackend, err := c.connect(fs, args)
+	if err != nil {
+		return err
+	}
+	defer closeBackend()
+
+	ids, err := c.ids(fs, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err = b.DeleteUser(ctx, id); err != nil {
+			return fmt.Errorf("user %d: %v", id, err)
+		}
+	}
+
+	return nil
+}
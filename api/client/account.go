@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"dinero/api/models"
+	"net/http"
+	"strconv"
+)
+
+// accountPath is the path of the account with accountID
+func accountPath(accountID int) string {
+	return "/accounts/" + strconv.Itoa(accountID)
+}
+
+// ListAccounts returns a page of the accounts matching filter, along with how
+// many match in all
+func (c *Client) ListAccounts(ctx context.Context, filter models.AccountFilter, opts models.ListOptions) ([]*models.Account, int, error) {
+	q := listQuery(opts)
+	if filter.UserID > 0 {
+		q.Set("userID", strconv.Itoa(filter.UserID))
+	}
+	if filter.AccountType != "" {
+		q.Set("accountType", filter.AccountType)
+	}
+	if filter.DueDate != "" {
+		q.Set("dueDate", filter.DueDate)
+	}
+	if filter.MinFullAmount != nil {
+		q.Set("minFullAmount", filter.MinFullAmount.String())
+	}
+	if filter.MaxFullAmount != nil {
+		q.Set("maxFullAmount", filter.MaxFullAmount.String())
+	}
+
+	accounts := make([]*models.Account, 0)
+	res, err := c.do(ctx, request{method: http.MethodGet, path: "/accounts", query: q}, &accounts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return accounts, total(res), nil
+}
+
+// GetAccount returns the account with accountID, with its Version set so a later
+// UpdateAccount fails if someone else changes it first
+func (c *Client) GetAccount(ctx context.Context, accountID int) (*models.Account, error) {
+	account := new(models.Account)
+	res, err := c.do(ctx, request{method: http.MethodGet, path: accountPath(accountID)}, account)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Version = version(res)
+	return account, nil
+}
+
+// CreateAccount creates an account and returns it
+func (c *Client) CreateAccount(ctx context.Context, account models.Account) (*models.Account, error) {
+	created := new(models.Account)
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/accounts", body: account}, created)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateAccount replaces the account with account.ID. A non-zero
+// account.Version makes the update conditional on the account still being at
+// that version, failing with an error IsConflict reports otherwise. Only a
+// conditional update is retried. On success account.Version is set to the new
+// version.
+func (c *Client) UpdateAccount(ctx context.Context, account *models.Account) error {
+	res, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   accountPath(account.ID),
+		header: ifMatch(account.Version),
+		body:   account,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	account.Version = version(res)
+	return nil
+}
+
+// DeleteAccount deletes the account with accountID
+func (c *Client) DeleteAccount(ctx context.Context, accountID int) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: accountPath(accountID)}, nil)
+	return err
+}
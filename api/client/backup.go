@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"dinero/api/models"
+	"net/http"
+	"net/url"
+)
+
+// Export returns the client's user along with their accounts and transactions
+func (c *Client) Export(ctx context.Context) (*models.Backup, error) {
+	b := new(models.Backup)
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/export"}, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Import restores a backup made by Export. onConflict is one of
+// models.ConflictFail, models.ConflictSkip or models.ConflictOverwrite, and
+// decides what happens to rows that already exist and differ.
+func (c *Client) Import(ctx context.Context, b *models.Backup, onConflict string) (*models.RestoreReport, error) {
+	report := new(models.RestoreReport)
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/import",
+		query:  url.Values{"onConflict": {onConflict}},
+		body:   b,
+	}, report)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
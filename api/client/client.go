@@ -0,0 +1,321 @@
+// Package client is a typed client for the Dinero API. It sends and receives the
+// same models the server uses, and turns error responses into *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPath is where a Dinero server serves the version of the API Client calls
+const apiPath = "/api/v1"
+
+// Client calls the Dinero API on the server at BaseURL
+type Client struct {
+	// BaseURL is where Dinero is served, like http://localhost:3000
+	BaseURL string
+	// Token is the session token sent with every request. Login sets it.
+	Token string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Retries is how many more times an idempotent request is sent after it fails
+	// to reach the server, or the server answers 502, 503 or 504
+	Retries int
+	// Backoff is how long to wait before the first retry. Each retry after that
+	// waits twice as long as the one before.
+	Backoff time.Duration
+}
+
+// New returns a Client for the Dinero server at baseURL that retries idempotent requests
+// a few times
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Retries: 3,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+// Error is an error response from the API
+type Error struct {
+	Status    int
+	Code      string
+	Message   string
+	RequestID string
+	// Details lists the fields that failed validation
+	Details []models.FieldError
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("error: %d %s", e.Status, e.Message)
+	for i, detail := range e.Details {
+		if i == 0 {
+			msg += ":"
+		} else {
+			msg += ","
+		}
+		msg += " " + detail.Field + " " + detail.Message
+	}
+	return msg
+}
+
+// status returns the status of an *Error, or 0 for any other error
+func status(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.Status
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 Not Found response
+func IsNotFound(err error) bool {
+	return status(err) == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 Conflict response, or a 412
+// Precondition Failed response to a write of a record that has since changed
+func IsConflict(err error) bool {
+	return status(err) == http.StatusConflict || status(err) == http.StatusPreconditionFailed
+}
+
+// IsInvalid reports whether err is a 422 Unprocessable Entity response, listing
+// the fields that failed validation in its Details
+func IsInvalid(err error) bool {
+	return status(err) == http.StatusUnprocessableEntity
+}
+
+// request is an API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body is encoded as JSON, unless it's nil
+	body interface{}
+}
+
+// do sends req, retrying if it's idempotent, and decodes a successful response
+// body into out unless out is nil. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, req request, out interface{}) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+
+	u := c.BaseURL + apiPath + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	attempts := 1
+	if idempotent(req) {
+		attempts += c.Retries
+	}
+
+	var res *http.Response
+	var err error
+	wait := c.Backoff
+	for attempt := 1; ; attempt++ {
+		res, err = c.send(ctx, req, u, body)
+		if attempt >= attempts || !retryable(res, err) {
+			break
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return res, readError(res)
+	}
+
+	if out != nil && res.StatusCode != http.StatusNoContent {
+		if err = json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res, err
+		}
+	}
+	io.Copy(ioutil.Discard, res.Body)
+
+	return res, nil
+}
+
+// send sends one attempt at req to u
+func (c *Client) send(ctx context.Context, req request, u string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequest(req.method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return httpClient.Do(httpReq)
+}
+
+// idempotent reports whether sending req twice has the same effect as sending
+// it once, so it's safe to retry. A PUT only is with If-Match: without it, a PUT
+// to a missing record creates one with the next free ID, so a retry after the
+// first attempt got through but its response was lost would create another.
+func idempotent(req request) bool {
+	switch req.method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return true
+	case http.MethodPut:
+		return req.header.Get("If-Match") != ""
+	}
+	return false
+}
+
+// retryable reports whether an attempt failed in a way that trying again could fix
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// readError reads the error envelope of an error response
+func readError(res *http.Response) error {
+	var envelope struct {
+		Error struct {
+			Code      string              `json:"code"`
+			Message   string              `json:"message"`
+			RequestID string              `json:"requestID"`
+			Details   []models.FieldError `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(res.Body).Decode(&envelope)
+
+	e := &Error{
+		Status:    res.StatusCode,
+		Code:      envelope.Error.Code,
+		Message:   envelope.Error.Message,
+		RequestID: envelope.Error.RequestID,
+		Details:   envelope.Error.Details,
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(res.StatusCode)
+	}
+	return e
+}
+
+// listQuery encodes the paging and sorting of a list request
+func listQuery(opts models.ListOptions) url.Values {
+	q := make(url.Values)
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	fields := make([]string, len(opts.Sort))
+	for i, field := range opts.Sort {
+		fields[i] = field.Field
+		if field.Desc {
+			fields[i] = "-" + field.Field
+		}
+	}
+	if len(fields) > 0 {
+		q.Set("sort", strings.Join(fields, ","))
+	}
+
+	return q
+}
+
+// total reads the X-Total-Count header of a list response
+func total(res *http.Response) int {
+	n, _ := strconv.Atoi(res.Header.Get("X-Total-Count"))
+	return n
+}
+
+// version reads the record version from the ETag header of a response, or
+// returns 0 when it has none
+func version(res *http.Response) int {
+	tag := strings.TrimPrefix(res.Header.Get("ETag"), "W/")
+	n, _ := strconv.Atoi(strings.Trim(tag, `"`))
+	return n
+}
+
+// ifMatch returns the header making a write conditional on the record still being
+// at version, or no header when the version isn't known
+func ifMatch(version int) http.Header {
+	if version < 1 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
+
+// Login starts a session for the user with email and password, and uses it for
+// every request after
+func (c *Client) Login(ctx context.Context, email string, password string) (*models.Session, error) {
+	session := new(models.Session)
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/login",
+		body:   map[string]string{"email": email, "password": password},
+	}, session)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Token = session.Token
+	return session, nil
+}
+
+// Logout ends the client's session
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/auth/logout"}, nil)
+	if err == nil {
+		c.Token = ""
+	}
+	return err
+}
@@ -0,0 +1,236 @@
+package client_test
+
+import (
+	"context"
+	"dinero/api/client"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newServer starts an API server backed by an empty MemoryStore
+func newServer(t *testing.T) *httptest.Server {
+	env := &config.Env{DB: models.NewMemoryStore(), Log: config.Log}
+	return httptest.NewServer(routes.NewRouter(env))
+}
+
+// signUp registers a user and returns a client logged in as them
+func signUp(t *testing.T, url string, email string) (*client.Client, *models.User) {
+	ctx := context.Background()
+	c := client.New(url)
+
+	user, err := c.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: email, BiweeklyIncome: models.USD(140000)}, "correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Login(ctx, email, "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+
+	return c, user
+}
+
+func TestAccounts(t *testing.T) {
+	t.Parallel()
+
+	ts := newServer(t)
+	defer ts.Close()
+	c, user := signUp(t, ts.URL, "lptoth55@gmail.com")
+	ctx := context.Background()
+
+	phone := models.Account{Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), FullAmount: models.USD(72800), DueDate: "10"}
+	created, err := c.CreateAccount(ctx, phone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.UserID != user.ID || created.Name != phone.Name {
+		t.Errorf("\nCreateAccount:\n\tGot: \t\t%+v\n\tExpected: \tphone account for user %d\n", created, user.ID)
+	}
+	if _, err = c.CreateAccount(ctx, models.Account{Name: "Gym", AccountType: "monthly", FullAmount: models.USD(3000), DueDate: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Duplicate names and invalid fields map to typed errors
+	if _, err = c.CreateAccount(ctx, phone); !client.IsConflict(err) {
+		t.Errorf("\nDuplicate name:\n\tGot: \t\t%v\n\tExpected: \ta conflict\n", err)
+	}
+	_, err = c.CreateAccount(ctx, models.Account{Name: "Bad", AccountType: "fortnightly", DueDate: "10"})
+	if !client.IsInvalid(err) || len(err.(*client.Error).Details) == 0 || err.(*client.Error).Details[0].Field != "accountType" {
+		t.Errorf("\nInvalid account:\n\tGot: \t\t%v\n\tExpected: \taccountType to be invalid\n", err)
+	}
+
+	min := models.USD(10000)
+	accounts, total, err := c.ListAccounts(ctx, models.AccountFilter{MinFullAmount: &min}, models.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || total != 1 || accounts[0].ID != created.ID {
+		t.Errorf("\nListAccounts:\n\tGot: \t\t%d accounts of %d\n\tExpected: \tthe phone account\n", len(accounts), total)
+	}
+	accounts, total, err = c.ListAccounts(ctx, models.AccountFilter{}, models.ListOptions{Limit: 1, Sort: []models.SortField{{Field: "name"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || total != 2 || accounts[0].Name != "Gym" {
+		t.Errorf("\nListAccounts sorted:\n\tGot: \t\t%d accounts of %d\n\tExpected: \tGym, of 2\n", len(accounts), total)
+	}
+
+	// Updates are conditional on the version that was read
+	account, err := c.GetAccount(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := *account
+	account.CurrentPayment = models.USD(10000)
+	if err = c.UpdateAccount(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Version != stale.Version+1 {
+		t.Errorf("\nVersion after update:\n\tGot: \t\t%d\n\tExpected: \t%d\n", account.Version, stale.Version+1)
+	}
+	if err = c.UpdateAccount(ctx, &stale); !client.IsConflict(err) {
+		t.Errorf("\nStale update:\n\tGot: \t\t%v\n\tExpected: \ta conflict\n", err)
+	}
+
+	if err = c.DeleteAccount(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetAccount(ctx, created.ID); !client.IsNotFound(err) {
+		t.Errorf("\nDeleted account:\n\tGot: \t\t%v\n\tExpected: \tnot found\n", err)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	t.Parallel()
+
+	ts := newServer(t)
+	defer ts.Close()
+	c, user := signUp(t, ts.URL, "lptoth55@gmail.com")
+	ctx := context.Background()
+
+	if _, err := c.CreateUser(ctx, *user, "another password"); !client.IsConflict(err) {
+		t.Errorf("\nDuplicate email:\n\tGot: \t\t%v\n\tExpected: \ta conflict\n", err)
+	}
+	if _, err := c.CreateUser(ctx, models.User{FirstName: "John"}, "short"); !client.IsInvalid(err) {
+		t.Errorf("\nInvalid user:\n\tGot: \t\t%v\n\tExpected: \tinvalid\n", err)
+	}
+
+	users, total, err := c.ListUsers(ctx, models.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || total != 1 || users[0].Email != user.Email {
+		t.Errorf("\nListUsers:\n\tGot: \t\t%d users of %d\n\tExpected: \tthe caller\n", len(users), total)
+	}
+
+	got, err := c.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Payday = "2026-10-23"
+	if err = c.UpdateUser(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = c.GetUser(ctx, user.ID); err != nil || got.Payday != "2026-10-23" {
+		t.Errorf("\nUpdated user:\n\tGot: \t\t%+v, %v\n\tExpected: \tpayday 2026-10-23\n", got, err)
+	}
+
+	if err = c.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting the user ends their sessions too
+	if _, err = c.GetUser(ctx, user.ID); err == nil || err.(*client.Error).Status != http.StatusUnauthorized {
+		t.Errorf("\nDeleted user:\n\tGot: \t\t%v\n\tExpected: \tunauthorized\n", err)
+	}
+}
+
+// flaky answers the first failures requests with 503 and passes the rest to next
+func flaky(failures int32, next http.Handler) (http.Handler, *int32) {
+	var calls int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &calls
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		method         string
+		version        int
+		retries        int
+		expectedCalls  int32
+		expectedStatus int
+	}{
+		{name: "GET_RECOVERS", method: "GET", retries: 3, expectedCalls: 3, expectedStatus: http.StatusUnauthorized},
+		{name: "GET_GIVES_UP", method: "GET", retries: 1, expectedCalls: 2, expectedStatus: http.StatusServiceUnavailable},
+		{name: "DELETE_RECOVERS", method: "DELETE", retries: 3, expectedCalls: 3, expectedStatus: http.StatusUnauthorized},
+		{name: "POST_NOT_RETRIED", method: "POST", retries: 3, expectedCalls: 1, expectedStatus: http.StatusServiceUnavailable},
+		{name: "PUT_IF_MATCH_RECOVERS", method: "PUT", version: 1, retries: 3, expectedCalls: 3, expectedStatus: http.StatusUnauthorized},
+		// a PUT without If-Match could create the account again
+		{name: "PUT_NOT_RETRIED", method: "PUT", retries: 3, expectedCalls: 1, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			env := &config.Env{DB: models.NewMemoryStore(), Log: config.Log}
+			handler, calls := flaky(2, routes.NewRouter(env))
+			ts := httptest.NewServer(handler)
+			defer ts.Close()
+
+			c := client.New(ts.URL)
+			c.Retries, c.Backoff = test.retries, time.Millisecond
+
+			// Without a session every call that reaches the router is refused
+			var err error
+			switch test.method {
+			case "GET":
+				_, err = c.GetAccount(context.Background(), 1)
+			case "DELETE":
+				err = c.DeleteAccount(context.Background(), 1)
+			case "POST":
+				_, err = c.CreateAccount(context.Background(), models.Account{})
+			case "PUT":
+				err = c.UpdateAccount(context.Background(), &models.Account{ID: 1, Version: test.version})
+			}
+
+			if e, ok := err.(*client.Error); !ok || e.Status != test.expectedStatus {
+				t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \tstatus %d\n", err, test.expectedStatus)
+			}
+			if got := atomic.LoadInt32(calls); got != test.expectedCalls {
+				t.Errorf("\nCalls:\n\tGot: \t\t%d\n\tExpected: \t%d\n", got, test.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	t.Parallel()
+
+	handler, _ := flaky(100, http.NotFoundHandler())
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	c := client.New(ts.URL)
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetAccount(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"dinero/api/models"
+	"dinero/api/plan"
+	"dinero/api/schedule"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// userPath is the path of the user with userID
+func userPath(userID int) string {
+	return "/users/" + strconv.Itoa(userID)
+}
+
+// ListUsers returns a page of users, along with how many there are in all
+func (c *Client) ListUsers(ctx context.Context, opts models.ListOptions) ([]*models.User, int, error) {
+	users := make([]*models.User, 0)
+	res, err := c.do(ctx, request{method: http.MethodGet, path: "/users", query: listQuery(opts)}, &users)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total(res), nil
+}
+
+// GetUser returns the user with userID, with its Version set so a later
+// UpdateUser fails if someone else changes it first
+func (c *Client) GetUser(ctx context.Context, userID int) (*models.User, error) {
+	user := new(models.User)
+	res, err := c.do(ctx, request{method: http.MethodGet, path: userPath(userID)}, user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Version = version(res)
+	return user, nil
+}
+
+// CreateUser registers a user who signs in with password and returns them
+func (c *Client) CreateUser(ctx context.Context, user models.User, password string) (*models.User, error) {
+	reg := struct {
+		models.User
+		Password string `json:"password"`
+	}{user, password}
+
+	created := new(models.User)
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/users", body: reg}, created)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateUser replaces the user with user.ID. A non-zero user.Version makes the
+// update conditional on the user still being at that version, failing with an
+// error IsConflict reports otherwise. Only a conditional update is retried. On
+// success user.Version is set to the new version.
+func (c *Client) UpdateUser(ctx context.Context, user *models.User) error {
+	res, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   userPath(user.ID),
+		header: ifMatch(user.Version),
+		body:   user,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	user.Version = version(res)
+	return nil
+}
+
+// DeleteUser deletes the user with userID
+func (c *Client) DeleteUser(ctx context.Context, userID int) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: userPath(userID)}, nil)
+	return err
+}
+
+// Plan lays out count of the user's paychecks starting with the one on or before
+// from, along with the bills each one has to cover
+func (c *Client) Plan(ctx context.Context, userID int, from time.Time, count int) (*plan.Plan, error) {
+	q := url.Values{
+		"from":      {from.Format(schedule.DateFormat)},
+		"paychecks": {strconv.Itoa(count)},
+	}
+
+	p := new(plan.Plan)
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: userPath(userID) + "/plan", query: q}, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
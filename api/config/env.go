@@ -10,4 +10,7 @@ import (
 type Env struct {
 	DB  models.Store
 	Log *log.Logger
+	// MaxBodyBytes caps the size of request bodies, other than the uploads that
+	// set their own limit. Zero leaves them unlimited.
+	MaxBodyBytes int64
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/middleware"
 	"github.com/sirupsen/logrus"
 )
 
@@ -52,22 +53,9 @@ func RouteLogger(env *Env) func(http.Handler) http.Handler {
 				"method":    r.Method,
 				"path":      r.URL.Path,
 				"requester": r.RemoteAddr,
+				"requestID": middleware.GetReqID(r.Context()),
 				"status":    sw.status,
 			}).Info()
 		})
 	}
 }
-
-func init() {
-	// // Logging in JSON
-	// Log.SetFormatter(&logrus.JSONFormatter{})
-
-	// // Logging the function where the log was called
-	// Log.SetReportCaller(true)
-
-	// // Sets the logging output to stdout
-	// Log.SetOutput(os.Stdout)
-
-	// // Sets the logging level to warn or higher
-	// Log.SetLevel(logrus.WarnLevel)
-}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"dinero/api/models"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EnvPrefix starts the name of every environment variable holding a setting.
+// The rest of the name is the setting's flag in upper case with underscores, so
+// -log-level is read from DINERO_LOG_LEVEL.
+const EnvPrefix = "DINERO_"
+
+// sections are the tables a configuration file groups settings into. A flag
+// starting with a section's name is a key in its table, so -log-level is level
+// in the [log] table.
+var sections = []string{"http", "log", "tls"}
+
+// Config holds the settings the server starts with
+type Config struct {
+	DB               string
+	Addr             string
+	Demo             bool
+	LogLevel         string
+	LogFormat        string
+	TLSCert          string
+	TLSKey           string
+	UserDeletePolicy string
+	ReassignTo       int
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	MaxHeaderBytes  int
+	MaxBodyBytes    int64
+
+	// sources says where each setting that isn't a default came from, by flag name
+	sources map[string]string
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() *Config {
+	return &Config{
+		DB:               "../dinero.db",
+		Addr:             ":3000",
+		LogLevel:         "info",
+		LogFormat:        "text",
+		UserDeletePolicy: models.DeleteRestrict,
+		ReadTimeout:      15 * time.Second,
+		WriteTimeout:     60 * time.Second,
+		IdleTimeout:      2 * time.Minute,
+		ShutdownTimeout:  20 * time.Second,
+		MaxHeaderBytes:   64 << 10,
+		MaxBodyBytes:     1 << 20,
+	}
+}
+
+// register defines a flag on fs for every setting, defaulting to its value in c
+func (c *Config) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.DB, "db", c.DB, "path of the SQLite database, or a postgres:// URL")
+	fs.StringVar(&c.Addr, "addr", c.Addr, "address to listen on, as host:port")
+	fs.BoolVar(&c.Demo, "demo", c.Demo, "serve sample data kept in memory instead of a database")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "least severe level logged: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log line format: text or json")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "path of the TLS certificate; serves HTTPS when set with -tls-key")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "path of the TLS private key")
+	fs.StringVar(&c.UserDeletePolicy, "user-delete-policy", c.UserDeletePolicy, "what deleting a user does with their accounts: restrict, cascade or reassign")
+	fs.IntVar(&c.ReassignTo, "reassign-to", c.ReassignTo, "user ID that receives a deleted user's accounts with -user-delete-policy=reassign")
+	fs.DurationVar(&c.ReadTimeout, "http-read-timeout", c.ReadTimeout, "longest time to read a request, body included")
+	fs.DurationVar(&c.WriteTimeout, "http-write-timeout", c.WriteTimeout, "longest time from reading a request's headers to finishing its response")
+	fs.DurationVar(&c.IdleTimeout, "http-idle-timeout", c.IdleTimeout, "longest time a keep-alive connection waits for its next request")
+	fs.DurationVar(&c.ShutdownTimeout, "http-shutdown-timeout", c.ShutdownTimeout, "longest time to let in-flight requests finish on shutdown")
+	fs.IntVar(&c.MaxHeaderBytes, "http-max-header-bytes", c.MaxHeaderBytes, "largest request headers accepted, in bytes")
+	fs.Int64Var(&c.MaxBodyBytes, "http-max-body-bytes", c.MaxBodyBytes, "largest request body accepted, in bytes, other than statement and backup uploads; 0 for no limit")
+}
+
+// settings returns the flag names of every setting, in the order they're printed:
+// alphabetically, with the ones outside any section first
+func settings() []string {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	Default().register(fs)
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	sort.SliceStable(names, func(i, j int) bool {
+		return !strings.Contains(fileKey(names[i]), ".") && strings.Contains(fileKey(names[j]), ".")
+	})
+
+	return names
+}
+
+// Load works out the configuration from, in increasing priority, the defaults,
+// the TOML file named by -config or DINERO_CONFIG, DINERO_* environment variables
+// and the flags in args. Any flags the caller already defined on fs are parsed
+// along with the settings. The configuration is returned even when it fails
+// validation, so it can still be printed.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	c := Default()
+	c.sources = make(map[string]string)
+	c.register(fs)
+	path := fs.String("config", "", "path of a TOML configuration file; also read from "+EnvPrefix+"CONFIG")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Flags were parsed first to find the file, so they must not be overwritten
+	onCommandLine := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+	if !onCommandLine["config"] {
+		*path = getenv(EnvPrefix + "CONFIG")
+	}
+
+	if *path != "" {
+		values, err := readTOML(*path)
+		if err != nil {
+			return nil, err
+		}
+
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := flagName(key)
+			if !isSetting(name) || key != fileKey(name) {
+				return nil, fmt.Errorf("%s: unknown setting %s", *path, key)
+			}
+			if onCommandLine[name] {
+				continue
+			}
+			if err := fs.Set(name, values[key]); err != nil {
+				return nil, fmt.Errorf("%s: %s: %v", *path, key, err)
+			}
+			c.sources[name] = *path
+		}
+	}
+
+	for _, name := range settings() {
+		if onCommandLine[name] {
+			c.sources[name] = "flag -" + name
+			continue
+		}
+
+		value := getenv(envName(name))
+		if value == "" {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: %v", envName(name), err)
+		}
+		c.sources[name] = envName(name)
+	}
+
+	return c, c.Validate()
+}
+
+// Validate checks every setting holds a usable value
+func (c *Config) Validate() error {
+	if c.DB == "" && !c.Demo {
+		return errors.New("db must be set unless serving the demo")
+	}
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr %q must be host:port", c.Addr)
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log-level %q is not a log level", c.LogLevel)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log-format %q must be text or json", c.LogFormat)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	for _, path := range []string{c.TLSCert, c.TLSKey} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+	for name, timeout := range map[string]time.Duration{
+		"http-read-timeout":     c.ReadTimeout,
+		"http-write-timeout":    c.WriteTimeout,
+		"http-idle-timeout":     c.IdleTimeout,
+		"http-shutdown-timeout": c.ShutdownTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("%s %s must not be negative", name, timeout)
+		}
+	}
+	if c.MaxHeaderBytes < 1 {
+		return fmt.Errorf("http-max-header-bytes %d must be positive", c.MaxHeaderBytes)
+	}
+	if c.MaxBodyBytes < 0 {
+		return fmt.Errorf("http-max-body-bytes %d must not be negative", c.MaxBodyBytes)
+	}
+	if _, err := c.DeletePolicy(); err != nil {
+		return fmt.Errorf("user-delete-policy %q with reassign-to %d is not a delete policy", c.UserDeletePolicy, c.ReassignTo)
+	}
+
+	return nil
+}
+
+// DeletePolicy returns what deleting a user does with their accounts
+func (c *Config) DeletePolicy() (models.DeletePolicy, error) {
+	return models.ParseDeletePolicy(c.UserDeletePolicy, c.ReassignTo)
+}
+
+// TLS reports whether the server should serve HTTPS
+func (c *Config) TLS() bool {
+	return c.TLSCert != ""
+}
+
+// ConfigureLogger sets the level and format of log to the configured ones
+func (c *Config) ConfigureLogger(log *logrus.Logger) {
+	if level, err := logrus.ParseLevel(c.LogLevel); err == nil {
+		log.SetLevel(level)
+	}
+
+	if c.LogFormat == "json" {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		log.SetFormatter(&logrus.TextFormatter{})
+	}
+}
+
+// Print writes the configuration as a TOML file that would reproduce it, noting
+// where each setting came from. A password in the database URL is masked.
+func (c *Config) Print(w io.Writer) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	printed := *c
+	printed.DB = maskPassword(c.DB)
+	printed.register(fs)
+
+	table := ""
+	for _, name := range settings() {
+		key := fileKey(name)
+		if i := strings.IndexByte(key, '.'); i >= 0 {
+			if key[:i] != table {
+				table = key[:i]
+				fmt.Fprintf(w, "\n[%s]\n", table)
+			}
+			key = key[i+1:]
+		}
+
+		// Numbers and booleans are bare, everything else is a string
+		value := fs.Lookup(name).Value.(flag.Getter).Get()
+		switch value.(type) {
+		case bool, int, int64:
+		default:
+			value = strconv.Quote(fmt.Sprint(value))
+		}
+
+		source, ok := c.sources[name]
+		if !ok {
+			source = "default"
+		}
+
+		if _, err := fmt.Fprintf(w, "%s = %v # %s\n", key, value, source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maskPassword hides the password in a database URL
+func maskPassword(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return dsn
+	}
+	if _, ok := u.User.Password(); !ok {
+		return dsn
+	}
+
+	u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return u.String()
+}
+
+// fileKey returns the dotted key a setting has in a configuration file
+func fileKey(name string) string {
+	for _, section := range sections {
+		if strings.HasPrefix(name, section+"-") {
+			return section + "." + strings.TrimPrefix(name, section+"-")
+		}
+	}
+	return name
+}
+
+// flagName returns the flag of a dotted configuration file key
+func flagName(key string) string {
+	return strings.Replace(key, ".", "-", 1)
+}
+
+// envName returns the environment variable a setting is read from
+func envName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// isSetting reports whether name is the flag of a setting
+func isSetting(name string) bool {
+	for _, setting := range settings() {
+		if setting == name {
+			return true
+		}
+	}
+	return false
+}
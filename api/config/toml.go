@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readTOML reads the settings in a TOML file, see parseTOML
+func readTOML(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values, err := parseTOML(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return values, nil
+}
+
+// parseTOML reads settings written as TOML, returning each value as the text
+// flag.Value.Set expects, keyed by its dotted name ("log.level" for level in the
+// [log] table). Only as much of TOML as a settings file needs is understood:
+// comments, tables, and string, integer and boolean values.
+func parseTOML(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	table := ""
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		if text[0] == '[' {
+			end := strings.IndexByte(text, ']')
+			if end < 0 || !isComment(text[end+1:]) {
+				return nil, fmt.Errorf("line %d: table header must look like [name]", line)
+			}
+			table = strings.TrimSpace(text[1:end])
+			if !isBareKey(table) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", line, table)
+			}
+			continue
+		}
+
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key := strings.TrimSpace(text[:eq])
+		if !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", line, key)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("line %d: %s is set twice", line, key)
+		}
+
+		value, err := parseTOMLValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", line, key, err)
+		}
+		values[key] = value
+	}
+
+	return values, scanner.Err()
+}
+
+// parseTOMLValue unquotes a string, or checks a bare value is an integer or
+// boolean, and drops any comment that follows it
+func parseTOMLValue(text string) (string, error) {
+	var value, rest string
+
+	switch {
+	case strings.HasPrefix(text, `"`):
+		end := 1
+		for ; end < len(text) && text[end] != '"'; end++ {
+			if text[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(text) {
+			return "", fmt.Errorf("unterminated string")
+		}
+		unquoted, err := strconv.Unquote(text[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", text[:end+1])
+		}
+		value, rest = unquoted, text[end+1:]
+	case strings.HasPrefix(text, "'"):
+		end := strings.IndexByte(text[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		value, rest = text[1:end+1], text[end+2:]
+	default:
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		value = strings.TrimSpace(text)
+		if value != "true" && value != "false" {
+			if _, err := strconv.ParseInt(strings.Replace(value, "_", "", -1), 10, 64); err != nil {
+				return "", fmt.Errorf("expected a string, integer or boolean, got %q", value)
+			}
+			value = strings.Replace(value, "_", "", -1)
+		}
+	}
+
+	if !isComment(rest) {
+		return "", fmt.Errorf("unexpected %q after value", strings.TrimSpace(rest))
+	}
+
+	return value, nil
+}
+
+// isComment reports whether text is blank or only a comment
+func isComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || text[0] == '#'
+}
+
+// isBareKey reports whether key is made of the letters, digits, dashes and
+// underscores TOML allows in an unquoted key
+func isBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -1,10 +1,12 @@
 module dinero/api
 
-go 1.12
+go 1.16
 
 require (
 	github.com/go-chi/chi v4.0.2+incompatible
+	github.com/lib/pq v1.3.0
 	github.com/mattn/go-sqlite3 v1.10.0
 	github.com/sirupsen/logrus v1.4.1
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3 // indirect
 )
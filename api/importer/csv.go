@@ -0,0 +1,296 @@
+package importer
+
+import (
+	"bytes"
+	"dinero/api/models"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping says which columns of a CSV statement hold which fields. Columns are
+// named by their header or by their position, counting from 1. Fields left empty
+// are looked for under the headers banks commonly use.
+type Mapping struct {
+	Date   string
+	Amount string
+	// Debit and Credit are for statements that put money out and money in into
+	// separate columns instead of one signed Amount
+	Debit  string
+	Credit string
+	Payee  string
+	Memo   string
+	ID     string
+	// DateFormat is the layout of the dates, written like "MM/DD/YYYY". Common
+	// layouts are tried when it's empty, with month before day.
+	DateFormat string
+	// NoHeader says the first row is a transaction rather than column headers
+	NoHeader bool
+}
+
+// commonHeaders are the headers fields are looked for under when a Mapping leaves them out
+var commonHeaders = map[string][]string{
+	"date":   {"date", "posted date", "posting date", "post date", "transaction date", "trans. date"},
+	"amount": {"amount", "transaction amount"},
+	"debit":  {"debit", "debits", "withdrawal", "withdrawals"},
+	"credit": {"credit", "credits", "deposit", "deposits"},
+	"payee":  {"payee", "description", "name", "merchant"},
+	"memo":   {"memo", "notes", "note", "details"},
+	"id":     {"id", "transaction id", "fitid", "reference"},
+}
+
+// dateLayouts are the layouts tried when a Mapping has no DateFormat
+var dateLayouts = []string{DateFormat, "1/2/2006", "1/2/06", "2006/1/2", "20060102", "2 Jan 2006", "Jan 2, 2006"}
+
+// dateFormatTokens turns a DateFormat into a time layout
+var dateFormatTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "M", "1", "DD", "02", "D", "2")
+
+// columns is the position of each field of a CSV statement, -1 when it's missing
+type columns struct {
+	date, amount, debit, credit, payee, memo, id int
+}
+
+// resolve finds the column a field is in. An empty name falls back to the common
+// headers for the field, and only an explicit name that can't be found is an error.
+func resolve(header map[string]int, field, name string, v *models.ValidationError) int {
+	if name == "" {
+		for _, common := range commonHeaders[field] {
+			if i, ok := header[common]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 1 {
+			v.Add(field, "column", "must be a column header or a position from 1")
+			return -1
+		}
+		return n - 1
+	}
+
+	if i, ok := header[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return i
+	}
+
+	v.Add(field, "column", "must name a column in the statement")
+	return -1
+}
+
+// delimiter guesses the delimiter of a CSV statement from its first line, since
+// banks in countries with decimal commas often separate columns with semicolons
+func delimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+
+	delim, most := ',', bytes.Count(line, []byte(","))
+	for _, r := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(r))); n > most {
+			delim, most = r, n
+		}
+	}
+
+	return delim
+}
+
+// csvRow is the text of one row of a CSV statement and the line it starts on
+type csvRow struct {
+	line int
+	text string
+}
+
+// splitRows splits a CSV statement into rows. It's done by hand rather than by
+// encoding/csv so rows keep their line numbers, which means counting quotes to
+// find the line breaks that are inside quoted cells.
+func splitRows(data []byte) []csvRow {
+	rows := make([]csvRow, 0)
+	var current []string
+	start, quotes := 0, 0
+	for i, line := range strings.Split(string(data), "\n") {
+		if len(current) == 0 {
+			start = i + 1
+		}
+		current = append(current, strings.TrimRight(line, "\r"))
+		quotes += strings.Count(line, `"`)
+
+		if quotes%2 == 0 {
+			rows = append(rows, csvRow{line: start, text: strings.Join(current, "\n")})
+			current, quotes = nil, 0
+		}
+	}
+	if len(current) > 0 {
+		rows = append(rows, csvRow{line: start, text: strings.Join(current, "\n")})
+	}
+
+	return rows
+}
+
+// readRow reads the cells of one row
+func readRow(text string, comma rune) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	return reader.Read()
+}
+
+// parseCSV reads a CSV statement
+func parseCSV(data []byte, mapping Mapping) ([]Record, []RowError, error) {
+	comma := delimiter(data)
+	rows := splitRows(data)
+	for len(rows) > 0 && strings.TrimSpace(rows[0].text) == "" {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return nil, nil, ErrMalformed
+	}
+
+	header := make(map[string]int)
+	if !mapping.NoHeader {
+		names, err := readRow(rows[0].text, comma)
+		if err != nil {
+			return nil, nil, ErrMalformed
+		}
+		for i, name := range names {
+			header[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		rows = rows[1:]
+	}
+
+	v := new(models.ValidationError)
+	cols := columns{
+		date:   resolve(header, "date", mapping.Date, v),
+		amount: resolve(header, "amount", mapping.Amount, v),
+		debit:  resolve(header, "debit", mapping.Debit, v),
+		credit: resolve(header, "credit", mapping.Credit, v),
+		payee:  resolve(header, "payee", mapping.Payee, v),
+		memo:   resolve(header, "memo", mapping.Memo, v),
+		id:     resolve(header, "id", mapping.ID, v),
+	}
+	if len(v.Fields) == 0 {
+		if cols.date < 0 {
+			v.Add("date", "required", "must name the column dates are in")
+		}
+		if cols.amount < 0 && cols.debit < 0 && cols.credit < 0 {
+			v.Add("amount", "required", "must name the column amounts are in, or debit and credit must")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	layouts := dateLayouts
+	if mapping.DateFormat != "" {
+		layouts = []string{dateFormatTokens.Replace(mapping.DateFormat)}
+	}
+
+	records := make([]Record, 0, len(rows))
+	errs := make([]RowError, 0)
+	for _, row := range rows {
+		if strings.TrimSpace(row.text) == "" {
+			continue
+		}
+
+		cells, err := readRow(row.text, comma)
+		if err != nil {
+			errs = append(errs, rowError(row.line, "is not valid CSV"))
+			continue
+		}
+
+		record, rerr := cols.record(cells, row.line, layouts)
+		if rerr != nil {
+			errs = append(errs, *rerr)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, errs, nil
+}
+
+// cell is the trimmed value of a column in a row, empty when the row is too short
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// record reads one row of a CSV statement
+func (c columns) record(row []string, line int, layouts []string) (Record, *RowError) {
+	record := Record{
+		Line:  line,
+		Payee: cell(row, c.payee),
+		Memo:  cell(row, c.memo),
+		FITID: cell(row, c.id),
+	}
+
+	date, err := parseDate(cell(row, c.date), layouts)
+	if err != nil {
+		rerr := rowError(line, "has an invalid date %q", cell(row, c.date))
+		return record, &rerr
+	}
+	record.Date = date
+
+	if value := cell(row, c.amount); c.amount >= 0 && value != "" {
+		record.Amount, err = parseAmount(value)
+	} else {
+		record.Amount, err = debitCredit(cell(row, c.debit), cell(row, c.credit))
+	}
+	if err != nil {
+		rerr := rowError(line, "%s", err)
+		return record, &rerr
+	}
+
+	return record, nil
+}
+
+// debitCredit combines separate debit and credit columns into a signed amount.
+// Debits are money out whichever way the bank signed them.
+func debitCredit(debit, credit string) (models.Money, error) {
+	if debit == "" && credit == "" {
+		return models.Money{}, errMissingAmount
+	}
+
+	amount := models.NewMoney(0, models.DefaultCurrency)
+	if debit != "" {
+		d, err := parseAmount(debit)
+		if err != nil {
+			return models.Money{}, err
+		}
+		if !d.IsNegative() {
+			d = d.Neg()
+		}
+		amount = amount.Add(d)
+	}
+
+	if credit != "" {
+		c, err := parseAmount(credit)
+		if err != nil {
+			return models.Money{}, err
+		}
+		amount = amount.Add(c)
+	}
+
+	return amount, nil
+}
+
+// parseDate parses a date in the first of layouts it matches, returning it as YYYY-MM-DD
+func parseDate(s string, layouts []string) (string, error) {
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		date, err = time.Parse(layout, s)
+		if err == nil {
+			return date.Format(DateFormat), nil
+		}
+	}
+
+	return "", err
+}
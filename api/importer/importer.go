@@ -0,0 +1,248 @@
+// Package importer reads bank statements in CSV, OFX/QFX and QIF format and
+// turns their rows into transactions, leaving out rows imported before.
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dinero/api/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+// DateFormat is the layout dates are written in
+const DateFormat = "2006-01-02"
+
+// Format is a statement file format
+type Format string
+
+const (
+	// CSV is comma separated values, with the columns described by a Mapping
+	CSV Format = "csv"
+	// OFX is Open Financial Exchange, in either its SGML or XML flavour. QFX is
+	// Quicken's name for the same thing.
+	OFX Format = "ofx"
+	// QIF is the Quicken Interchange Format
+	QIF Format = "qif"
+)
+
+var (
+	// ErrUnknownFormat is returned for formats other than the ones above
+	ErrUnknownFormat = errors.New("importer: unknown statement format")
+	// ErrMalformed is returned for statements that can't be read at all
+	ErrMalformed = errors.New("importer: malformed statement")
+
+	// errMissingAmount is the RowError message for rows without an amount
+	errMissingAmount = errors.New("has no amount")
+)
+
+// Record is one row of a statement. Amounts are signed the way banks sign them,
+// so money leaving the account is negative.
+type Record struct {
+	// Line is where the row starts in the statement, for error messages
+	Line   int
+	Date   string
+	Amount models.Money
+	Payee  string
+	Memo   string
+	// FITID is the ID the bank gave the row, when it gave one
+	FITID string
+}
+
+// RowError is a row of a statement that couldn't be read
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// rowError builds a RowError
+func rowError(line int, format string, args ...interface{}) RowError {
+	return RowError{Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+// ParseFormat parses the name of a format. "qfx" is accepted as OFX.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv":
+		return CSV, nil
+	case "ofx", "qfx":
+		return OFX, nil
+	case "qif":
+		return QIF, nil
+	}
+
+	return "", ErrUnknownFormat
+}
+
+// Detect works out the format of a statement from the media type it was uploaded
+// with, falling back to looking at its contents
+func Detect(contentType string, data []byte) Format {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return CSV
+	case "application/x-ofx", "application/ofx", "application/vnd.intu.qfx", "application/x-qfx":
+		return OFX
+	case "application/qif", "application/x-qif":
+		return QIF
+	}
+
+	head := bytes.ToUpper(bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	switch {
+	case bytes.HasPrefix(head, []byte("OFXHEADER")), bytes.Contains(head, []byte("<OFX>")):
+		return OFX
+	case bytes.HasPrefix(head, []byte("!TYPE")), bytes.HasPrefix(head, []byte("!ACCOUNT")):
+		return QIF
+	}
+
+	return CSV
+}
+
+// Parse reads the rows of a statement. Rows that can't be read are returned as
+// RowErrors alongside the ones that could; an error means the statement as a
+// whole couldn't be read. mapping is only used for CSV.
+func Parse(format Format, data []byte, mapping Mapping) ([]Record, []RowError, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	switch format {
+	case CSV:
+		return parseCSV(data, mapping)
+	case OFX:
+		return parseOFX(data)
+	case QIF:
+		return parseQIF(data)
+	}
+
+	return nil, nil, ErrUnknownFormat
+}
+
+// Options configures how records become transactions
+type Options struct {
+	// Ledger says the statement already signs amounts the way transactions are
+	// signed, with charges positive. Otherwise amounts are negated.
+	Ledger bool
+}
+
+// Preview is what importing a statement into an account does
+type Preview struct {
+	// Transactions are the rows that haven't been imported before
+	Transactions []models.Transaction `json:"transactions"`
+	// Duplicates are the rows that have, either earlier in the statement or into
+	// the account before
+	Duplicates []models.Transaction `json:"duplicates"`
+	// Errors are the rows that couldn't be read
+	Errors []RowError `json:"errors"`
+}
+
+// Build turns the records of a statement into transactions against an account,
+// splitting out the ones whose import IDs are in imported
+func Build(accountID int, records []Record, imported map[string]bool, opts Options) *Preview {
+	preview := &Preview{
+		Transactions: make([]models.Transaction, 0, len(records)),
+		Duplicates:   make([]models.Transaction, 0),
+		Errors:       make([]RowError, 0),
+	}
+
+	seen := make(map[string]bool)
+	occurrences := make(map[string]int)
+	for _, record := range records {
+		amount := record.Amount
+		if !opts.Ledger {
+			amount = amount.Neg()
+		}
+
+		payee := record.Payee
+		if payee == "" {
+			payee = record.Memo
+		}
+
+		t := models.Transaction{
+			AccountID:  accountID,
+			Amount:     amount,
+			PostedDate: record.Date,
+			Payee:      payee,
+			Memo:       record.Memo,
+			Status:     models.StatusCleared,
+			ImportID:   importID(record, occurrences),
+		}
+
+		if err := t.Validate(); err != nil {
+			preview.Errors = append(preview.Errors, invalidRow(record.Line, err))
+			continue
+		}
+
+		if imported[t.ImportID] || seen[t.ImportID] {
+			preview.Duplicates = append(preview.Duplicates, t)
+			continue
+		}
+
+		seen[t.ImportID] = true
+		preview.Transactions = append(preview.Transactions, t)
+	}
+
+	return preview
+}
+
+// invalidRow describes a row whose transaction failed validation
+func invalidRow(line int, err error) RowError {
+	verr, ok := err.(*models.ValidationError)
+	if !ok || len(verr.Fields) == 0 {
+		return rowError(line, "is invalid")
+	}
+
+	field := verr.Fields[0]
+	return rowError(line, "%s %s", field.Field, field.Message)
+}
+
+// importID identifies a record across imports. Banks that give rows a FITID
+// promise it never changes; rows without one are identified by their contents,
+// numbered so that identical rows on the same statement are told apart.
+func importID(record Record, occurrences map[string]int) string {
+	if record.FITID != "" {
+		return "fitid:" + record.FITID
+	}
+
+	key := strings.Join([]string{record.Date, record.Amount.String(), record.Payee, record.Memo}, "\x00")
+	occurrences[key]++
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+	return "hash:" + hex.EncodeToString(sum[:16])
+}
+
+// nonAmount is everything in an amount that isn't part of the number
+var nonAmount = regexp.MustCompile(`[^0-9.,+-]`)
+
+// parseAmount parses an amount the way banks write them, with currency symbols,
+// thousands separators, decimal commas and parentheses for negative numbers
+func parseAmount(s string) (models.Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	number := nonAmount.ReplaceAllString(s, "")
+
+	// A comma followed by one or two digits at the end is a decimal comma,
+	// anywhere else commas separate thousands
+	if i := strings.LastIndexByte(number, ','); i >= 0 && !strings.Contains(number, ".") && len(number)-i-1 <= 2 {
+		number = number[:i] + "." + number[i+1:]
+	}
+	number = strings.Replace(number, ",", "", -1)
+
+	// Some banks write more decimal places than there are cents
+	if i := strings.IndexByte(number, '.'); i >= 0 && len(number)-i-1 > 2 {
+		number = strings.TrimRight(number, "0")
+		number = strings.TrimSuffix(number, ".")
+	}
+
+	amount, err := models.ParseMoney(number)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("has an invalid amount %q", s)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+
+	return amount, nil
+}
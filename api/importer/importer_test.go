@@ -0,0 +1,249 @@
+package importer_test
+
+import (
+	"dinero/api/importer"
+	"dinero/api/models"
+	"reflect"
+	"testing"
+)
+
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20200103120000.000[-5:EST]
+<TRNAMT>-12.50
+<FITID>2020010301
+<NAME>Joe's Coffee &amp; Tea
+<MEMO>POS PURCHASE
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20200104
+<TRNAMT>1000.00
+<FITID>2020010402
+<PAYEE><NAME>ACME Payroll<ADDR1>1 Main St</PAYEE>
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>2020
+<TRNAMT>-1.00
+<FITID>2020010403
+<NAME>Broken
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlOFX = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20200105</DTPOSTED><TRNAMT>-45.1</TRNAMT><FITID>A1</FITID><NAME>Grocer</NAME></STMTTRN>
+</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+const qif = `!Type:Bank
+D1/ 3'20
+T-12.50
+PJoe's Coffee
+MPOS PURCHASE
+^
+D01/04/2020
+T1,000.00
+PACME Payroll
+SSalary
+$1000.00
+^
+Dnope
+T5.00
+PBroken
+^
+!Type:Invst
+D1/5'20
+NBuy
+T100.00
+^
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		format  importer.Format
+		data    string
+		mapping importer.Mapping
+		records []importer.Record
+		errs    []importer.RowError
+	}{
+		{
+			name:   "CSV",
+			format: importer.CSV,
+			data:   "Posted Date,Description,Amount,Memo\n01/03/2020,Joe's Coffee,($12.50),POS\n\n2020-01-04,ACME Payroll,\"1,000.00\",\nbad,Broken,1.00,\n01/05/2020,Nothing,,\n",
+			records: []importer.Record{
+				{Line: 2, Date: "2020-01-03", Amount: models.USD(-1250), Payee: "Joe's Coffee", Memo: "POS"},
+				{Line: 4, Date: "2020-01-04", Amount: models.USD(100000), Payee: "ACME Payroll"},
+			},
+			errs: []importer.RowError{
+				{Line: 5, Message: `has an invalid date "bad"`},
+				{Line: 6, Message: "has no amount"},
+			},
+		},
+		{
+			name:    "CSV_MAPPING",
+			format:  importer.CSV,
+			data:    "03.01.2020;Coffee;;12,50;X1\n04.01.2020;Payroll;1000,00;;X2\n",
+			mapping: importer.Mapping{Date: "1", Payee: "2", Credit: "3", Debit: "4", ID: "5", DateFormat: "DD.MM.YYYY", NoHeader: true},
+			records: []importer.Record{
+				{Line: 1, Date: "2020-01-03", Amount: models.USD(-1250), Payee: "Coffee", FITID: "X1"},
+				{Line: 2, Date: "2020-01-04", Amount: models.USD(100000), Payee: "Payroll", FITID: "X2"},
+			},
+			errs: []importer.RowError{},
+		},
+		{
+			name:   "OFX_SGML",
+			format: importer.OFX,
+			data:   sgmlOFX,
+			records: []importer.Record{
+				{Line: 8, Date: "2020-01-03", Amount: models.USD(-1250), Payee: "Joe's Coffee & Tea", Memo: "POS PURCHASE", FITID: "2020010301"},
+				{Line: 16, Date: "2020-01-04", Amount: models.USD(100000), Payee: "ACME Payroll", FITID: "2020010402"},
+			},
+			errs: []importer.RowError{{Line: 23, Message: `has an invalid date "2020"`}},
+		},
+		{
+			name:   "OFX_XML",
+			format: importer.OFX,
+			data:   xmlOFX,
+			records: []importer.Record{
+				{Line: 4, Date: "2020-01-05", Amount: models.USD(-4510), Payee: "Grocer", FITID: "A1"},
+			},
+			errs: []importer.RowError{},
+		},
+		{
+			name:   "QIF",
+			format: importer.QIF,
+			data:   qif,
+			records: []importer.Record{
+				{Line: 2, Date: "2020-01-03", Amount: models.USD(-1250), Payee: "Joe's Coffee", Memo: "POS PURCHASE"},
+				{Line: 7, Date: "2020-01-04", Amount: models.USD(100000), Payee: "ACME Payroll"},
+			},
+			errs: []importer.RowError{{Line: 13, Message: `has an invalid date "nope"`}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, errs, err := importer.Parse(test.format, []byte(test.data), test.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(records, test.records) {
+				t.Errorf("\nRecords:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", records, test.records)
+			}
+
+			if !reflect.DeepEqual(errs, test.errs) {
+				t.Errorf("\nErrors:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", errs, test.errs)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := importer.Parse(importer.OFX, []byte("not a statement"), importer.Mapping{}); err != importer.ErrMalformed {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, importer.ErrMalformed)
+	}
+
+	_, _, err := importer.Parse(importer.CSV, []byte("When,What\n2020-01-01,Coffee\n"), importer.Mapping{Payee: "Who"})
+	verr, ok := err.(*models.ValidationError)
+	expected := []models.FieldError{{Field: "payee", Rule: "column", Message: "must name a column in the statement"}}
+	if !ok || !reflect.DeepEqual(verr.Fields, expected) {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, expected)
+	}
+
+	_, _, err = importer.Parse(importer.CSV, []byte("When,What\n2020-01-01,Coffee\n"), importer.Mapping{})
+	verr, ok = err.(*models.ValidationError)
+	if !ok || len(verr.Fields) != 2 {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%s\n", err, "date and amount required")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		expected    importer.Format
+	}{
+		{"CONTENT_TYPE", "application/vnd.intu.qfx", "", importer.OFX},
+		{"CONTENT_TYPE_PARAMS", "text/csv; charset=utf-8", "", importer.CSV},
+		{"OFX_HEADER", "application/octet-stream", sgmlOFX, importer.OFX},
+		{"OFX_XML", "", xmlOFX, importer.OFX},
+		{"QIF", "text/plain", qif, importer.QIF},
+		{"CSV", "text/plain", "Date,Amount\n", importer.CSV},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := importer.Detect(test.contentType, []byte(test.data)); got != test.expected {
+				t.Errorf("\nFormat:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	records := []importer.Record{
+		{Line: 2, Date: "2020-01-03", Amount: models.USD(-500), Payee: "Coffee"},
+		{Line: 3, Date: "2020-01-03", Amount: models.USD(-500), Payee: "Coffee"},
+		{Line: 4, Date: "2020-01-04", Amount: models.USD(2000), Memo: "Refund", FITID: "F1"},
+		{Line: 5, Date: "2020-01-04", Amount: models.USD(2000), Memo: "Refund", FITID: "F1"},
+		{Line: 6, Date: "2020-01-05", Amount: models.USD(-100)},
+	}
+
+	first := importer.Build(1, records, nil, importer.Options{})
+	if len(first.Transactions) != 3 || len(first.Duplicates) != 1 || len(first.Errors) != 1 {
+		t.Fatalf("\nPreview:\n\tGot: \t\t%+v\n\tExpected: \t%s\n", first, "3 transactions, 1 duplicate and 1 error")
+	}
+
+	// Identical rows without FITIDs are both imported, but not twice
+	if first.Transactions[0].ImportID == first.Transactions[1].ImportID {
+		t.Errorf("\nImport IDs:\n\tGot: \t\t%s\n\tExpected: \t%s\n", first.Transactions[0].ImportID, "distinct IDs")
+	}
+
+	refund := first.Transactions[2]
+	expected := models.Transaction{AccountID: 1, Amount: models.USD(-2000), PostedDate: "2020-01-04", Payee: "Refund", Memo: "Refund", Status: models.StatusCleared, ImportID: "fitid:F1"}
+	if refund != expected {
+		t.Errorf("\nTransaction:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", refund, expected)
+	}
+
+	expectedErr := importer.RowError{Line: 6, Message: "payee is required"}
+	if first.Errors[0] != expectedErr {
+		t.Errorf("\nError:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", first.Errors[0], expectedErr)
+	}
+
+	imported := make(map[string]bool)
+	for _, transaction := range first.Transactions {
+		imported[transaction.ImportID] = true
+	}
+
+	second := importer.Build(1, records, imported, importer.Options{Ledger: true})
+	if len(second.Transactions) != 0 || len(second.Duplicates) != 4 {
+		t.Errorf("\nPreview:\n\tGot: \t\t%+v\n\tExpected: \t%s\n", second, "4 duplicates")
+	}
+
+	if second.Duplicates[0].Amount != models.USD(-500) {
+		t.Errorf("\nAmount:\n\tGot: \t\t%s\n\tExpected: \t%s\n", second.Duplicates[0].Amount, models.USD(-500))
+	}
+}
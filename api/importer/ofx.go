@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ofxStart finds the body of an OFX statement, after any SGML headers
+	ofxStart = regexp.MustCompile(`(?i)<OFX>`)
+	// ofxTransaction finds each STMTTRN aggregate. Aggregates are closed in both
+	// the SGML and XML flavours of OFX, it's only elements that aren't in SGML.
+	ofxTransaction = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	// ofxElements finds the elements of an aggregate, whose values run to the
+	// next tag or the end of the line
+	ofxElements = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+)
+
+// parseOFX reads an OFX or QFX statement
+func parseOFX(data []byte) ([]Record, []RowError, error) {
+	if !ofxStart.Match(data) {
+		return nil, nil, ErrMalformed
+	}
+
+	records := make([]Record, 0)
+	errs := make([]RowError, 0)
+	for _, match := range ofxTransaction.FindAllSubmatchIndex(data, -1) {
+		line := bytes.Count(data[:match[0]], []byte("\n")) + 1
+		elements := make(map[string]string)
+		for _, element := range ofxElements.FindAllSubmatch(data[match[2]:match[3]], -1) {
+			name := strings.ToUpper(string(element[1]))
+			// The payee's NAME is used when the transaction has no NAME of its own
+			if _, ok := elements[name]; !ok {
+				elements[name] = html.UnescapeString(strings.TrimSpace(string(element[2])))
+			}
+		}
+
+		record, rerr := ofxRecord(elements, line)
+		if rerr != nil {
+			errs = append(errs, *rerr)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, errs, nil
+}
+
+// ofxRecord reads the elements of one STMTTRN aggregate
+func ofxRecord(elements map[string]string, line int) (Record, *RowError) {
+	record := Record{
+		Line:  line,
+		Payee: elements["NAME"],
+		Memo:  elements["MEMO"],
+		FITID: elements["FITID"],
+	}
+
+	// Dates are YYYYMMDD, optionally followed by a time and time zone
+	posted := elements["DTPOSTED"]
+	if len(posted) > 8 {
+		posted = posted[:8]
+	}
+	date, err := parseDate(posted, []string{"20060102"})
+	if err != nil {
+		rerr := rowError(line, "has an invalid date %q", elements["DTPOSTED"])
+		return record, &rerr
+	}
+	record.Date = date
+
+	if elements["TRNAMT"] == "" {
+		rerr := rowError(line, "%s", errMissingAmount)
+		return record, &rerr
+	}
+	record.Amount, err = parseAmount(elements["TRNAMT"])
+	if err != nil {
+		rerr := rowError(line, "%s", err)
+		return record, &rerr
+	}
+
+	return record, nil
+}
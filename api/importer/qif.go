@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// qifDateLayouts are the layouts of QIF dates once apostrophes are replaced with
+// slashes, since Quicken writes years after 1999 like 1/ 2'06
+var qifDateLayouts = []string{"1/2/2006", "1/2/06", DateFormat}
+
+// parseQIF reads a QIF statement. Investment and category lists aren't
+// transactions, so only bank, cash and credit card records are read.
+func parseQIF(data []byte) ([]Record, []RowError, error) {
+	records := make([]Record, 0)
+	errs := make([]RowError, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	fields := make(map[byte]string)
+	start, line := 0, 0
+	skipping := false
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		switch {
+		case text[0] == '!':
+			header := strings.ToLower(strings.TrimSpace(text))
+			switch {
+			case strings.HasPrefix(header, "!type:bank"), strings.HasPrefix(header, "!type:cash"),
+				strings.HasPrefix(header, "!type:ccard"):
+				skipping = false
+			default:
+				skipping = true
+			}
+			fields = make(map[byte]string)
+			continue
+
+		case text[0] == '^':
+			if !skipping && len(fields) > 0 {
+				record, rerr := qifRecord(fields, start)
+				if rerr != nil {
+					errs = append(errs, *rerr)
+				} else {
+					records = append(records, record)
+				}
+			}
+			fields = make(map[byte]string)
+			continue
+		}
+
+		if len(fields) == 0 {
+			start = line
+		}
+		// Split lines repeat their codes, only the first of each is the transaction's
+		if _, ok := fields[text[0]]; !ok {
+			fields[text[0]] = strings.TrimSpace(text[1:])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, ErrMalformed
+	}
+
+	return records, errs, nil
+}
+
+// qifRecord reads the fields of one QIF record
+func qifRecord(fields map[byte]string, line int) (Record, *RowError) {
+	record := Record{
+		Line:  line,
+		Payee: fields['P'],
+		Memo:  fields['M'],
+	}
+
+	raw := fields['D']
+	date, err := parseDate(strings.Replace(strings.Replace(raw, " ", "", -1), "'", "/", -1), qifDateLayouts)
+	if err != nil {
+		rerr := rowError(line, "has an invalid date %q", raw)
+		return record, &rerr
+	}
+	record.Date = date
+
+	amount, ok := fields['T']
+	if !ok {
+		amount = fields['U']
+	}
+	if amount == "" {
+		rerr := rowError(line, "%s", errMissingAmount)
+		return record, &rerr
+	}
+	record.Amount, err = parseAmount(amount)
+	if err != nil {
+		rerr := rowError(line, "%s", err)
+		return record, &rerr
+	}
+
+	return record, nil
+}
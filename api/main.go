@@ -1,33 +1,17 @@
 package main
 
 import (
-	"dinero/api/config"
-	"dinero/api/models"
-	"dinero/api/routes"
-	"net/http"
-)
-
-const (
-	dbName = "../dinero.db"
+	"context"
+	"dinero/api/cli"
+	"os"
 )
 
 func main() {
-	logger := config.Log
-
-	// Get database reference
-	db, err := models.InitDB(dbName)
-	if err != nil {
-		logger.Error(err)
+	console := &cli.Console{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Getenv: os.Getenv,
 	}
-
-	// Set up environment
-	env := &config.Env{DB: db, Log: logger}
-
-	// Register chi router
-	r := routes.NewRouter(env)
-
-	// Serve
-	port := ":3000"
-	logger.WithField("port", port).Info("Serving...")
-	logger.Fatal(http.ListenAndServe(port, r))
+	os.Exit(cli.Run(context.Background(), console, os.Args[1:]))
 }
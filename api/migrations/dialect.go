@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect is the flavour of SQL a database speaks
+type Dialect string
+
+const (
+	// SQLiteDialect is SQLite, which takes ? placeholders. It is the zero Dialect.
+	SQLiteDialect Dialect = ""
+	// PostgresDialect is PostgreSQL, which takes numbered $1 placeholders
+	PostgresDialect Dialect = "postgres"
+)
+
+// Rebind rewrites the ? placeholders in query into the ones the dialect takes.
+// Question marks inside quoted strings and identifiers are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != PostgresDialect || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var (
+		b     strings.Builder
+		quote rune
+		n     int
+	)
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,290 @@
+// Package migrations applies ordered, numbered schema changes to the
+// Dinero database and records which ones have run in a schema_migrations
+// table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const bookkeepingTableStmt = `
+	CREATE TABLE IF NOT EXISTS "schema_migrations" (
+		"version" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"applied_at" TEXT NOT NULL,
+
+		PRIMARY KEY("version")
+	)`
+
+var (
+	// ErrSchemaTooNew is returned when the database has migrations applied that
+	// this binary doesn't know about, meaning it was written by a newer Dinero
+	ErrSchemaTooNew = errors.New("error: database schema is newer than this version of dinero")
+	// ErrNoDown is returned when rolling back a migration that can't be reversed
+	ErrNoDown = errors.New("error: migration cannot be rolled back")
+)
+
+// Migration is a single numbered schema change. Up and Down may contain
+// several statements separated by semicolons.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// DisableForeignKeys turns foreign key enforcement off while the migration
+	// runs, which SQLite needs when rebuilding a table that others reference.
+	// The constraints are checked again before the migration commits.
+	DisableForeignKeys bool
+}
+
+// Migrator applies a set of migrations to a database
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	// Dialect is the SQL the database speaks, SQLite unless set
+	Dialect Dialect
+	// DryRun prints the SQL that would run to Out instead of executing it
+	DryRun bool
+	Out    io.Writer
+}
+
+// New creates a Migrator for db using the given migrations
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{DB: db, Migrations: sorted, Out: ioutil.Discard}
+}
+
+// Latest returns the highest migration version known to the Migrator
+func (m *Migrator) Latest() int {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the version the database is currently migrated to, or 0
+// if no migrations have been applied
+func (m *Migrator) Version() (int, error) {
+	var version sql.NullInt64
+	err := m.DB.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		// A database that predates the bookkeeping table is at version 0
+		exists, existsErr := m.hasBookkeepingTable()
+		if existsErr != nil {
+			return 0, existsErr
+		}
+		if !exists {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+func (m *Migrator) hasBookkeepingTable() (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	if m.Dialect == PostgresDialect {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	}
+
+	var n int
+	err := m.DB.QueryRow(query).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Pending returns the migrations that have not been applied yet, in order
+func (m *Migrator) Pending() ([]Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > m.Latest() {
+		return nil, ErrSchemaTooNew
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range m.Migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up applies every pending migration, each in its own transaction. It
+// refuses to run against a database migrated by a newer binary.
+func (m *Migrator) Up() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+
+	if !m.DryRun {
+		if _, err = m.DB.Exec(bookkeepingTableStmt); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range pending {
+		if err = m.run(migration, migration.Up, true); err != nil {
+			return fmt.Errorf("error: migration %d (%s): %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls the database back until it is at the target version
+func (m *Migrator) Down(target int) error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return ErrSchemaTooNew
+	}
+
+	for i := len(m.Migrations) - 1; i >= 0; i-- {
+		migration := m.Migrations[i]
+		if migration.Version <= target || migration.Version > version {
+			continue
+		}
+
+		if migration.Down == "" {
+			return fmt.Errorf("error: migration %d (%s): %v", migration.Version, migration.Name, ErrNoDown)
+		}
+
+		if err = m.run(migration, migration.Down, false); err != nil {
+			return fmt.Errorf("error: migration %d (%s): %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// run applies a single migration on a dedicated connection, since the
+// foreign key pragma is per connection and can't change inside a transaction
+func (m *Migrator) run(migration Migration, stmt string, up bool) error {
+	if m.DryRun {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		fmt.Fprintf(m.Out, "-- %d %s (%s)\n%s;\n\n", migration.Version, migration.Name, direction, stmt)
+		return nil
+	}
+
+	ctx := context.Background()
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Postgres checks foreign keys by statement, so it never needs them off
+	if migration.DisableForeignKeys && m.Dialect != PostgresDialect {
+		if _, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	return m.apply(ctx, conn, migration, stmt, up)
+}
+
+// apply runs a single migration's SQL and updates the bookkeeping table in
+// the same transaction so a failure leaves no trace
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, stmt string, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(stmt); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if migration.DisableForeignKeys && m.Dialect != PostgresDialect {
+		if err = checkForeignKeys(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if up {
+		_, err = tx.Exec(m.Dialect.Rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339))
+	} else {
+		_, err = tx.Exec(m.Dialect.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkForeignKeys fails if any row violates a foreign key constraint,
+// listing every such row by table so they can be fixed by hand
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		order      []string
+		violations = make(map[string][]string)
+	)
+	for rows.Next() {
+		var (
+			table  string
+			rowid  sql.NullInt64
+			parent string
+			fkid   int
+		)
+		if err = rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s (missing %s)", table, parent)
+		if _, ok := violations[key]; !ok {
+			order = append(order, key)
+		}
+		violations[key] = append(violations[key], strconv.FormatInt(rowid.Int64, 10))
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	found := make([]string, len(order))
+	for i, key := range order {
+		found[i] = key + ": " + strings.Join(violations[key], ", ")
+	}
+	return fmt.Errorf("error: rows violate their foreign keys, fix or delete them and migrate again: %s", strings.Join(found, "; "))
+}
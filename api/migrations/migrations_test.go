@@ -0,0 +1,230 @@
+package migrations_test
+
+import (
+	"bytes"
+	"database/sql"
+	"dinero/api/migrations"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// SQLite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens a throwaway SQLite database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	dir, err := ioutil.TempDir("", "dinero-migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func exec(t *testing.T, db *sql.DB, stmts ...string) {
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpFreshDatabase(t *testing.T) {
+	db := openTestDB(t)
+	m := migrations.New(db, migrations.SQLite)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != m.Latest() {
+		t.Errorf("\nVersion:\n\tGot: \t\t%d\n\tExpected: \t%d\n", version, m.Latest())
+	}
+
+	// Running again is a no-op
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// A database created before migrations existed, with dollars stored as REAL
+	exec(t, db,
+		`CREATE TABLE "users" ("id" INTEGER, "first_name" TEXT NOT NULL, "last_name" TEXT NOT NULL, "full_name" TEXT NOT NULL, "email" TEXT NOT NULL UNIQUE, "biweekly_income" REAL NOT NULL, PRIMARY KEY("id"))`,
+		`CREATE TABLE "accounts" ("id" INTEGER, "user_id" INTEGER NOT NULL, "name" TEXT NOT NULL, "account_type" TEXT NOT NULL, "minimum_payment" REAL NOT NULL, "current_payment" REAL NOT NULL, "full_amount" REAL NOT NULL, "due_date" TEXT NOT NULL, "url" TEXT NOT NULL, UNIQUE("user_id", "name") PRIMARY KEY("id"))`,
+		`INSERT INTO users VALUES (1, 'John', 'Ide', 'John Ide', 'ide.johnc@gmail.com', 1860.99)`,
+		`INSERT INTO accounts VALUES (1, 1, 'Car Payment', 'monthly', 217.99, 0.1, 21000, '10', 'ford.com')`,
+	)
+
+	if err := migrations.New(db, migrations.SQLite).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var income, minimum, current, full int64
+	if err := db.QueryRow(`SELECT biweekly_income FROM users WHERE id = 1`).Scan(&income); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT minimum_payment, current_payment, full_amount FROM accounts WHERE id = 1`).Scan(&minimum, &current, &full); err != nil {
+		t.Fatal(err)
+	}
+
+	if income != 186099 || minimum != 21799 || current != 10 || full != 2100000 {
+		t.Errorf("\nCents:\n\tGot: \t\t%d %d %d %d\n\tExpected: \t%d %d %d %d\n", income, minimum, current, full, 186099, 21799, 10, 2100000)
+	}
+}
+
+func TestUpReportsOrphans(t *testing.T) {
+	db := openTestDB(t)
+	m := migrations.New(db, migrations.SQLite)
+	m.Migrations = m.Migrations[:3]
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts whose user is gone and a transaction whose account is gone,
+	// left behind before the foreign keys were enforced
+	exec(t, db,
+		`INSERT INTO users (id, first_name, last_name, full_name, email, biweekly_income) VALUES (1, 'John', 'Ide', 'John Ide', 'ide.johnc@gmail.com', 186099)`,
+		`INSERT INTO accounts VALUES (1, 1, 'Car Payment', 'monthly', 21799, 10, 2100000, '10', 'ford.com')`,
+		`INSERT INTO accounts VALUES (2, 7, 'Phone', 'monthly', 5000, 5000, 5000, '3', '')`,
+		`INSERT INTO accounts VALUES (3, 7, 'Internet', 'monthly', 6000, 6000, 6000, '4', '')`,
+		`INSERT INTO transactions VALUES (1, 1, -21799, '2020-01-10', 'Ford', '', 'cleared')`,
+		`INSERT INTO transactions VALUES (2, 9, -100, '2020-01-11', 'Gone', '', 'cleared')`,
+	)
+
+	m = migrations.New(db, migrations.SQLite)
+	err := m.Up()
+	if err == nil {
+		t.Fatal("\nError:\n\tGot: \t\tnil\n\tExpected: \tthe orphaned rows\n")
+	}
+	for _, expected := range []string{"migration 4", "accounts (missing users): 2, 3", "transactions (missing accounts): 2"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \tto contain %q\n", err, expected)
+		}
+	}
+
+	// Nothing was deleted and the database stays at the last good version
+	var accounts, transactions int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM accounts`).Scan(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&transactions); err != nil {
+		t.Fatal(err)
+	}
+	if accounts != 3 || transactions != 2 {
+		t.Errorf("\nRows:\n\tGot: \t\t%d %d\n\tExpected: \t%d %d\n", accounts, transactions, 3, 2)
+	}
+	if version, _ := m.Version(); version != 3 {
+		t.Errorf("\nVersion:\n\tGot: \t\t%d\n\tExpected: \t%d\n", version, 3)
+	}
+
+	// Once the operator deals with them the migration goes through
+	exec(t, db, `UPDATE accounts SET user_id = 1, name = name || ' (recovered)' WHERE user_id = 7`, `DELETE FROM transactions WHERE id = 2`)
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpRefusesNewerSchema(t *testing.T) {
+	db := openTestDB(t)
+	m := migrations.New(db, migrations.SQLite)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from the future', '')`)
+
+	if err := m.Up(); err != migrations.ErrSchemaTooNew {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, migrations.ErrSchemaTooNew)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db := openTestDB(t)
+	m := migrations.New(db, migrations.SQLite)
+
+	var out bytes.Buffer
+	m.DryRun = true
+	m.Out = &out
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), `CREATE TABLE IF NOT EXISTS "users"`) {
+		t.Errorf("dry run did not print the pending SQL:\n%s", out.String())
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Errorf("dry run applied migrations, database is at version %d", version)
+	}
+}
+
+func TestDown(t *testing.T) {
+	db := openTestDB(t)
+	m := migrations.New(db, migrations.SQLite)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, `INSERT INTO users (id, first_name, last_name, full_name, email, biweekly_income) VALUES (1, 'John', 'Ide', 'John Ide', 'ide.johnc@gmail.com', 186099)`)
+
+	if err := m.Down(1); err != nil {
+		t.Fatal(err)
+	}
+
+	var income float64
+	if err := db.QueryRow(`SELECT biweekly_income FROM users WHERE id = 1`).Scan(&income); err != nil {
+		t.Fatal(err)
+	}
+	if income != 1860.99 {
+		t.Errorf("\nIncome:\n\tGot: \t\t%v\n\tExpected: \t%v\n", income, 1860.99)
+	}
+
+	if err := m.Down(0); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := m.Version(); version != 0 {
+		t.Errorf("\nVersion:\n\tGot: \t\t%d\n\tExpected: \t%d\n", version, 0)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dialect  migrations.Dialect
+		query    string
+		expected string
+	}{
+		{migrations.SQLiteDialect, `SELECT * FROM users WHERE id = ? AND email = ?`, `SELECT * FROM users WHERE id = ? AND email = ?`},
+		{migrations.PostgresDialect, `SELECT * FROM users WHERE id = ? AND email = ?`, `SELECT * FROM users WHERE id = $1 AND email = $2`},
+		{migrations.PostgresDialect, `SELECT '?', "a?" FROM users WHERE payday = 'it''s ?' AND id = ?`, `SELECT '?', "a?" FROM users WHERE payday = 'it''s ?' AND id = $1`},
+	}
+
+	for _, test := range tests {
+		if got := test.dialect.Rebind(test.query); got != test.expected {
+			t.Errorf("\nRebind:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package migrations
+
+// Postgres is the ordered list of migrations for PostgreSQL databases. Postgres
+// support arrived with the SQLite schema at version 10, so it starts there with
+// the whole schema at once; later changes get the same version in both lists.
+// Never edit a migration once it has shipped; add a new one instead.
+var Postgres = []Migration{
+	{
+		Version: 10,
+		Name:    "create schema",
+		Up: `
+	CREATE TABLE "users" (
+		"id" SERIAL,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" BIGINT NOT NULL,
+		"password_hash" TEXT NOT NULL DEFAULT '',
+		"version" INTEGER NOT NULL DEFAULT 1,
+		"payday" TEXT NOT NULL DEFAULT '',
+
+		PRIMARY KEY("id")
+	);
+
+	CREATE TABLE "accounts" (
+		"id" SERIAL,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" BIGINT NOT NULL,
+		"current_payment" BIGINT NOT NULL,
+		"full_amount" BIGINT NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+		"version" INTEGER NOT NULL DEFAULT 1,
+		"anchor_date" TEXT NOT NULL DEFAULT '',
+		"apr" INTEGER NOT NULL DEFAULT 0,
+
+		UNIQUE("user_id", "name"),
+		PRIMARY KEY("id"),
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+
+	CREATE TABLE "transactions" (
+		"id" SERIAL,
+		"account_id" INTEGER NOT NULL,
+		"amount" BIGINT NOT NULL,
+		"posted_date" TEXT NOT NULL,
+		"payee" TEXT NOT NULL,
+		"memo" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+		"import_id" TEXT NOT NULL DEFAULT '',
+
+		PRIMARY KEY("id"),
+		FOREIGN KEY("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE
+	);
+	CREATE INDEX "transactions_account_id" ON "transactions" ("account_id", "posted_date");
+	CREATE UNIQUE INDEX "transactions_import_id"
+	ON "transactions" ("account_id", "import_id") WHERE "import_id" <> '';
+
+	CREATE TABLE "sessions" (
+		"token_hash" TEXT NOT NULL,
+		"user_id" INTEGER NOT NULL,
+		"created_at" BIGINT NOT NULL,
+		"expires_at" BIGINT NOT NULL,
+
+		PRIMARY KEY("token_hash"),
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+	);
+	CREATE INDEX "sessions_user_id" ON "sessions" ("user_id")`,
+		Down: `
+	DROP TABLE "sessions";
+	DROP TABLE "transactions";
+	DROP TABLE "accounts";
+	DROP TABLE "users"`,
+	},
+	{
+		Version: 11,
+		Name:    "add currencies",
+		Up: `
+	ALTER TABLE "users" ADD COLUMN "currency" TEXT NOT NULL DEFAULT 'USD';
+	ALTER TABLE "accounts" ADD COLUMN "currency" TEXT NOT NULL DEFAULT 'USD'`,
+		Down: `
+	ALTER TABLE "accounts" DROP COLUMN "currency";
+	ALTER TABLE "users" DROP COLUMN "currency"`,
+	},
+}
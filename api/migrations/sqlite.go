@@ -0,0 +1,479 @@
+package migrations
+
+// SQLite is the ordered list of migrations for SQLite databases. Never edit
+// a migration once it has shipped; add a new one instead.
+var SQLite = []Migration{
+	{
+		Version: 1,
+		Name:    "create users and accounts",
+		// IF NOT EXISTS adopts databases created before migrations existed
+		Up: `
+	CREATE TABLE IF NOT EXISTS "users" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" REAL NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	CREATE TABLE IF NOT EXISTS "accounts" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" REAL NOT NULL,
+		"current_payment" REAL NOT NULL,
+		"full_amount" REAL NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+	)`,
+		Down: `
+	DROP TABLE "accounts";
+	DROP TABLE "users"`,
+	},
+	{
+		Version: 2,
+		Name:    "store money as integer cents",
+		// Values that are already integers were written by a build that stored
+		// cents before this migration existed, so only REAL values are scaled
+		Up: `
+	CREATE TABLE "users_new" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" INTEGER NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_new"
+	SELECT id, first_name, last_name, full_name, email,
+		CASE WHEN typeof(biweekly_income) = 'real' THEN CAST(ROUND(biweekly_income * 100) AS INTEGER) ELSE biweekly_income END
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_new" RENAME TO "users";
+
+	CREATE TABLE "accounts_new" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "accounts_new"
+	SELECT id, user_id, name, account_type,
+		CASE WHEN typeof(minimum_payment) = 'real' THEN CAST(ROUND(minimum_payment * 100) AS INTEGER) ELSE minimum_payment END,
+		CASE WHEN typeof(current_payment) = 'real' THEN CAST(ROUND(current_payment * 100) AS INTEGER) ELSE current_payment END,
+		CASE WHEN typeof(full_amount) = 'real' THEN CAST(ROUND(full_amount * 100) AS INTEGER) ELSE full_amount END,
+		due_date, url
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_new" RENAME TO "accounts"`,
+		Down: `
+	CREATE TABLE "users_old" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" REAL NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_old"
+	SELECT id, first_name, last_name, full_name, email, biweekly_income / 100.0
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_old" RENAME TO "users";
+
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" REAL NOT NULL,
+		"current_payment" REAL NOT NULL,
+		"full_amount" REAL NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "accounts_old"
+	SELECT id, user_id, name, account_type, minimum_payment / 100.0, current_payment / 100.0, full_amount / 100.0, due_date, url
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts"`,
+	},
+	{
+		Version: 3,
+		Name:    "create transactions",
+		Up: `
+	CREATE TABLE IF NOT EXISTS "transactions" (
+		"id" INTEGER,
+		"account_id" INTEGER NOT NULL,
+		"amount" INTEGER NOT NULL,
+		"posted_date" TEXT NOT NULL,
+		"payee" TEXT NOT NULL,
+		"memo" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	CREATE INDEX IF NOT EXISTS "transactions_account_id"
+	ON "transactions" ("account_id", "posted_date")`,
+		Down: `
+	DROP TABLE "transactions"`,
+	},
+	{
+		Version:            4,
+		Name:               "add account and transaction foreign keys",
+		DisableForeignKeys: true,
+		// Rows that already point at a deleted parent fail the foreign key
+		// check and are listed in the error, so the operator can decide what
+		// to do with them before migrating again
+		Up: `
+	CREATE TABLE "accounts_new" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+	INSERT INTO "accounts_new" SELECT * FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_new" RENAME TO "accounts";
+
+	CREATE TABLE "transactions_new" (
+		"id" INTEGER,
+		"account_id" INTEGER NOT NULL,
+		"amount" INTEGER NOT NULL,
+		"posted_date" TEXT NOT NULL,
+		"payee" TEXT NOT NULL,
+		"memo" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+
+		PRIMARY KEY("id")
+		FOREIGN KEY("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE
+	);
+	INSERT INTO "transactions_new" SELECT * FROM "transactions";
+	DROP TABLE "transactions";
+	ALTER TABLE "transactions_new" RENAME TO "transactions";
+	CREATE INDEX "transactions_account_id" ON "transactions" ("account_id", "posted_date")`,
+		Down: `
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "accounts_old" SELECT * FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts";
+
+	CREATE TABLE "transactions_old" (
+		"id" INTEGER,
+		"account_id" INTEGER NOT NULL,
+		"amount" INTEGER NOT NULL,
+		"posted_date" TEXT NOT NULL,
+		"payee" TEXT NOT NULL,
+		"memo" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "transactions_old" SELECT * FROM "transactions";
+	DROP TABLE "transactions";
+	ALTER TABLE "transactions_old" RENAME TO "transactions";
+	CREATE INDEX "transactions_account_id" ON "transactions" ("account_id", "posted_date")`,
+	},
+	{
+		Version:            5,
+		Name:               "add passwords and sessions",
+		DisableForeignKeys: true,
+		Up: `
+	ALTER TABLE "users" ADD COLUMN "password_hash" TEXT NOT NULL DEFAULT '';
+
+	CREATE TABLE "sessions" (
+		"token_hash" TEXT NOT NULL,
+		"user_id" INTEGER NOT NULL,
+		"created_at" INTEGER NOT NULL,
+		"expires_at" INTEGER NOT NULL,
+
+		PRIMARY KEY("token_hash")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+	);
+	CREATE INDEX "sessions_user_id" ON "sessions" ("user_id")`,
+		// SQLite can't drop a column, so users is rebuilt without it
+		Down: `
+	DROP TABLE "sessions";
+
+	CREATE TABLE "users_old" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" INTEGER NOT NULL,
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_old"
+	SELECT id, first_name, last_name, full_name, email, biweekly_income
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_old" RENAME TO "users"`,
+	},
+	{
+		Version:            6,
+		Name:               "add record versions",
+		DisableForeignKeys: true,
+		Up: `
+	ALTER TABLE "accounts" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE "users" ADD COLUMN "version" INTEGER NOT NULL DEFAULT 1`,
+		// SQLite can't drop a column, so both tables are rebuilt without it
+		Down: `
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+	INSERT INTO "accounts_old"
+	SELECT id, user_id, name, account_type, minimum_payment, current_payment, full_amount, due_date, url
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts";
+
+	CREATE TABLE "users_old" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" INTEGER NOT NULL,
+		"password_hash" TEXT NOT NULL DEFAULT '',
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_old"
+	SELECT id, first_name, last_name, full_name, email, biweekly_income, password_hash
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_old" RENAME TO "users"`,
+	},
+	{
+		Version:            7,
+		Name:               "add account anchor dates",
+		DisableForeignKeys: true,
+		Up: `
+	ALTER TABLE "accounts" ADD COLUMN "anchor_date" TEXT NOT NULL DEFAULT ''`,
+		// SQLite can't drop a column, so accounts is rebuilt without it
+		Down: `
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+		"version" INTEGER NOT NULL DEFAULT 1,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+	INSERT INTO "accounts_old"
+	SELECT id, user_id, name, account_type, minimum_payment, current_payment, full_amount, due_date, url, version
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts"`,
+	},
+	{
+		Version:            8,
+		Name:               "add user paydays",
+		DisableForeignKeys: true,
+		Up: `
+	ALTER TABLE "users" ADD COLUMN "payday" TEXT NOT NULL DEFAULT ''`,
+		// SQLite can't drop a column, so users is rebuilt without it
+		Down: `
+	CREATE TABLE "users_old" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" INTEGER NOT NULL,
+		"password_hash" TEXT NOT NULL DEFAULT '',
+		"version" INTEGER NOT NULL DEFAULT 1,
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_old"
+	SELECT id, first_name, last_name, full_name, email, biweekly_income, password_hash, version
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_old" RENAME TO "users"`,
+	},
+	{
+		Version:            9,
+		Name:               "add account interest rates",
+		DisableForeignKeys: true,
+		// Rates are stored in basis points, hundredths of a percent
+		Up: `
+	ALTER TABLE "accounts" ADD COLUMN "apr" INTEGER NOT NULL DEFAULT 0`,
+		// SQLite can't drop a column, so accounts is rebuilt without it
+		Down: `
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+		"version" INTEGER NOT NULL DEFAULT 1,
+		"anchor_date" TEXT NOT NULL DEFAULT '',
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+	INSERT INTO "accounts_old"
+	SELECT id, user_id, name, account_type, minimum_payment, current_payment, full_amount, due_date, url, version, anchor_date
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts"`,
+	},
+	{
+		Version:            10,
+		Name:               "add transaction import IDs",
+		DisableForeignKeys: true,
+		// An import ID is the FITID a bank gave a statement row, or a hash of the
+		// row when it had none. Typed-in transactions have none, so only non-empty
+		// IDs have to be unique within an account.
+		Up: `
+	ALTER TABLE "transactions" ADD COLUMN "import_id" TEXT NOT NULL DEFAULT '';
+	CREATE UNIQUE INDEX "transactions_import_id"
+	ON "transactions" ("account_id", "import_id") WHERE "import_id" <> ''`,
+		// SQLite can't drop a column, so transactions is rebuilt without it
+		Down: `
+	DROP INDEX "transactions_import_id";
+	CREATE TABLE "transactions_old" (
+		"id" INTEGER,
+		"account_id" INTEGER NOT NULL,
+		"amount" INTEGER NOT NULL,
+		"posted_date" TEXT NOT NULL,
+		"payee" TEXT NOT NULL,
+		"memo" TEXT NOT NULL,
+		"status" TEXT NOT NULL,
+
+		PRIMARY KEY("id")
+		FOREIGN KEY("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE
+	);
+	INSERT INTO "transactions_old"
+	SELECT id, account_id, amount, posted_date, payee, memo, status
+	FROM "transactions";
+	DROP TABLE "transactions";
+	ALTER TABLE "transactions_old" RENAME TO "transactions";
+	CREATE INDEX "transactions_account_id" ON "transactions" ("account_id", "posted_date")`,
+	},
+	{
+		Version:            11,
+		Name:               "add currencies",
+		DisableForeignKeys: true,
+		// Amounts are in the currency of the user or account they belong to, and
+		// transactions are in the currency of their account
+		Up: `
+	ALTER TABLE "users" ADD COLUMN "currency" TEXT NOT NULL DEFAULT 'USD';
+	ALTER TABLE "accounts" ADD COLUMN "currency" TEXT NOT NULL DEFAULT 'USD'`,
+		// SQLite can't drop a column, so both tables are rebuilt without it
+		Down: `
+	CREATE TABLE "users_old" (
+		"id" INTEGER,
+		"first_name" TEXT NOT NULL,
+		"last_name" TEXT NOT NULL,
+		"full_name" TEXT NOT NULL,
+		"email" TEXT NOT NULL UNIQUE,
+		"biweekly_income" INTEGER NOT NULL,
+		"password_hash" TEXT NOT NULL DEFAULT '',
+		"version" INTEGER NOT NULL DEFAULT 1,
+		"payday" TEXT NOT NULL DEFAULT '',
+
+		PRIMARY KEY("id")
+	);
+	INSERT INTO "users_old"
+	SELECT id, first_name, last_name, full_name, email, biweekly_income, password_hash, version, payday
+	FROM "users";
+	DROP TABLE "users";
+	ALTER TABLE "users_old" RENAME TO "users";
+
+	CREATE TABLE "accounts_old" (
+		"id" INTEGER,
+		"user_id" INTEGER NOT NULL,
+		"name" TEXT NOT NULL,
+		"account_type" TEXT NOT NULL,
+		"minimum_payment" INTEGER NOT NULL,
+		"current_payment" INTEGER NOT NULL,
+		"full_amount" INTEGER NOT NULL,
+		"due_date" TEXT NOT NULL,
+		"url" TEXT NOT NULL,
+		"version" INTEGER NOT NULL DEFAULT 1,
+		"anchor_date" TEXT NOT NULL DEFAULT '',
+		"apr" INTEGER NOT NULL DEFAULT 0,
+
+		UNIQUE("user_id", "name")
+		PRIMARY KEY("id")
+		FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE RESTRICT
+	);
+	INSERT INTO "accounts_old"
+	SELECT id, user_id, name, account_type, minimum_payment, current_payment, full_amount, due_date, url, version, anchor_date, apr
+	FROM "accounts";
+	DROP TABLE "accounts";
+	ALTER TABLE "accounts_old" RENAME TO "accounts"`,
+	},
+}
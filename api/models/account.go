@@ -1,26 +1,79 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"regexp"
+	"time"
 )
 
-// Account is an account a User wants to track
+// Account is an account a User wants to track. Its amounts are all in its
+// Currency, as are its transactions.
 type Account struct {
-	ID             int     `json:"ID"`
-	UserID         int     `json:"userID"`
-	Name           string  `json:"name"`
-	AccountType    string  `json:"accountType"`
-	MinimumPayment float64 `json:"minimumPayment"`
-	CurrentPayment float64 `json:"currentPayment"`
-	FullAmount     float64 `json:"fullAmount"`
-	DueDate        string  `json:"dueDate"`
-	URL            string  `json:"URL"`
+	ID             int    `json:"ID"`
+	UserID         int    `json:"userID"`
+	Name           string `json:"name"`
+	AccountType    string `json:"accountType"`
+	MinimumPayment Money  `json:"minimumPayment"`
+	CurrentPayment Money  `json:"currentPayment"`
+	FullAmount     Money  `json:"fullAmount"`
+	Currency       string `json:"currency"`
+	APR            Rate   `json:"apr"`
+	DueDate        string `json:"dueDate"`
+	AnchorDate     string `json:"anchorDate"`
+	URL            string `json:"URL"`
+	Version        int    `json:"-"`
+}
+
+// accountColumns is the column list matching scanAccount
+const accountColumns = "id, user_id, name, account_type, minimum_payment, current_payment, full_amount, currency, apr, due_date, anchor_date, url, version"
+
+// scanAccount scans a row selected with accountColumns into an Account
+func scanAccount(row interface{ Scan(...interface{}) error }) (*Account, error) {
+	account := new(Account)
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Name,
+		&account.AccountType,
+		&account.MinimumPayment,
+		&account.CurrentPayment,
+		&account.FullAmount,
+		&account.Currency,
+		&account.APR,
+		&account.DueDate,
+		&account.AnchorDate,
+		&account.URL,
+		&account.Version)
+	account.SetCurrency(account.Currency)
+
+	return account, err
+}
+
+// SetCurrency sets the currency of the account and of each of its amounts. An
+// empty code sets DefaultCurrency.
+func (a *Account) SetCurrency(code string) {
+	a.Currency = currencyOrDefault(code)
+	a.MinimumPayment.Currency = a.Currency
+	a.CurrentPayment.Currency = a.Currency
+	a.FullAmount.Currency = a.Currency
+}
+
+// UnmarshalJSON decodes an account, putting its amounts in its currency
+func (a *Account) UnmarshalJSON(b []byte) error {
+	type account Account
+	if err := json.Unmarshal(b, (*account)(a)); err != nil {
+		return err
+	}
+
+	a.SetCurrency(a.Currency)
+	return nil
 }
 
 // AllAccounts retrieves all account rows from the accounts table
-func (db *DB) AllAccounts() ([]*Account, error) {
-	rows, err := db.Query("SELECT * FROM accounts")
+func (db *DB) AllAccounts(ctx context.Context) ([]*Account, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts")
 	if err != nil {
 		return nil, err
 	}
@@ -28,18 +81,31 @@ func (db *DB) AllAccounts() ([]*Account, error) {
 
 	accounts := make([]*Account, 0)
 	for rows.Next() {
-		account := new(Account)
-		err := rows.Scan(
-			&account.ID,
-			&account.UserID,
-			&account.Name,
-			&account.AccountType,
-			&account.MinimumPayment,
-			&account.CurrentPayment,
-			&account.FullAmount,
-			&account.DueDate,
-			&account.URL)
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
 
+// UserAccounts retrieves the account rows that belong to a user
+func (db *DB) UserAccounts(ctx context.Context, userID int) ([]*Account, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]*Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -53,56 +119,170 @@ func (db *DB) AllAccounts() ([]*Account, error) {
 	return accounts, nil
 }
 
-// Validate validates the fields in an Account object
-func (a *Account) Validate() bool {
+// AccountFilter narrows the accounts ListAccounts returns. Zero values don't filter.
+type AccountFilter struct {
+	UserID        int
+	AccountType   string
+	DueDate       string
+	MinFullAmount *Money
+	MaxFullAmount *Money
+}
+
+// accountSortColumns are the account fields ListAccounts can sort by
+var accountSortColumns = map[string]string{
+	"ID":             "id",
+	"userID":         "user_id",
+	"name":           "name",
+	"accountType":    "account_type",
+	"minimumPayment": "minimum_payment",
+	"currentPayment": "current_payment",
+	"fullAmount":     "full_amount",
+	"apr":            "apr",
+	"dueDate":        "CAST(due_date AS INTEGER)",
+}
+
+// ListAccounts retrieves one page of the account rows matching filter, along with
+// the number of rows that match across all pages
+func (db *DB) ListAccounts(ctx context.Context, filter AccountFilter, opts ListOptions) ([]*Account, int, error) {
+	where := new(whereClause)
+	if filter.UserID != 0 {
+		where.add("user_id = ?", filter.UserID)
+	}
+	if filter.AccountType != "" {
+		where.add("account_type = ?", filter.AccountType)
+	}
+	if filter.DueDate != "" {
+		where.add("due_date = ?", filter.DueDate)
+	}
+	if filter.MinFullAmount != nil {
+		where.add("full_amount >= ?", filter.MinFullAmount.Amount)
+	}
+	if filter.MaxFullAmount != nil {
+		where.add("full_amount <= ?", filter.MaxFullAmount.Amount)
+	}
+
+	order, err := orderBy(opts, accountSortColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM accounts"+where.String(), where.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts"+where.String()+order, where.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	accounts := make([]*Account, 0)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return accounts, total, nil
+}
+
+// Validate validates the fields in an Account object, returning a *ValidationError
+// listing every field that is wrong
+func (a *Account) Validate() error {
 	namePattern := regexp.MustCompile(`^[a-zA-Z ]+$`)
 	typePattern := regexp.MustCompile(`^(daily|weekly|biweekly|monthly|yearly)$`)
 	datePattern := regexp.MustCompile(`^([1-9]|[12]\d|3[01])$`)
 
+	v := new(ValidationError)
+
 	if a.UserID < 1 {
-		return false
+		v.Add("userID", "required", "must be the ID of an existing user")
 	}
 
 	if !namePattern.MatchString(a.Name) {
-		return false
+		v.Add("name", "pattern", "must only contain letters and spaces")
 	}
 
 	if !typePattern.MatchString(a.AccountType) {
-		return false
+		v.Add("accountType", "oneOf", "must be one of daily, weekly, biweekly, monthly or yearly")
 	}
 
-	if a.MinimumPayment > a.FullAmount {
-		return false
+	currency := currencyOrDefault(a.Currency)
+	if !currencyPattern.MatchString(currency) {
+		v.Add("currency", "pattern", "must be a three letter ISO 4217 currency code")
 	}
 
-	if a.CurrentPayment > a.FullAmount {
-		return false
+	amounts := []struct {
+		field  string
+		amount Money
+	}{
+		{"minimumPayment", a.MinimumPayment},
+		{"currentPayment", a.CurrentPayment},
+		{"fullAmount", a.FullAmount},
+	}
+	inCurrency := true
+	for _, m := range amounts {
+		if m.amount.currency() != currency {
+			v.Add(m.field, "currency", "must be in the account's currency")
+			inCurrency = false
+		}
+	}
+
+	if inCurrency && a.MinimumPayment.Cmp(a.FullAmount) > 0 {
+		v.Add("minimumPayment", "lteFullAmount", "must not exceed fullAmount")
+	}
+
+	if inCurrency && a.CurrentPayment.Cmp(a.FullAmount) > 0 {
+		v.Add("currentPayment", "lteFullAmount", "must not exceed fullAmount")
+	}
+
+	if a.APR < 0 || a.APR > 10000 {
+		v.Add("apr", "range", "must be a percentage from 0 to 100")
 	}
 
 	if !datePattern.MatchString(a.DueDate) {
-		return false
+		v.Add("dueDate", "range", "must be a day of the month from 1 to 31")
 	}
 
-	return true
+	if a.AnchorDate != "" {
+		if _, err := time.Parse("2006-01-02", a.AnchorDate); err != nil {
+			v.Add("anchorDate", "date", "must be a date formatted as YYYY-MM-DD")
+		}
+	}
+
+	return v.Err()
 }
 
 // GetAccount retrieves an account that matches the accountID parameter
 // from the accounts table, otherwise will return nothing.
-func (db *DB) GetAccount(accountID int) (*Account, error) {
-	row := db.QueryRow("SELECT * FROM accounts WHERE id = ?", accountID)
+func (db *DB) GetAccount(ctx context.Context, accountID int) (*Account, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ?", accountID)
 
-	account := new(Account)
-	err := row.Scan(
-		&account.ID,
-		&account.UserID,
-		&account.Name,
-		&account.AccountType,
-		&account.MinimumPayment,
-		&account.CurrentPayment,
-		&account.FullAmount,
-		&account.DueDate,
-		&account.URL)
+	account, err := scanAccount(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
 
+// GetUserAccount retrieves an account that matches the accountID parameter and
+// belongs to the user, otherwise will return nothing. An account owned by a
+// different user is reported as not found.
+func (db *DB) GetUserAccount(ctx context.Context, userID int, accountID int) (*Account, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ? AND user_id = ?", accountID, userID)
+
+	account, err := scanAccount(row)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -113,29 +293,27 @@ func (db *DB) GetAccount(accountID int) (*Account, error) {
 }
 
 // CreateAccount creates an account in the database and returns the account in JSON in the response
-func (db *DB) CreateAccount(a Account) (*Account, error) {
-	result, err := db.Exec(`
-		INSERT INTO accounts (user_id, name, account_type, minimum_payment, current_payment, full_amount, due_date, url)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+func (db *DB) CreateAccount(ctx context.Context, a Account) (*Account, error) {
+	id, err := db.insert(ctx, `
+		INSERT INTO accounts (user_id, name, account_type, minimum_payment, current_payment, full_amount, currency, apr, due_date, anchor_date, url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		a.UserID,
 		a.Name,
 		a.AccountType,
 		a.MinimumPayment,
 		a.CurrentPayment,
 		a.FullAmount,
+		currencyOrDefault(a.Currency),
+		a.APR,
 		a.DueDate,
+		a.AnchorDate,
 		a.URL,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
-	account, err := db.GetAccount(int(id))
+	account, err := db.GetAccount(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -143,9 +321,11 @@ func (db *DB) CreateAccount(a Account) (*Account, error) {
 	return account, nil
 }
 
-// UpdateAccount updates a full resource in the database and returns an error if something goes wrong
-func (db *DB) UpdateAccount(accountID int, a *Account) error {
-	_, err := db.Exec(`
+// UpdateAccount updates a full resource in the database and returns an error if something goes wrong.
+// A non-zero a.Version makes the update conditional on the row still being at that
+// version, returning ErrVersionConflict if it isn't.
+func (db *DB) UpdateAccount(ctx context.Context, accountID int, a *Account) error {
+	query := `
 		UPDATE accounts
 		SET
 			user_id = ?,
@@ -154,34 +334,122 @@ func (db *DB) UpdateAccount(accountID int, a *Account) error {
 			minimum_payment = ?,
 			current_payment = ?,
 			full_amount = ?,
+			currency = ?,
+			apr = ?,
 			due_date = ?,
-			url = ?
-		WHERE id = ?`,
+			anchor_date = ?,
+			url = ?,
+			version = version + 1
+		WHERE id = ?`
+	args := []interface{}{
 		a.UserID,
 		a.Name,
 		a.AccountType,
 		a.MinimumPayment,
 		a.CurrentPayment,
 		a.FullAmount,
+		currencyOrDefault(a.Currency),
+		a.APR,
 		a.DueDate,
+		a.AnchorDate,
 		a.URL,
-		accountID)
+		accountID,
+	}
 
-	if err != nil {
-		return err
+	return db.updateVersioned(ctx, query, args, a.Version)
+}
+
+// accountColumnsByField maps the JSON names of the Account fields that can be patched to their columns
+var accountColumnsByField = map[string]string{
+	"userID":         "user_id",
+	"name":           "name",
+	"accountType":    "account_type",
+	"minimumPayment": "minimum_payment",
+	"currentPayment": "current_payment",
+	"fullAmount":     "full_amount",
+	"currency":       "currency",
+	"apr":            "apr",
+	"dueDate":        "due_date",
+	"anchorDate":     "anchor_date",
+	"URL":            "url",
+}
+
+// Changes lists the JSON names of the fields that differ between a and b. Amounts
+// are compared without their currency, which is reported as the currency field.
+func (a *Account) Changes(b *Account) []string {
+	fields := make([]string, 0)
+	if a.UserID != b.UserID {
+		fields = append(fields, "userID")
+	}
+	if a.Name != b.Name {
+		fields = append(fields, "name")
+	}
+	if a.AccountType != b.AccountType {
+		fields = append(fields, "accountType")
+	}
+	if a.MinimumPayment.Amount != b.MinimumPayment.Amount {
+		fields = append(fields, "minimumPayment")
+	}
+	if a.CurrentPayment.Amount != b.CurrentPayment.Amount {
+		fields = append(fields, "currentPayment")
+	}
+	if a.FullAmount.Amount != b.FullAmount.Amount {
+		fields = append(fields, "fullAmount")
+	}
+	if currencyOrDefault(a.Currency) != currencyOrDefault(b.Currency) {
+		fields = append(fields, "currency")
+	}
+	if a.APR != b.APR {
+		fields = append(fields, "apr")
+	}
+	if a.DueDate != b.DueDate {
+		fields = append(fields, "dueDate")
+	}
+	if a.AnchorDate != b.AnchorDate {
+		fields = append(fields, "anchorDate")
+	}
+	if a.URL != b.URL {
+		fields = append(fields, "URL")
 	}
 
-	return nil
+	return fields
+}
+
+// PatchAccount updates only the columns of the named fields of an account, leaving
+// the rest of the row alone
+func (db *DB) PatchAccount(ctx context.Context, accountID int, a *Account, fields []string) error {
+	values := map[string]interface{}{
+		"userID":         a.UserID,
+		"name":           a.Name,
+		"accountType":    a.AccountType,
+		"minimumPayment": a.MinimumPayment,
+		"currentPayment": a.CurrentPayment,
+		"fullAmount":     a.FullAmount,
+		"currency":       currencyOrDefault(a.Currency),
+		"apr":            a.APR,
+		"dueDate":        a.DueDate,
+		"anchorDate":     a.AnchorDate,
+		"URL":            a.URL,
+	}
+
+	return db.patchRow(ctx, "accounts", accountID, a.Version, accountColumnsByField, values, fields)
 }
 
-// DeleteAccount removes a resource from the database and returns an error if something goes wrong
-func (db *DB) DeleteAccount(userID int) error {
-	result, err := db.Exec(`
+// DeleteAccount removes a resource from the database and returns an error if something goes wrong.
+// A non-zero version makes the delete conditional, returning ErrVersionConflict unless the
+// account is still at that version.
+func (db *DB) DeleteAccount(ctx context.Context, accountID int, version int) error {
+	query := `
 		DELETE
 		FROM accounts
-		WHERE id = ?`,
-		userID)
+		WHERE id = ?`
+	args := []interface{}{accountID}
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
 
+	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -191,7 +459,9 @@ func (db *DB) DeleteAccount(userID int) error {
 		return err
 	}
 
-	if rows < 1 {
+	if rows < 1 && version != 0 {
+		return ErrVersionConflict
+	} else if rows < 1 {
 		return ErrNotFound
 	}
 
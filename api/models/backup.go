@@ -0,0 +1,443 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"dinero/api/migrations"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BackupVersion is the version of the Backup document format. It changes when
+// the document changes in a way older builds can't restore.
+const BackupVersion = 1
+
+// Conflict modes say what Restore does with rows that already exist and differ
+const (
+	// ConflictFail restores nothing if any row conflicts
+	ConflictFail = "fail"
+	// ConflictSkip keeps the existing rows and restores the rest
+	ConflictSkip = "skip"
+	// ConflictOverwrite replaces the existing rows with the restored ones
+	ConflictOverwrite = "overwrite"
+)
+
+var (
+	// ErrRestoreConflict is returned by Restore in ConflictFail mode when rows conflict
+	ErrRestoreConflict = errors.New("error: backup conflicts with existing rows")
+	// ErrBadConflictMode is returned for conflict modes other than the ones above
+	ErrBadConflictMode = errors.New("error: unknown conflict mode")
+)
+
+// Backup is an export of users along with their accounts and transactions.
+// Password hashes and sessions are never exported.
+type Backup struct {
+	Version      int            `json:"version"`
+	ExportedAt   string         `json:"exportedAt"`
+	Users        []*User        `json:"users"`
+	Accounts     []*Account     `json:"accounts"`
+	Transactions []*Transaction `json:"transactions"`
+}
+
+// Validate validates a Backup as a whole: its version, every record in it, and
+// that accounts and transactions only belong to users and accounts in the backup.
+// Fields are named by where they are, like "accounts[2].dueDate".
+func (b *Backup) Validate() error {
+	v := new(ValidationError)
+
+	if b.Version != BackupVersion {
+		v.Add("version", "oneOf", fmt.Sprintf("must be %d", BackupVersion))
+	}
+
+	users := make(map[int]bool)
+	for i, u := range b.Users {
+		at := fmt.Sprintf("users[%d]", i)
+		addNested(v, at, u.Validate())
+		if u.ID < 1 || users[u.ID] {
+			v.Add(at+".ID", "unique", "must be a unique positive ID")
+		}
+		users[u.ID] = true
+	}
+
+	accounts := make(map[int]bool)
+	for i, a := range b.Accounts {
+		at := fmt.Sprintf("accounts[%d]", i)
+		addNested(v, at, a.Validate())
+		if a.ID < 1 || accounts[a.ID] {
+			v.Add(at+".ID", "unique", "must be a unique positive ID")
+		}
+		if !users[a.UserID] {
+			v.Add(at+".userID", "exists", "must be the ID of a user in the backup")
+		}
+		accounts[a.ID] = true
+	}
+
+	transactions := make(map[int]bool)
+	for i, t := range b.Transactions {
+		at := fmt.Sprintf("transactions[%d]", i)
+		addNested(v, at, t.Validate())
+		if t.ID < 1 || transactions[t.ID] {
+			v.Add(at+".ID", "unique", "must be a unique positive ID")
+		}
+		if !accounts[t.AccountID] {
+			v.Add(at+".accountID", "exists", "must be the ID of an account in the backup")
+		}
+		transactions[t.ID] = true
+	}
+
+	return v.Err()
+}
+
+// addNested adds the fields of a record's validation error to v, under the
+// record's place in the backup
+func addNested(v *ValidationError, at string, err error) {
+	if verr, ok := err.(*ValidationError); ok {
+		for _, field := range verr.Fields {
+			v.Add(at+"."+field.Field, field.Rule, field.Message)
+		}
+	}
+}
+
+// Export exports a user along with their accounts and transactions
+func (db *DB) Export(ctx context.Context, userID int) (*Backup, error) {
+	user, err := db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := db.UserAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.export(ctx, []*User{user}, accounts)
+}
+
+// ExportAll exports every user along with their accounts and transactions, for
+// backing up a whole database. The API only ever exports the caller, so this is
+// for running against the database directly.
+func (db *DB) ExportAll(ctx context.Context) (*Backup, error) {
+	users, err := db.AllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := db.AllAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.export(ctx, users, accounts)
+}
+
+// export builds a Backup of users and accounts, along with the accounts' transactions
+func (db *DB) export(ctx context.Context, users []*User, accounts []*Account) (*Backup, error) {
+	backup := &Backup{
+		Version:      BackupVersion,
+		ExportedAt:   time.Now().UTC().Format(time.RFC3339),
+		Users:        users,
+		Accounts:     accounts,
+		Transactions: make([]*Transaction, 0),
+	}
+
+	for _, account := range accounts {
+		transactions, err := db.AccountTransactions(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+		backup.Transactions = append(backup.Transactions, transactions...)
+	}
+
+	return backup, nil
+}
+
+// RestoreCounts counts restored rows by table
+type RestoreCounts struct {
+	Users        int `json:"users"`
+	Accounts     int `json:"accounts"`
+	Transactions int `json:"transactions"`
+}
+
+// Conflict is a row of a backup that differs from the row already in the database
+type Conflict struct {
+	Table  string `json:"table"`
+	ID     int    `json:"ID"`
+	Reason string `json:"reason"`
+}
+
+// RestoreReport is what Restore did, or in ConflictFail mode what it would have done
+type RestoreReport struct {
+	Created   RestoreCounts `json:"created"`
+	Updated   RestoreCounts `json:"updated"`
+	Unchanged RestoreCounts `json:"unchanged"`
+	Conflicts []Conflict    `json:"conflicts"`
+}
+
+// restorer restores one backup inside a database transaction
+type restorer struct {
+	tx     *Tx
+	mode   string
+	report *RestoreReport
+	// users and accounts are the IDs of the rows that are in the database once
+	// they've been restored, which are the only ones children can be restored under
+	users    map[int]bool
+	accounts map[int]bool
+}
+
+// Restore restores a validated Backup in a single database transaction. Rows are
+// matched by ID: missing rows are created, identical rows are left alone, and rows
+// that differ, or that would break a unique constraint, are conflicts handled
+// according to mode. Restored users that didn't exist have no password.
+func (db *DB) Restore(ctx context.Context, b *Backup, mode string) (*RestoreReport, error) {
+	switch mode {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+	default:
+		return nil, ErrBadConflictMode
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &restorer{
+		tx:       tx,
+		mode:     mode,
+		report:   &RestoreReport{Conflicts: make([]Conflict, 0)},
+		users:    make(map[int]bool),
+		accounts: make(map[int]bool),
+	}
+	err = r.restore(ctx, b)
+	if err == nil && mode == ConflictFail && len(r.report.Conflicts) > 0 {
+		err = ErrRestoreConflict
+	}
+	if err == nil && db.dialect == migrations.PostgresDialect {
+		err = resetSequences(ctx, tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		if err == ErrRestoreConflict {
+			return r.report, err
+		}
+		return nil, err
+	}
+
+	return r.report, tx.Commit()
+}
+
+// resetSequences moves the Postgres ID sequences past the IDs a restore gave rows
+// explicitly, which don't advance them, so new rows don't get an ID that's taken
+func resetSequences(ctx context.Context, tx *Tx) error {
+	for _, table := range []string{"users", "accounts", "transactions"} {
+		_, err := tx.ExecContext(ctx, `SELECT setval(pg_get_serial_sequence('`+table+`', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM `+table)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *restorer) restore(ctx context.Context, b *Backup) error {
+	for _, u := range b.Users {
+		if err := r.user(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range b.Accounts {
+		if err := r.account(ctx, a); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range b.Transactions {
+		if err := r.transaction(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// conflict records a conflicting row, returning whether the row should be written anyway
+func (r *restorer) conflict(table string, id int, reason string) bool {
+	r.report.Conflicts = append(r.report.Conflicts, Conflict{Table: table, ID: id, Reason: reason})
+	return r.mode == ConflictOverwrite
+}
+
+// taken returns the ID of a row other than id that already has the unique values
+// a query looks for, or 0 if there isn't one
+func (r *restorer) taken(ctx context.Context, query string, id int, args ...interface{}) (int, error) {
+	var other int
+	err := r.tx.QueryRowContext(ctx, query+" AND id <> ?", append(args, id)...).Scan(&other)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return other, err
+}
+
+func (r *restorer) user(ctx context.Context, u *User) error {
+	other, err := r.taken(ctx, "SELECT id FROM users WHERE email = ?", u.ID, u.Email)
+	if err != nil {
+		return err
+	}
+	if other != 0 {
+		// A unique constraint can't be overwritten, so this row is skipped whatever the mode
+		r.conflict("users", u.ID, fmt.Sprintf("email is already used by user %d", other))
+		return nil
+	}
+
+	existing, err := scanUser(r.tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", u.ID))
+	if err == nil || err == sql.ErrNoRows {
+		r.users[u.ID] = true
+	}
+	if err == sql.ErrNoRows {
+		_, err = r.tx.ExecContext(ctx, `
+			INSERT INTO users (id, first_name, last_name, full_name, email, biweekly_income, currency, payday)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			u.ID, u.FirstName, u.LastName, u.FullName, u.Email, u.BiweeklyIncome, currencyOrDefault(u.Currency), u.Payday)
+		r.report.Created.Users++
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	changes := existing.Changes(u)
+	if len(changes) == 0 {
+		r.report.Unchanged.Users++
+		return nil
+	}
+	if !r.conflict("users", u.ID, differs(changes)) {
+		return nil
+	}
+
+	_, err = r.tx.ExecContext(ctx, `
+		UPDATE users
+		SET first_name = ?, last_name = ?, full_name = ?, email = ?, biweekly_income = ?, currency = ?, payday = ?, version = version + 1
+		WHERE id = ?`,
+		u.FirstName, u.LastName, u.FullName, u.Email, u.BiweeklyIncome, currencyOrDefault(u.Currency), u.Payday, u.ID)
+	r.report.Updated.Users++
+	return err
+}
+
+func (r *restorer) account(ctx context.Context, a *Account) error {
+	if !r.users[a.UserID] {
+		r.conflict("accounts", a.ID, fmt.Sprintf("user %d wasn't restored", a.UserID))
+		return nil
+	}
+
+	other, err := r.taken(ctx, "SELECT id FROM accounts WHERE user_id = ? AND name = ?", a.ID, a.UserID, a.Name)
+	if err != nil {
+		return err
+	}
+	if other != 0 {
+		r.conflict("accounts", a.ID, fmt.Sprintf("name is already used by account %d", other))
+		return nil
+	}
+
+	existing, err := scanAccount(r.tx.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ?", a.ID))
+	if err == nil && existing.UserID != a.UserID {
+		// Rows are never taken from another user, whatever the mode
+		r.conflict("accounts", a.ID, "belongs to another user")
+		return nil
+	}
+	if err == nil || err == sql.ErrNoRows {
+		r.accounts[a.ID] = true
+	}
+	if err == sql.ErrNoRows {
+		_, err = r.tx.ExecContext(ctx, `
+			INSERT INTO accounts (id, user_id, name, account_type, minimum_payment, current_payment, full_amount, currency, apr, due_date, anchor_date, url)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.UserID, a.Name, a.AccountType, a.MinimumPayment, a.CurrentPayment, a.FullAmount, currencyOrDefault(a.Currency), a.APR, a.DueDate, a.AnchorDate, a.URL)
+		r.report.Created.Accounts++
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	changes := existing.Changes(a)
+	if len(changes) == 0 {
+		r.report.Unchanged.Accounts++
+		return nil
+	}
+	if !r.conflict("accounts", a.ID, differs(changes)) {
+		return nil
+	}
+
+	_, err = r.tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET user_id = ?, name = ?, account_type = ?, minimum_payment = ?, current_payment = ?, full_amount = ?,
+			currency = ?, apr = ?, due_date = ?, anchor_date = ?, url = ?, version = version + 1
+		WHERE id = ?`,
+		a.UserID, a.Name, a.AccountType, a.MinimumPayment, a.CurrentPayment, a.FullAmount, currencyOrDefault(a.Currency), a.APR, a.DueDate, a.AnchorDate, a.URL, a.ID)
+	r.report.Updated.Accounts++
+	return err
+}
+
+func (r *restorer) transaction(ctx context.Context, t *Transaction) error {
+	if !r.accounts[t.AccountID] {
+		r.conflict("transactions", t.ID, fmt.Sprintf("account %d wasn't restored", t.AccountID))
+		return nil
+	}
+
+	if t.ImportID != "" {
+		other, err := r.taken(ctx, "SELECT id FROM transactions WHERE account_id = ? AND import_id = ?", t.ID, t.AccountID, t.ImportID)
+		if err != nil {
+			return err
+		}
+		if other != 0 {
+			r.conflict("transactions", t.ID, fmt.Sprintf("importID is already used by transaction %d", other))
+			return nil
+		}
+	}
+
+	existing, err := scanTransaction(r.tx.QueryRowContext(ctx, "SELECT "+transactionColumns+transactionsFrom+" WHERE t.id = ?", t.ID))
+	if err == nil && existing.AccountID != t.AccountID {
+		r.conflict("transactions", t.ID, "belongs to another account")
+		return nil
+	}
+	if err == sql.ErrNoRows {
+		_, err = r.tx.ExecContext(ctx, `
+			INSERT INTO transactions (id, account_id, amount, posted_date, payee, memo, status, import_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.ID, t.AccountID, t.Amount, t.PostedDate, t.Payee, t.Memo, t.Status, t.ImportID)
+		r.report.Created.Transactions++
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// A transaction is in its account's currency rather than one of its own
+	restored := *t
+	restored.Amount.Currency = existing.Amount.Currency
+	if *existing == restored {
+		r.report.Unchanged.Transactions++
+		return nil
+	}
+	if !r.conflict("transactions", t.ID, "differs from the existing transaction") {
+		return nil
+	}
+
+	_, err = r.tx.ExecContext(ctx, `
+		UPDATE transactions
+		SET account_id = ?, amount = ?, posted_date = ?, payee = ?, memo = ?, status = ?, import_id = ?
+		WHERE id = ?`,
+		t.AccountID, t.Amount, t.PostedDate, t.Payee, t.Memo, t.Status, t.ImportID, t.ID)
+	r.report.Updated.Transactions++
+	return err
+}
+
+// differs describes the fields of a conflicting row
+func differs(fields []string) string {
+	reason := "differs in"
+	for i, field := range fields {
+		if i > 0 {
+			reason += ","
+		}
+		reason += " " + field
+	}
+	return reason
+}
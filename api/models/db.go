@@ -1,109 +1,260 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"dinero/api/migrations"
+	"strings"
+	"time"
 
-	// SQLite3 driver
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
+// SQLSTATE codes Postgres reports constraint violations with
 const (
-	usersTableStmt = `
-	CREATE TABLE IF NOT EXISTS "users" (
-		"id" INTEGER,
-		"first_name" TEXT NOT NULL,
-		"last_name" TEXT NOT NULL,
-		"full_name" TEXT NOT NULL,
-		"email" TEXT NOT NULL UNIQUE,
-		"biweekly_income" REAL NOT NULL,
-		
-		PRIMARY KEY("id")
-	)`
-	accountsTableStmt = `
-	CREATE TABLE IF NOT EXISTS "accounts" (
-		"id" INTEGER,
-		"user_id" INTEGER NOT NULL,
-		"name" TEXT NOT NULL,
-		"account_type" TEXT NOT NULL,
-		"minimum_payment" REAL NOT NULL,
-		"current_payment" REAL NOT NULL,
-		"full_amount" REAL NOT NULL,
-		"due_date" TEXT NOT NULL,
-		"url" TEXT NOT NULL,
-
-		UNIQUE("user_id", "name")
-		PRIMARY KEY("id")
-	)`
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
 )
 
-// Store is a general interface for a datastore (real vs mock)
+// Store is a general interface for a datastore (real vs mock). Every method takes
+// the context of the request it serves, so a cancelled request or one past its
+// deadline stops waiting on the database.
 type Store interface {
-	AllAccounts() ([]*Account, error)
-	GetAccount(int) (*Account, error)
-	CreateAccount(Account) (*Account, error)
-	UpdateAccount(int, *Account) error
-	DeleteAccount(int) error
-	AllUsers() ([]*User, error)
-	GetUser(int) (*User, error)
-	CreateUser(User) (*User, error)
-	UpdateUser(int, *User) error
-	DeleteUser(int) error
-}
-
-// DB is a general DB type for actual DB connections (vs mock DBs)
+	AllAccounts(context.Context) ([]*Account, error)
+	GetAccount(context.Context, int) (*Account, error)
+	CreateAccount(context.Context, Account) (*Account, error)
+	UpdateAccount(context.Context, int, *Account) error
+	PatchAccount(context.Context, int, *Account, []string) error
+	DeleteAccount(context.Context, int, int) error
+	UserAccounts(context.Context, int) ([]*Account, error)
+	GetUserAccount(context.Context, int, int) (*Account, error)
+	ListAccounts(context.Context, AccountFilter, ListOptions) ([]*Account, int, error)
+	AllUsers(context.Context) ([]*User, error)
+	ListUsers(context.Context, UserFilter, ListOptions) ([]*User, int, error)
+	GetUser(context.Context, int) (*User, error)
+	CreateUser(context.Context, User) (*User, error)
+	UpdateUser(context.Context, int, *User) error
+	PatchUser(context.Context, int, *User, []string) error
+	DeleteUser(context.Context, int, int) error
+	UserByEmail(context.Context, string) (*User, error)
+	CreateSession(context.Context, int, time.Duration) (*Session, error)
+	SessionUser(context.Context, string) (*User, error)
+	DeleteSession(context.Context, string) error
+	AccountTransactions(context.Context, int) ([]*Transaction, error)
+	GetTransaction(context.Context, int) (*Transaction, error)
+	CreateTransaction(context.Context, Transaction) (*Transaction, error)
+	UpdateTransaction(context.Context, int, *Transaction) error
+	DeleteTransaction(context.Context, int) error
+	AccountBalance(context.Context, int) (*Balance, error)
+	ImportedIDs(context.Context, int) (map[string]bool, error)
+	ImportTransactions(context.Context, int, []Transaction) ([]*Transaction, error)
+	Export(context.Context, int) (*Backup, error)
+	ExportAll(context.Context) (*Backup, error)
+	Restore(context.Context, *Backup, string) (*RestoreReport, error)
+}
+
+// DB is a general DB type for actual DB connections (vs mock DBs). Its ExecContext,
+// QueryContext, QueryRowContext and BeginTx take ? placeholders whichever database
+// it's connected to, and report constraint violations as ErrConflict and ErrForeignKey.
 type DB struct {
 	*sql.DB
+	// UserDeletePolicy decides what DeleteUser does with the user's accounts
+	UserDeletePolicy DeletePolicy
+
+	dialect migrations.Dialect
 }
 
-// InitDB initializes a database
-func InitDB(dbName string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbName)
+// isPostgres reports whether a DSN points at a PostgreSQL server rather than
+// naming a SQLite file
+func isPostgres(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// OpenDB opens a connection to the database without touching its schema.
+// postgres:// and postgresql:// URLs open PostgreSQL; anything else is the
+// path of a SQLite file, which has foreign key enforcement switched on for
+// every connection in the pool and is written through a write-ahead log.
+func OpenDB(dsn string) (*DB, error) {
+	driver, dialect := "sqlite3", migrations.SQLiteDialect
+	if isPostgres(dsn) {
+		driver, dialect = "postgres", migrations.PostgresDialect
+	} else if strings.Contains(dsn, "?") {
+		dsn += "&_foreign_keys=1&_journal_mode=WAL"
+	} else {
+		dsn += "?_foreign_keys=1&_journal_mode=WAL"
+	}
+
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
 	if err = db.Ping(); err != nil {
+		db.Close()
 		return nil, ErrBadPing
 	}
 
-	err = createUsersTable(db)
-	if err != nil {
-		panic(err)
+	return &DB{DB: db, dialect: dialect}, nil
+}
+
+// Close closes the database. SQLite's write-ahead log is checkpointed into the
+// database file first, so the file holds everything on its own once Dinero stops.
+func (db *DB) Close() error {
+	if db.dialect == migrations.SQLiteDialect {
+		if _, err := db.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			db.DB.Close()
+			return err
+		}
 	}
-	err = createAccountsTable(db)
+
+	return db.DB.Close()
+}
+
+// InitDB initializes a database, bringing its schema up to date. It refuses
+// to open a database that was migrated by a newer version of Dinero.
+func InitDB(dsn string) (*DB, error) {
+	db, err := OpenDB(dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	if err = db.Migrator().Up(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &DB{db}, nil
+	return db, nil
 }
 
-func createUsersTable(db *sql.DB) error {
-	stmt, err := db.Prepare(usersTableStmt)
-	if err != nil {
-		return err
+// Migrator returns a schema migrator for the database
+func (db *DB) Migrator() *migrations.Migrator {
+	list := migrations.SQLite
+	if db.dialect == migrations.PostgresDialect {
+		list = migrations.Postgres
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec()
+	m := migrations.New(db.DB, list)
+	m.Dialect = db.dialect
+	return m
+}
+
+// ExecContext executes a query without returning any rows
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := db.DB.ExecContext(ctx, db.dialect.Rebind(query), args...)
+	return result, translate(err)
+}
+
+// QueryContext executes a query that returns rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := db.DB.QueryContext(ctx, db.dialect.Rebind(query), args...)
+	return rows, translate(err)
+}
+
+// QueryRowContext executes a query that returns at most one row
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.dialect.Rebind(query), args...)
+}
+
+// BeginTx starts a database transaction, which is rolled back if ctx is done
+// before it's committed
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
 }
 
-func createAccountsTable(db *sql.DB) error {
-	stmt, err := db.Prepare(accountsTableStmt)
-	if err != nil {
-		return err
+// insert executes an INSERT and returns the ID of the row it created
+func (db *DB) insert(ctx context.Context, query string, args ...interface{}) (int, error) {
+	return insert(ctx, db, db.dialect, query, args...)
+}
+
+// Tx is a database transaction begun by a DB, taking the same placeholders
+type Tx struct {
+	*sql.Tx
+
+	dialect migrations.Dialect
+}
+
+// ExecContext executes a query without returning any rows
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := tx.Tx.ExecContext(ctx, tx.dialect.Rebind(query), args...)
+	return result, translate(err)
+}
+
+// QueryContext executes a query that returns rows
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := tx.Tx.QueryContext(ctx, tx.dialect.Rebind(query), args...)
+	return rows, translate(err)
+}
+
+// QueryRowContext executes a query that returns at most one row
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.dialect.Rebind(query), args...)
+}
+
+// insert executes an INSERT and returns the ID of the row it created
+func (tx *Tx) insert(ctx context.Context, query string, args ...interface{}) (int, error) {
+	return insert(ctx, tx, tx.dialect, query, args...)
+}
+
+// execer is what DB and Tx have in common
+type execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// insert executes an INSERT into a table with an id column and returns the ID of
+// the row it created. Postgres doesn't report the last insert ID, so it's asked
+// for with RETURNING instead.
+func insert(ctx context.Context, e execer, dialect migrations.Dialect, query string, args ...interface{}) (int, error) {
+	if dialect == migrations.PostgresDialect {
+		var id int
+		err := e.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, translate(err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec()
+	result, err := e.ExecContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// qualify prefixes each column of a column list like userColumns with the alias
+// of its table, for queries that join another table
+func qualify(alias string, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = alias + "." + name
+	}
+	return strings.Join(names, ", ")
+}
+
+// translate turns a driver's constraint violation errors into ErrConflict and
+// ErrForeignKey, so callers don't need to know which database they're using
+func translate(err error) error {
+	switch e := err.(type) {
+	case sqlite3.Error:
+		switch e.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return ErrConflict
+		case sqlite3.ErrConstraintForeignKey:
+			return ErrForeignKey
+		}
+	case *pq.Error:
+		switch e.Code {
+		case pqUniqueViolation:
+			return ErrConflict
+		case pqForeignKeyViolation:
+			return ErrForeignKey
+		}
 	}
 
-	return nil
+	return err
 }
@@ -1,6 +1,9 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	// ErrNotFound is an error creator for models where DB query results return nothing
@@ -8,4 +11,59 @@ var (
 	// ErrBadPing is an error creator for the DB model where the application errors in
 	// pinging the database
 	ErrBadPing = errors.New("error: cannot ping database")
+	// ErrHasDependents is an error creator for deletes that are refused because other
+	// records still reference the record being deleted
+	ErrHasDependents = errors.New("error: record is still referenced by other records")
+	// ErrBadDeletePolicy is an error creator for unknown or incomplete user delete policies
+	ErrBadDeletePolicy = errors.New("error: invalid user delete policy")
+	// ErrVersionConflict is an error creator for writes made against a version of a
+	// record that has since been changed by someone else
+	ErrVersionConflict = errors.New("error: record has been changed since it was read")
+	// ErrConflict is an error creator for writes that would give a record the same
+	// unique value as another one
+	ErrConflict = errors.New("error: record conflicts with an existing record")
+	// ErrForeignKey is an error creator for writes that reference a record that
+	// doesn't exist
+	ErrForeignKey = errors.New("error: record references a record that does not exist")
+	// ErrInvalidRate is an error creator for Money.Mul given no rate to multiply by
+	ErrInvalidRate = errors.New("error: invalid rate")
+	// ErrMoneyOverflow is an error creator for arithmetic whose result is too large
+	// to hold in Money
+	ErrMoneyOverflow = errors.New("error: money amount out of range")
 )
+
+// FieldError describes a single field that failed a validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by the Validate methods and lists every field of a
+// record that failed validation, in the order they were checked
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add records that field failed rule
+func (e *ValidationError) Add(field, rule, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// Err returns e if any field failed validation, otherwise nil
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field + " " + f.Message
+	}
+
+	return "error: invalid fields: " + strings.Join(fields, "; ")
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SortField is one column a list is ordered by, named by its JSON field
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions pages and orders the rows a List method returns. A Limit of zero
+// or less returns every row after Offset.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+}
+
+// ParseSort parses a sort query parameter like "dueDate,-fullAmount", where a
+// leading "-" sorts that field in descending order
+func ParseSort(s string) []SortField {
+	fields := make([]SortField, 0)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if strings.HasPrefix(field, "-") {
+			fields = append(fields, SortField{Field: field[1:], Desc: true})
+		} else {
+			fields = append(fields, SortField{Field: field})
+		}
+	}
+
+	return fields
+}
+
+// whereClause collects the conditions and arguments of a filtered query
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add adds a condition, with its placeholder arguments, to the clause
+func (w *whereClause) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+// String returns the WHERE clause, or nothing if there are no conditions
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// orderBy builds the ORDER BY and LIMIT clauses for opts. columns maps the JSON
+// field names that can be sorted on to the SQL expression to sort by. Rows are
+// always ordered by id last so pages are stable.
+func orderBy(opts ListOptions, columns map[string]string) (string, error) {
+	v := new(ValidationError)
+	order := make([]string, 0, len(opts.Sort)+1)
+	for _, field := range opts.Sort {
+		column, ok := columns[field.Field]
+		if !ok {
+			v.Add("sort", "oneOf", "cannot sort by "+field.Field)
+			continue
+		}
+
+		if field.Desc {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+	order = append(order, "id")
+
+	if err := v.Err(); err != nil {
+		return "", err
+	}
+
+	clause := " ORDER BY " + strings.Join(order, ", ")
+	if opts.Limit > 0 || opts.Offset > 0 {
+		// SQLite can't OFFSET without a LIMIT and Postgres has no -1 for no limit,
+		// so an unlimited page is limited to as many rows as either can count
+		limit := int64(opts.Limit)
+		if limit <= 0 {
+			limit = math.MaxInt64
+		}
+		clause += " LIMIT " + strconv.FormatInt(limit, 10) + " OFFSET " + strconv.Itoa(opts.Offset)
+	}
+
+	return clause, nil
+}
@@ -0,0 +1,1113 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps everything in memory, for tests and demos.
+// It enforces the same unique and foreign key constraints as the database
+// schema, returning ErrConflict and ErrForeignKey like DB does, and is safe for
+// concurrent use. Its methods never wait on anything but each other, so they
+// ignore their context.
+type MemoryStore struct {
+	// UserDeletePolicy decides what DeleteUser does with the user's accounts
+	UserDeletePolicy DeletePolicy
+
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// memoryData is the contents of a MemoryStore. Records are stored by value so
+// callers never share them with the store.
+type memoryData struct {
+	users        map[int]User
+	accounts     map[int]Account
+	transactions map[int]Transaction
+	sessions     map[string]memorySession
+	// lastID is the last ID given to a row of each table, like a sequence
+	lastID map[string]int
+}
+
+// memorySession is a stored session, keyed by the hash of its token
+type memorySession struct {
+	userID    int
+	expiresAt int64
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: &memoryData{
+		users:        make(map[int]User),
+		accounts:     make(map[int]Account),
+		transactions: make(map[int]Transaction),
+		sessions:     make(map[string]memorySession),
+		lastID:       make(map[string]int),
+	}}
+}
+
+// clone copies the data, so a restore can be thrown away if it fails
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		users:        make(map[int]User, len(d.users)),
+		accounts:     make(map[int]Account, len(d.accounts)),
+		transactions: make(map[int]Transaction, len(d.transactions)),
+		sessions:     make(map[string]memorySession, len(d.sessions)),
+		lastID:       make(map[string]int, len(d.lastID)),
+	}
+	for id, u := range d.users {
+		c.users[id] = u
+	}
+	for id, a := range d.accounts {
+		c.accounts[id] = a
+	}
+	for id, t := range d.transactions {
+		c.transactions[id] = t
+	}
+	for hash, s := range d.sessions {
+		c.sessions[hash] = s
+	}
+	for table, id := range d.lastID {
+		c.lastID[table] = id
+	}
+
+	return c
+}
+
+// nextID returns the ID for a new row in table
+func (d *memoryData) nextID(table string) int {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+// useID makes sure rows created later in table get an ID after id
+func (d *memoryData) useID(table string, id int) {
+	if id > d.lastID[table] {
+		d.lastID[table] = id
+	}
+}
+
+// putUser stores a user row as the database would, with their income in their currency
+func (d *memoryData) putUser(u User) {
+	u.SetCurrency(u.Currency)
+	d.users[u.ID] = u
+}
+
+// putAccount stores an account row as the database would, with its amounts in
+// its currency
+func (d *memoryData) putAccount(a Account) {
+	a.SetCurrency(a.Currency)
+	d.accounts[a.ID] = a
+}
+
+// putTransaction stores a transaction row as the database would, without a
+// currency of its own
+func (d *memoryData) putTransaction(t Transaction) {
+	t.Amount.Currency = ""
+	d.transactions[t.ID] = t
+}
+
+// transaction copies a stored transaction, in the currency of its account
+func (d *memoryData) transaction(id int) *Transaction {
+	t := d.transactions[id]
+	t.Amount.Currency = currencyOrDefault(d.accounts[t.AccountID].Currency)
+	return &t
+}
+
+// emailTaken returns the ID of a user other than id with the email, or 0
+func (d *memoryData) emailTaken(email string, id int) int {
+	for _, u := range d.users {
+		if u.Email == email && u.ID != id {
+			return u.ID
+		}
+	}
+	return 0
+}
+
+// nameTaken returns the ID of an account other than id that the user has given
+// the name, or 0
+func (d *memoryData) nameTaken(userID int, name string, id int) int {
+	for _, a := range d.accounts {
+		if a.UserID == userID && a.Name == name && a.ID != id {
+			return a.ID
+		}
+	}
+	return 0
+}
+
+// importIDTaken returns the ID of a transaction other than id in the account
+// with the import ID, or 0. Empty import IDs are never taken.
+func (d *memoryData) importIDTaken(accountID int, importID string, id int) int {
+	if importID == "" {
+		return 0
+	}
+	for _, t := range d.transactions {
+		if t.AccountID == accountID && t.ImportID == importID && t.ID != id {
+			return t.ID
+		}
+	}
+	return 0
+}
+
+// checkUser checks the constraints on a user row with the given ID
+func (d *memoryData) checkUser(u User, id int) error {
+	if d.emailTaken(u.Email, id) != 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// checkAccount checks the constraints on an account row with the given ID
+func (d *memoryData) checkAccount(a Account, id int) error {
+	if d.nameTaken(a.UserID, a.Name, id) != 0 {
+		return ErrConflict
+	}
+	if _, ok := d.users[a.UserID]; !ok {
+		return ErrForeignKey
+	}
+	return nil
+}
+
+// checkTransaction checks the constraints on a transaction row with the given ID
+func (d *memoryData) checkTransaction(t Transaction, id int) error {
+	if d.importIDTaken(t.AccountID, t.ImportID, id) != 0 {
+		return ErrConflict
+	}
+	if _, ok := d.accounts[t.AccountID]; !ok {
+		return ErrForeignKey
+	}
+	return nil
+}
+
+// checkVersion checks there's a row to update or delete at version, which when
+// non-zero must be the row's current version. As with the SQL stores, a missing
+// row is ErrVersionConflict with a version and ErrNotFound without one.
+func checkVersion(exists bool, current int, version int) error {
+	if version != 0 && (!exists || current != version) {
+		return ErrVersionConflict
+	} else if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkPatch checks a patch's fields can be patched and finds the row to patch,
+// returning ErrNotFound for a missing row when there's no version to conflict with
+func checkPatch(table string, columns map[string]string, fields []string, exists bool, current int, version int) error {
+	for _, field := range fields {
+		if _, ok := columns[field]; !ok {
+			return fmt.Errorf("error: %s has no field %s", table, field)
+		}
+	}
+
+	if version != 0 && (!exists || current != version) {
+		return ErrVersionConflict
+	} else if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// page sorts n records by opts.Sort, then by ID, and returns the bounds of the
+// page of them opts asks for. compare compares records i and j by a field.
+func page(n int, opts ListOptions, columns map[string]string, swap func(i, j int), compare func(field string, i, j int) int) (int, int, error) {
+	v := new(ValidationError)
+	for _, field := range opts.Sort {
+		if _, ok := columns[field.Field]; !ok {
+			v.Add("sort", "oneOf", "cannot sort by "+field.Field)
+		}
+	}
+	if err := v.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	sort.Sort(sorter{n: n, swap: swap, less: func(i, j int) bool {
+		for _, field := range opts.Sort {
+			if c := compare(field.Field, i, j); c != 0 {
+				return (c < 0) != field.Desc
+			}
+		}
+		return compare("ID", i, j) < 0
+	}})
+
+	start := opts.Offset
+	if start > n {
+		start = n
+	}
+	end := n
+	if opts.Limit > 0 && start+opts.Limit < n {
+		end = start + opts.Limit
+	}
+
+	return start, end, nil
+}
+
+// sorter adapts functions to sort.Interface
+type sorter struct {
+	n    int
+	swap func(i, j int)
+	less func(i, j int) bool
+}
+
+func (s sorter) Len() int           { return s.n }
+func (s sorter) Swap(i, j int)      { s.swap(i, j) }
+func (s sorter) Less(i, j int) bool { return s.less(i, j) }
+
+// compareInts compares two integers like strings.Compare
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareAccounts compares two accounts by a field ListAccounts can sort by
+func compareAccounts(field string, a, b *Account) int {
+	switch field {
+	case "userID":
+		return compareInts(int64(a.UserID), int64(b.UserID))
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "accountType":
+		return strings.Compare(a.AccountType, b.AccountType)
+	case "minimumPayment":
+		return compareInts(a.MinimumPayment.Amount, b.MinimumPayment.Amount)
+	case "currentPayment":
+		return compareInts(a.CurrentPayment.Amount, b.CurrentPayment.Amount)
+	case "fullAmount":
+		return compareInts(a.FullAmount.Amount, b.FullAmount.Amount)
+	case "apr":
+		return compareInts(int64(a.APR), int64(b.APR))
+	case "dueDate":
+		aDay, _ := strconv.Atoi(a.DueDate)
+		bDay, _ := strconv.Atoi(b.DueDate)
+		return compareInts(int64(aDay), int64(bDay))
+	}
+	return compareInts(int64(a.ID), int64(b.ID))
+}
+
+// compareUsers compares two users by a field ListUsers can sort by
+func compareUsers(field string, a, b *User) int {
+	switch field {
+	case "firstName":
+		return strings.Compare(a.FirstName, b.FirstName)
+	case "lastName":
+		return strings.Compare(a.LastName, b.LastName)
+	case "fullName":
+		return strings.Compare(a.FullName, b.FullName)
+	case "email":
+		return strings.Compare(a.Email, b.Email)
+	case "biweeklyIncome":
+		return compareInts(a.BiweeklyIncome.Amount, b.BiweeklyIncome.Amount)
+	}
+	return compareInts(int64(a.ID), int64(b.ID))
+}
+
+// sortedAccounts copies the accounts match keeps, in ID order
+func (d *memoryData) sortedAccounts(match func(*Account) bool) []*Account {
+	accounts := make([]*Account, 0)
+	for _, a := range d.accounts {
+		account := a
+		if match == nil || match(&account) {
+			accounts = append(accounts, &account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+
+	return accounts
+}
+
+// sortedUsers copies the users match keeps, in ID order
+func (d *memoryData) sortedUsers(match func(*User) bool) []*User {
+	users := make([]*User, 0)
+	for _, u := range d.users {
+		user := u
+		if match == nil || match(&user) {
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users
+}
+
+// accountTransactions copies an account's transactions, oldest first
+func (d *memoryData) accountTransactions(accountID int) []*Transaction {
+	transactions := make([]*Transaction, 0)
+	for id, t := range d.transactions {
+		if t.AccountID == accountID {
+			transactions = append(transactions, d.transaction(id))
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].PostedDate != transactions[j].PostedDate {
+			return transactions[i].PostedDate < transactions[j].PostedDate
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+
+	return transactions
+}
+
+// deleteAccount deletes an account along with its transactions
+func (d *memoryData) deleteAccount(accountID int) {
+	for id, t := range d.transactions {
+		if t.AccountID == accountID {
+			delete(d.transactions, id)
+		}
+	}
+	delete(d.accounts, accountID)
+}
+
+// AllAccounts retrieves all accounts
+func (s *MemoryStore) AllAccounts(ctx context.Context) ([]*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.sortedAccounts(nil), nil
+}
+
+// GetAccount retrieves an account by ID
+func (s *MemoryStore) GetAccount(ctx context.Context, accountID int) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, ok := s.data.accounts[accountID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &account, nil
+}
+
+// CreateAccount creates an account and returns it
+func (s *MemoryStore) CreateAccount(ctx context.Context, a Account) (*Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.data.checkAccount(a, 0); err != nil {
+		return nil, err
+	}
+
+	a.ID = s.data.nextID("accounts")
+	a.Version = 1
+	s.data.putAccount(a)
+
+	created := s.data.accounts[a.ID]
+	return &created, nil
+}
+
+// UpdateAccount replaces an account. A non-zero a.Version makes the update
+// conditional on the account still being at that version.
+func (s *MemoryStore) UpdateAccount(ctx context.Context, accountID int, a *Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.accounts[accountID]
+	err := checkVersion(ok, existing.Version, a.Version)
+	if err != nil {
+		return err
+	}
+
+	updated := *a
+	updated.ID = accountID
+	updated.Version = existing.Version + 1
+	if err = s.data.checkAccount(updated, accountID); err != nil {
+		return err
+	}
+
+	s.data.putAccount(updated)
+	return nil
+}
+
+// PatchAccount updates only the named fields of an account
+func (s *MemoryStore) PatchAccount(ctx context.Context, accountID int, a *Account, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.accounts[accountID]
+	if err := checkPatch("accounts", accountColumnsByField, fields, ok, existing.Version, a.Version); err != nil {
+		return err
+	}
+
+	patched := existing
+	for _, field := range fields {
+		switch field {
+		case "userID":
+			patched.UserID = a.UserID
+		case "name":
+			patched.Name = a.Name
+		case "accountType":
+			patched.AccountType = a.AccountType
+		case "minimumPayment":
+			patched.MinimumPayment = a.MinimumPayment
+		case "currentPayment":
+			patched.CurrentPayment = a.CurrentPayment
+		case "fullAmount":
+			patched.FullAmount = a.FullAmount
+		case "currency":
+			patched.Currency = a.Currency
+		case "apr":
+			patched.APR = a.APR
+		case "dueDate":
+			patched.DueDate = a.DueDate
+		case "anchorDate":
+			patched.AnchorDate = a.AnchorDate
+		case "URL":
+			patched.URL = a.URL
+		}
+	}
+	patched.Version++
+	if err := s.data.checkAccount(patched, accountID); err != nil {
+		return err
+	}
+
+	s.data.putAccount(patched)
+	return nil
+}
+
+// DeleteAccount deletes an account along with its transactions. A non-zero version
+// makes the delete conditional, as with DB.DeleteAccount.
+func (s *MemoryStore) DeleteAccount(ctx context.Context, accountID int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.accounts[accountID]
+	if err := checkVersion(ok, existing.Version, version); err != nil {
+		return err
+	}
+
+	s.data.deleteAccount(accountID)
+	return nil
+}
+
+// UserAccounts retrieves the accounts that belong to a user
+func (s *MemoryStore) UserAccounts(ctx context.Context, userID int) ([]*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.sortedAccounts(func(a *Account) bool { return a.UserID == userID }), nil
+}
+
+// GetUserAccount retrieves an account that belongs to the user. An account owned
+// by a different user is reported as not found.
+func (s *MemoryStore) GetUserAccount(ctx context.Context, userID int, accountID int) (*Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, ok := s.data.accounts[accountID]
+	if !ok || account.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	return &account, nil
+}
+
+// ListAccounts retrieves one page of the accounts matching filter, along with
+// the number of accounts that match across all pages
+func (s *MemoryStore) ListAccounts(ctx context.Context, filter AccountFilter, opts ListOptions) ([]*Account, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts := s.data.sortedAccounts(func(a *Account) bool {
+		return (filter.UserID == 0 || a.UserID == filter.UserID) &&
+			(filter.AccountType == "" || a.AccountType == filter.AccountType) &&
+			(filter.DueDate == "" || a.DueDate == filter.DueDate) &&
+			(filter.MinFullAmount == nil || a.FullAmount.Amount >= filter.MinFullAmount.Amount) &&
+			(filter.MaxFullAmount == nil || a.FullAmount.Amount <= filter.MaxFullAmount.Amount)
+	})
+
+	start, end, err := page(len(accounts), opts, accountSortColumns,
+		func(i, j int) { accounts[i], accounts[j] = accounts[j], accounts[i] },
+		func(field string, i, j int) int { return compareAccounts(field, accounts[i], accounts[j]) })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return accounts[start:end], len(accounts), nil
+}
+
+// AllUsers retrieves all users
+func (s *MemoryStore) AllUsers(ctx context.Context) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.sortedUsers(nil), nil
+}
+
+// ListUsers retrieves one page of the users matching filter, along with the
+// number of users that match across all pages
+func (s *MemoryStore) ListUsers(ctx context.Context, filter UserFilter, opts ListOptions) ([]*User, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := s.data.sortedUsers(func(u *User) bool {
+		return (filter.ID == 0 || u.ID == filter.ID) &&
+			(filter.Email == "" || u.Email == filter.Email)
+	})
+
+	start, end, err := page(len(users), opts, userSortColumns,
+		func(i, j int) { users[i], users[j] = users[j], users[i] },
+		func(field string, i, j int) int { return compareUsers(field, users[i], users[j]) })
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users[start:end], len(users), nil
+}
+
+// GetUser retrieves a user by ID
+func (s *MemoryStore) GetUser(ctx context.Context, userID int) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.data.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &user, nil
+}
+
+// CreateUser creates a user and returns it
+func (s *MemoryStore) CreateUser(ctx context.Context, u User) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.data.checkUser(u, 0); err != nil {
+		return nil, err
+	}
+
+	u.ID = s.data.nextID("users")
+	u.Version = 1
+	s.data.putUser(u)
+
+	created := s.data.users[u.ID]
+	return &created, nil
+}
+
+// UpdateUser replaces a user, keeping their password. A non-zero u.Version makes
+// the update conditional on the user still being at that version.
+func (s *MemoryStore) UpdateUser(ctx context.Context, userID int, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.users[userID]
+	err := checkVersion(ok, existing.Version, u.Version)
+	if err != nil {
+		return err
+	}
+
+	updated := *u
+	updated.ID = userID
+	updated.PasswordHash = existing.PasswordHash
+	updated.Version = existing.Version + 1
+	if err = s.data.checkUser(updated, userID); err != nil {
+		return err
+	}
+
+	s.data.putUser(updated)
+	return nil
+}
+
+// PatchUser updates only the named fields of a user
+func (s *MemoryStore) PatchUser(ctx context.Context, userID int, u *User, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.users[userID]
+	if err := checkPatch("users", userColumnsByField, fields, ok, existing.Version, u.Version); err != nil {
+		return err
+	}
+
+	patched := existing
+	for _, field := range fields {
+		switch field {
+		case "firstName":
+			patched.FirstName = u.FirstName
+		case "lastName":
+			patched.LastName = u.LastName
+		case "fullName":
+			patched.FullName = u.FullName
+		case "email":
+			patched.Email = u.Email
+		case "biweeklyIncome":
+			patched.BiweeklyIncome = u.BiweeklyIncome
+		case "currency":
+			patched.Currency = u.Currency
+		case "payday":
+			patched.Payday = u.Payday
+		}
+	}
+	patched.Version++
+	if err := s.data.checkUser(patched, userID); err != nil {
+		return err
+	}
+
+	s.data.putUser(patched)
+	return nil
+}
+
+// DeleteUser deletes a user and their sessions. The user's accounts are handled
+// according to s.UserDeletePolicy, and a non-zero version makes the delete conditional,
+// as with DB.DeleteUser.
+func (s *MemoryStore) DeleteUser(ctx context.Context, userID int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.users[userID]
+	if err := checkVersion(ok, existing.Version, version); err != nil {
+		return err
+	}
+
+	accounts := s.data.sortedAccounts(func(a *Account) bool { return a.UserID == userID })
+	switch s.UserDeletePolicy.Mode {
+	case DeleteCascade:
+		for _, account := range accounts {
+			s.data.deleteAccount(account.ID)
+		}
+	case DeleteReassign:
+		to := s.UserDeletePolicy.ReassignTo
+		if to == userID {
+			return ErrHasDependents
+		}
+		// Check every account before moving any, as the database would
+		for _, account := range accounts {
+			account.UserID = to
+			if err := s.data.checkAccount(*account, account.ID); err != nil {
+				return err
+			}
+		}
+		for _, account := range accounts {
+			s.data.putAccount(*account)
+		}
+	case DeleteRestrict, "":
+		if len(accounts) > 0 {
+			return ErrHasDependents
+		}
+	default:
+		return ErrBadDeletePolicy
+	}
+
+	for hash, session := range s.data.sessions {
+		if session.userID == userID {
+			delete(s.data.sessions, hash)
+		}
+	}
+	delete(s.data.users, userID)
+
+	return nil
+}
+
+// UserByEmail retrieves the user with the given email address
+func (s *MemoryStore) UserByEmail(ctx context.Context, email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id := s.data.emailTaken(email, 0)
+	if id == 0 {
+		return nil, ErrNotFound
+	}
+
+	user := s.data.users[id]
+	return &user, nil
+}
+
+// CreateSession starts a new session for a user that lasts for ttl
+func (s *MemoryStore) CreateSession(ctx context.Context, userID int, ttl time.Duration) (*Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.users[userID]; !ok {
+		return nil, ErrForeignKey
+	}
+
+	session := &Session{Token: token, UserID: userID, ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second)}
+	s.data.sessions[hashToken(token)] = memorySession{userID: userID, expiresAt: session.ExpiresAt.Unix()}
+
+	return session, nil
+}
+
+// SessionUser retrieves the user a session token belongs to. Unknown and
+// expired tokens return ErrNotFound.
+func (s *MemoryStore) SessionUser(ctx context.Context, token string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.data.sessions[hashToken(token)]
+	if !ok || session.expiresAt <= time.Now().Unix() {
+		return nil, ErrNotFound
+	}
+
+	user := s.data.users[session.userID]
+	return &user, nil
+}
+
+// DeleteSession ends a session, along with any other expired sessions
+func (s *MemoryStore) DeleteSession(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := hashToken(token)
+	if _, ok := s.data.sessions[hash]; !ok {
+		return ErrNotFound
+	}
+	delete(s.data.sessions, hash)
+
+	now := time.Now().Unix()
+	for hash, session := range s.data.sessions {
+		if session.expiresAt <= now {
+			delete(s.data.sessions, hash)
+		}
+	}
+
+	return nil
+}
+
+// AccountTransactions retrieves all transactions for an account, oldest first
+func (s *MemoryStore) AccountTransactions(ctx context.Context, accountID int) ([]*Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.accountTransactions(accountID), nil
+}
+
+// GetTransaction retrieves a transaction by ID
+func (s *MemoryStore) GetTransaction(ctx context.Context, transactionID int) (*Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.transactions[transactionID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return s.data.transaction(transactionID), nil
+}
+
+// CreateTransaction creates a transaction and returns it
+func (s *MemoryStore) CreateTransaction(ctx context.Context, t Transaction) (*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.data.checkTransaction(t, 0); err != nil {
+		return nil, err
+	}
+
+	t.ID = s.data.nextID("transactions")
+	s.data.putTransaction(t)
+
+	return s.data.transaction(t.ID), nil
+}
+
+// UpdateTransaction replaces a transaction, keeping its import ID
+func (s *MemoryStore) UpdateTransaction(ctx context.Context, transactionID int, t *Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.data.transactions[transactionID]
+	if !ok {
+		return nil
+	}
+
+	updated := *t
+	updated.ID = transactionID
+	updated.ImportID = existing.ImportID
+	if err := s.data.checkTransaction(updated, transactionID); err != nil {
+		return err
+	}
+
+	s.data.putTransaction(updated)
+	return nil
+}
+
+// DeleteTransaction deletes a transaction
+func (s *MemoryStore) DeleteTransaction(ctx context.Context, transactionID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.transactions[transactionID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.data.transactions, transactionID)
+	return nil
+}
+
+// AccountBalance sums the ledger of an account into its current balance
+func (s *MemoryStore) AccountBalance(ctx context.Context, accountID int) (*Balance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	zero := NewMoney(0, s.data.accounts[accountID].Currency)
+	balance := &Balance{AccountID: accountID, Balance: zero, Cleared: zero, Pending: zero}
+	for _, t := range s.data.accountTransactions(accountID) {
+		balance.Balance = balance.Balance.Add(t.Amount)
+		switch t.Status {
+		case StatusCleared:
+			balance.Cleared = balance.Cleared.Add(t.Amount)
+		case StatusPending:
+			balance.Pending = balance.Pending.Add(t.Amount)
+		}
+	}
+
+	return balance, nil
+}
+
+// ImportedIDs returns the set of import IDs already used in an account
+func (s *MemoryStore) ImportedIDs(ctx context.Context, accountID int) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make(map[string]bool)
+	for _, t := range s.data.transactions {
+		if t.AccountID == accountID && t.ImportID != "" {
+			ids[t.ImportID] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// ImportTransactions creates transactions in an account all at once, so either
+// every one is created or none are
+func (s *MemoryStore) ImportTransactions(ctx context.Context, accountID int, transactions []Transaction) ([]*Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.data.clone()
+	created := make([]*Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		t.AccountID = accountID
+		if err := data.checkTransaction(t, 0); err != nil {
+			return nil, err
+		}
+
+		t.ID = data.nextID("transactions")
+		data.putTransaction(t)
+
+		created = append(created, data.transaction(t.ID))
+	}
+
+	s.data = data
+	return created, nil
+}
+
+// Export exports a user along with their accounts and transactions
+func (s *MemoryStore) Export(ctx context.Context, userID int) (*Backup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.data.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	accounts := s.data.sortedAccounts(func(a *Account) bool { return a.UserID == userID })
+	return s.data.export([]*User{&user}, accounts), nil
+}
+
+// ExportAll exports every user along with their accounts and transactions, as
+// with DB.ExportAll
+func (s *MemoryStore) ExportAll(ctx context.Context) (*Backup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data.export(s.data.sortedUsers(nil), s.data.sortedAccounts(nil)), nil
+}
+
+// export builds a Backup of users and accounts, along with the accounts' transactions
+func (d *memoryData) export(users []*User, accounts []*Account) *Backup {
+	backup := &Backup{
+		Version:      BackupVersion,
+		ExportedAt:   time.Now().UTC().Format(time.RFC3339),
+		Users:        users,
+		Accounts:     accounts,
+		Transactions: make([]*Transaction, 0),
+	}
+	for _, account := range accounts {
+		backup.Transactions = append(backup.Transactions, d.accountTransactions(account.ID)...)
+	}
+
+	return backup
+}
+
+// Restore restores a validated Backup all at once, matching rows by ID and
+// handling conflicts according to mode, as with DB.Restore
+func (s *MemoryStore) Restore(ctx context.Context, b *Backup, mode string) (*RestoreReport, error) {
+	switch mode {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+	default:
+		return nil, ErrBadConflictMode
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := &memoryRestorer{
+		data:     s.data.clone(),
+		mode:     mode,
+		report:   &RestoreReport{Conflicts: make([]Conflict, 0)},
+		users:    make(map[int]bool),
+		accounts: make(map[int]bool),
+	}
+	for _, u := range b.Users {
+		r.user(*u)
+	}
+	for _, a := range b.Accounts {
+		r.account(*a)
+	}
+	for _, t := range b.Transactions {
+		r.transaction(*t)
+	}
+
+	if mode == ConflictFail && len(r.report.Conflicts) > 0 {
+		return r.report, ErrRestoreConflict
+	}
+
+	s.data = r.data
+	return r.report, nil
+}
+
+// memoryRestorer restores one backup into a copy of a MemoryStore's data. It
+// reports the same conflicts as restorer.
+type memoryRestorer struct {
+	data     *memoryData
+	mode     string
+	report   *RestoreReport
+	users    map[int]bool
+	accounts map[int]bool
+}
+
+// conflict records a conflicting row, returning whether the row should be written anyway
+func (r *memoryRestorer) conflict(table string, id int, reason string) bool {
+	r.report.Conflicts = append(r.report.Conflicts, Conflict{Table: table, ID: id, Reason: reason})
+	return r.mode == ConflictOverwrite
+}
+
+func (r *memoryRestorer) user(u User) {
+	if other := r.data.emailTaken(u.Email, u.ID); other != 0 {
+		r.conflict("users", u.ID, fmt.Sprintf("email is already used by user %d", other))
+		return
+	}
+
+	r.users[u.ID] = true
+	existing, ok := r.data.users[u.ID]
+	if !ok {
+		u.PasswordHash, u.Version = "", 1
+		r.data.putUser(u)
+		r.data.useID("users", u.ID)
+		r.report.Created.Users++
+		return
+	}
+
+	changes := existing.Changes(&u)
+	if len(changes) == 0 {
+		r.report.Unchanged.Users++
+		return
+	}
+	if !r.conflict("users", u.ID, differs(changes)) {
+		return
+	}
+
+	u.PasswordHash, u.Version = existing.PasswordHash, existing.Version+1
+	r.data.putUser(u)
+	r.report.Updated.Users++
+}
+
+func (r *memoryRestorer) account(a Account) {
+	if !r.users[a.UserID] {
+		r.conflict("accounts", a.ID, fmt.Sprintf("user %d wasn't restored", a.UserID))
+		return
+	}
+
+	if other := r.data.nameTaken(a.UserID, a.Name, a.ID); other != 0 {
+		r.conflict("accounts", a.ID, fmt.Sprintf("name is already used by account %d", other))
+		return
+	}
+
+	existing, ok := r.data.accounts[a.ID]
+	if ok && existing.UserID != a.UserID {
+		r.conflict("accounts", a.ID, "belongs to another user")
+		return
+	}
+
+	r.accounts[a.ID] = true
+	if !ok {
+		a.Version = 1
+		r.data.putAccount(a)
+		r.data.useID("accounts", a.ID)
+		r.report.Created.Accounts++
+		return
+	}
+
+	changes := existing.Changes(&a)
+	if len(changes) == 0 {
+		r.report.Unchanged.Accounts++
+		return
+	}
+	if !r.conflict("accounts", a.ID, differs(changes)) {
+		return
+	}
+
+	a.Version = existing.Version + 1
+	r.data.putAccount(a)
+	r.report.Updated.Accounts++
+}
+
+func (r *memoryRestorer) transaction(t Transaction) {
+	if !r.accounts[t.AccountID] {
+		r.conflict("transactions", t.ID, fmt.Sprintf("account %d wasn't restored", t.AccountID))
+		return
+	}
+
+	if other := r.data.importIDTaken(t.AccountID, t.ImportID, t.ID); other != 0 {
+		r.conflict("transactions", t.ID, fmt.Sprintf("importID is already used by transaction %d", other))
+		return
+	}
+
+	existing, ok := r.data.transactions[t.ID]
+	if ok && existing.AccountID != t.AccountID {
+		r.conflict("transactions", t.ID, "belongs to another account")
+		return
+	}
+	if !ok {
+		r.data.putTransaction(t)
+		r.data.useID("transactions", t.ID)
+		r.report.Created.Transactions++
+		return
+	}
+
+	// A transaction is in its account's currency rather than one of its own
+	t.Amount.Currency = existing.Amount.Currency
+	if existing == t {
+		r.report.Unchanged.Transactions++
+		return
+	}
+	if !r.conflict("transactions", t.ID, "differs from the existing transaction") {
+		return
+	}
+
+	r.data.putTransaction(t)
+	r.report.Updated.Transactions++
+}
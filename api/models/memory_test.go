@@ -0,0 +1,92 @@
+package models_test
+
+import (
+	"context"
+	"dinero/api/models"
+	"sync"
+	"testing"
+)
+
+func TestMemoryStoreConcurrency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	store := models.NewMemoryStore()
+	user, _ := store.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com"})
+	account, _ := store.CreateAccount(ctx, models.Account{UserID: user.ID, Name: "Phone Payment", AccountType: "monthly", DueDate: "10"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.CreateTransaction(ctx, models.Transaction{AccountID: account.ID, Amount: models.USD(100), PostedDate: "2020-01-01", Payee: "Synchrony", Status: models.StatusCleared})
+			store.AccountBalance(ctx, account.ID)
+		}()
+	}
+	wg.Wait()
+
+	transactions, _ := store.AccountTransactions(ctx, account.ID)
+	ids := make(map[int]bool)
+	for _, transaction := range transactions {
+		ids[transaction.ID] = true
+	}
+	if len(ids) != 50 {
+		t.Errorf("\nTransactions:\n\tGot: \t\t%d\n\tExpected: \t%d\n", len(ids), 50)
+	}
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	store := models.NewMemoryStore()
+	user, _ := store.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com"})
+	user.Email = "changed@gmail.com"
+
+	stored, err := store.GetUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "lptoth55@gmail.com" {
+		t.Errorf("\nEmail:\n\tGot: \t\t%s\n\tExpected: \t%s\n", stored.Email, "lptoth55@gmail.com")
+	}
+}
+
+func TestMemoryStoreDeletePolicy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		policy   models.DeletePolicy
+		expected error
+		accounts int
+	}{
+		{"RESTRICT", models.DeletePolicy{}, models.ErrHasDependents, 1},
+		{"CASCADE", models.DeletePolicy{Mode: models.DeleteCascade}, nil, 0},
+		{"REASSIGN", models.DeletePolicy{Mode: models.DeleteReassign, ReassignTo: 2}, nil, 1},
+		{"REASSIGN_CONFLICT", models.DeletePolicy{Mode: models.DeleteReassign, ReassignTo: 3}, models.ErrConflict, 1},
+		{"REASSIGN_MISSING", models.DeletePolicy{Mode: models.DeleteReassign, ReassignTo: 9}, models.ErrForeignKey, 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			store := models.NewMemoryStore()
+			store.UserDeletePolicy = test.policy
+			store.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com"})
+			store.CreateUser(ctx, models.User{FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com"})
+			store.CreateUser(ctx, models.User{FirstName: "Jane", LastName: "Ide", FullName: "Jane Ide", Email: "jane@gmail.com"})
+			store.CreateAccount(ctx, models.Account{UserID: 1, Name: "Phone Payment", AccountType: "monthly", DueDate: "10"})
+			store.CreateAccount(ctx, models.Account{UserID: 3, Name: "Phone Payment", AccountType: "monthly", DueDate: "10"})
+
+			expectErr(t, "DeleteUser", store.DeleteUser(ctx, 1, 0), test.expected)
+
+			accounts, _ := store.AllAccounts(ctx)
+			if len(accounts) != test.accounts+1 {
+				t.Errorf("\nAccounts:\n\tGot: \t\t%d\n\tExpected: \t%d\n", len(accounts), test.accounts+1)
+			}
+		})
+	}
+}
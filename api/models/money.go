@@ -0,0 +1,337 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 code used for amounts that don't specify one
+const DefaultCurrency = "USD"
+
+// currencyPattern matches ISO 4217 currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// currencyOrDefault returns code, or DefaultCurrency when it's empty
+func currencyOrDefault(code string) string {
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// Money is an exact monetary amount stored as integer minor units (cents)
+// together with its ISO 4217 currency code. The zero value is $0.00.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney creates a Money value from an amount in minor units (cents)
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currencyOrDefault(currency)}
+}
+
+// USD creates a Money value from an amount of US cents, the default currency
+func USD(cents int64) Money {
+	return NewMoney(cents, DefaultCurrency)
+}
+
+// ParseMoney parses a decimal string such as "217.99" or "-5" into
+// Money in the default currency. More than two decimal places is an error
+// rather than being rounded, so no fraction of a cent is ever lost.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, fmt.Errorf("error: invalid money amount %q", s)
+	}
+
+	neg := false
+	digits := s
+	if digits[0] == '-' || digits[0] == '+' {
+		neg = digits[0] == '-'
+		digits = digits[1:]
+	}
+
+	whole, frac := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+
+	if whole == "" && frac == "" || len(frac) > 2 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("error: invalid money amount %q", s)
+	}
+
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("error: invalid money amount %q", s)
+	}
+
+	if neg {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: DefaultCurrency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// currency returns the currency code of m, falling back to DefaultCurrency
+func (m Money) currency() string {
+	return currencyOrDefault(m.Currency)
+}
+
+// SameCurrency reports whether m and o are in the same currency
+func (m Money) SameCurrency(o Money) bool {
+	return m.currency() == o.currency()
+}
+
+func (m Money) mustMatch(o Money) {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("models: currency mismatch %s and %s", m.currency(), o.currency()))
+	}
+}
+
+// Add returns m + o. It panics if the currencies differ.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currency()}
+}
+
+// Sub returns m - o. It panics if the currencies differ.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currency()}
+}
+
+// Mul returns m multiplied by rate, rounded to the nearest minor unit
+// with ties going to the even neighbour (banker's rounding). The rate is an
+// exact fraction, so a rate like 24.99% / 12 is 2499/120000 rather than the
+// closest binary float. ErrInvalidRate is returned for a nil rate and
+// ErrMoneyOverflow if the result doesn't fit in Money.
+func (m Money) Mul(rate *big.Rat) (Money, error) {
+	if rate == nil {
+		return Money{}, ErrInvalidRate
+	}
+
+	r := new(big.Rat).Mul(rate, new(big.Rat).SetInt64(m.Amount))
+	amount := roundHalfEven(r)
+	if !amount.IsInt64() {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: amount.Int64(), Currency: m.currency()}, nil
+}
+
+func roundHalfEven(r *big.Rat) *big.Int {
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	// Compare twice the remainder against the denominator to find which
+	// neighbour is closer
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	}
+
+	return q
+}
+
+// Allocate splits m between the given ratios without losing any minor
+// units; the remainder is handed out one cent at a time starting with
+// the first share.
+func (m Money) Allocate(ratios ...int) []Money {
+	total := 0
+	for _, r := range ratios {
+		total += r
+	}
+
+	shares := make([]Money, len(ratios))
+	if total == 0 {
+		for i := range shares {
+			shares[i] = Money{Currency: m.currency()}
+		}
+		return shares
+	}
+
+	remainder := m.Amount
+	for i, r := range ratios {
+		share := m.Amount * int64(r) / int64(total)
+		shares[i] = Money{Amount: share, Currency: m.currency()}
+		remainder -= share
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].Amount += step
+		remainder -= step
+	}
+
+	return shares
+}
+
+// Split divides m into n equal shares, spreading any leftover cents
+// across the first shares
+func (m Money) Split(n int) []Money {
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Cmp compares m and o and returns -1, 0 or +1. It panics if the
+// currencies differ.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether m is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether m is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.currency()}
+}
+
+// Float64 returns m in major units. It should only be used for display
+// or interest approximations, never for storing amounts.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / 100
+}
+
+// String formats m as a plain decimal, e.g. "217.99" or "100.00"
+func (m Money) String() string {
+	sign := ""
+	amount := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		amount = uint64(-m.Amount)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// MarshalJSON encodes m as a JSON number in major units with trailing
+// zeros trimmed (217.99, 100, 0)
+func (m Money) MarshalJSON() ([]byte, error) {
+	s := m.String()
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || s == "-" {
+		s = "0"
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON accepts either a JSON number (217.99) or a string ("217.99")
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	} else {
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		s = n.String()
+		// Numbers such as 1e2 are valid JSON, normalise them first
+		if strings.ContainsAny(s, "eE") {
+			r, ok := new(big.Rat).SetString(s)
+			if !ok {
+				return fmt.Errorf("error: invalid money amount %q", s)
+			}
+			s = r.FloatString(2)
+			if r2, _ := new(big.Rat).SetString(s); r2.Cmp(r) != 0 {
+				return fmt.Errorf("error: invalid money amount %q", n.String())
+			}
+		}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	parsed.Currency = m.currency()
+	*m = parsed
+	return nil
+}
+
+// Value stores m in the database as integer minor units
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+// Scan reads integer minor units from the database. Legacy REAL values
+// are treated as major units and rounded to the nearest cent.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		m.Amount = v
+	case float64:
+		m.Amount = int64(math.Round(v * 100))
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return err
+		}
+		m.Amount = n
+	case nil:
+		m.Amount = 0
+	default:
+		return fmt.Errorf("error: cannot scan %T into Money", src)
+	}
+
+	m.Currency = m.currency()
+	return nil
+}
@@ -0,0 +1,171 @@
+package models_test
+
+import (
+	"dinero/api/models"
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       string
+		expected int64
+		err      bool
+	}{
+		{"217.99", 21799, false},
+		{"100", 10000, false},
+		{"0.1", 10, false},
+		{"-5.05", -505, false},
+		{".5", 50, false},
+		{"1.999", 0, true},
+		{"abc", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			m, err := models.ParseMoney(test.in)
+			if (err != nil) != test.err {
+				t.Fatalf("\nError:\n\tGot: \t\t%v\n\tExpected error: \t%v\n", err, test.err)
+			}
+			if m.Amount != test.expected {
+				t.Errorf("\nAmount:\n\tGot: \t\t%d\n\tExpected: \t%d\n", m.Amount, test.expected)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{`217.99`, `217.99`},
+		{`"217.99"`, `217.99`},
+		{`100.00`, `100`},
+		{`0`, `0`},
+		{`-0.5`, `-0.5`},
+		{`1e2`, `100`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var m models.Money
+			if err := json.Unmarshal([]byte(test.in), &m); err != nil {
+				t.Fatal(err)
+			}
+
+			out, _ := json.Marshal(m)
+			if string(out) != test.expected {
+				t.Errorf("\nJSON:\n\tGot: \t\t%s\n\tExpected: \t%s\n", out, test.expected)
+			}
+		})
+	}
+
+	var m models.Money
+	if err := json.Unmarshal([]byte(`0.001`), &m); err == nil {
+		t.Errorf("expected an error for sub-cent amounts")
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	t.Parallel()
+
+	a := models.NewMoney(21799, models.DefaultCurrency)
+	b := models.NewMoney(4283, models.DefaultCurrency)
+
+	if got := a.Add(b).Amount; got != 26082 {
+		t.Errorf("Add: got %d, expected %d", got, 26082)
+	}
+	if got := b.Sub(a).Amount; got != -17516 {
+		t.Errorf("Sub: got %d, expected %d", got, -17516)
+	}
+
+	// An eighth of 100 and 300 cents are ties, so this exercises half-even rounding
+	if got, _ := models.NewMoney(100, "").Mul(big.NewRat(1, 8)); got.Amount != 12 {
+		t.Errorf("Mul: got %d, expected %d", got.Amount, 12)
+	}
+	if got, _ := models.NewMoney(300, "").Mul(big.NewRat(1, 8)); got.Amount != 38 {
+		t.Errorf("Mul: got %d, expected %d", got.Amount, 38)
+	}
+	// A tenth isn't a binary fraction, so 5 cents times 0.1 as a float would be just
+	// over the tie at 0.5 and round up
+	if got, _ := models.NewMoney(5, "").Mul(big.NewRat(1, 10)); got.Amount != 0 {
+		t.Errorf("Mul: got %d, expected %d", got.Amount, 0)
+	}
+	if got, _ := models.NewMoney(-15, "").Mul(big.NewRat(1, 10)); got.Amount != -2 {
+		t.Errorf("Mul: got %d, expected %d", got.Amount, -2)
+	}
+
+	// Adding a tenth of a dollar a thousand times must not drift
+	total := models.Money{}
+	for i := 0; i < 1000; i++ {
+		total = total.Add(models.NewMoney(10, ""))
+	}
+	if total.String() != "100.00" {
+		t.Errorf("Add drift: got %s, expected 100.00", total)
+	}
+}
+
+func TestMoneyMulErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		amount   int64
+		rate     *big.Rat
+		expected error
+	}{
+		{"NO_RATE", 100, nil, models.ErrInvalidRate},
+		{"OVERFLOW", math.MaxInt64, big.NewRat(3, 2), models.ErrMoneyOverflow},
+		{"NEGATIVE_OVERFLOW", math.MinInt64, big.NewRat(-1, 1), models.ErrMoneyOverflow},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := models.NewMoney(test.amount, "").Mul(test.rate); err != test.expected {
+				t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		amount   int64
+		ratios   []int
+		expected []int64
+	}{
+		{"EVEN", 100, []int{1, 1, 1}, []int64{34, 33, 33}},
+		{"WEIGHTED", 5, []int{3, 7}, []int64{2, 3}},
+		{"NEGATIVE", -100, []int{1, 1, 1}, []int64{-34, -33, -33}},
+		{"ZERO_RATIO", 101, []int{0, 1, 1}, []int64{0, 51, 50}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shares := models.NewMoney(test.amount, "").Allocate(test.ratios...)
+
+			sum := int64(0)
+			for i, s := range shares {
+				sum += s.Amount
+				if s.Amount != test.expected[i] {
+					t.Errorf("\nShare %d:\n\tGot: \t\t%d\n\tExpected: \t%d\n", i, s.Amount, test.expected[i])
+				}
+			}
+			if sum != test.amount {
+				t.Errorf("shares sum to %d, expected %d", sum, test.amount)
+			}
+		})
+	}
+}
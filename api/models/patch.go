@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// updateVersioned runs an UPDATE query ending in a "WHERE id = ?" clause. With a
+// non-zero version the update only applies to the row at that version, and
+// ErrVersionConflict is returned when nothing was updated. Without one,
+// ErrNotFound is returned when there was no row to update.
+func (db *DB) updateVersioned(ctx context.Context, query string, args []interface{}, version int) error {
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows < 1 && version != 0 {
+		return ErrVersionConflict
+	} else if rows < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// patchRow updates only the columns of the named fields of one row in table.
+// columns maps each field's JSON name to its column and values holds its new value.
+// A non-zero version makes the update conditional, as with updateVersioned.
+func (db *DB) patchRow(ctx context.Context, table string, id int, version int, columns map[string]string, values map[string]interface{}, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	set := make([]string, 0, len(fields)+1)
+	args := make([]interface{}, 0, len(fields)+2)
+	for _, field := range fields {
+		column, ok := columns[field]
+		if !ok {
+			return fmt.Errorf("error: %s has no field %s", table, field)
+		}
+
+		set = append(set, column+" = ?")
+		args = append(args, values[field])
+	}
+	set = append(set, "version = version + 1")
+	args = append(args, id)
+
+	query := "UPDATE " + table + " SET " + strings.Join(set, ", ") + " WHERE id = ?"
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows < 1 && version != 0 {
+		return ErrVersionConflict
+	} else if rows < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
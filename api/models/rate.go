@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rate is an exact percentage stored as integer hundredths of a percent (basis
+// points), so 24.99% is 2499. It uses the same decimal format as Money.
+type Rate int64
+
+// ParseRate parses a decimal percentage such as "24.99" into a Rate. More than two
+// decimal places is an error.
+func ParseRate(s string) (Rate, error) {
+	m, err := ParseMoney(s)
+	if err != nil {
+		return 0, fmt.Errorf("error: invalid rate %q", strings.TrimSpace(s))
+	}
+
+	return Rate(m.Amount), nil
+}
+
+// Monthly returns an annual rate as the exact fraction charged each month, so
+// 24.99% is 2499/120000
+func (r Rate) Monthly() *big.Rat {
+	return big.NewRat(int64(r), 120000)
+}
+
+// String formats r as a plain decimal percentage, e.g. "24.99"
+func (r Rate) String() string {
+	return Money{Amount: int64(r)}.String()
+}
+
+// MarshalJSON encodes r as a JSON number with trailing zeros trimmed (24.99, 5, 0)
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return Money{Amount: int64(r)}.MarshalJSON()
+}
+
+// UnmarshalJSON accepts either a JSON number (24.99) or a string ("24.99")
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	var m Money
+	if err := m.UnmarshalJSON(b); err != nil {
+		return fmt.Errorf("error: invalid rate %s", b)
+	}
+
+	*r = Rate(m.Amount)
+	return nil
+}
+
+// Value stores r in the database as integer basis points
+func (r Rate) Value() (driver.Value, error) {
+	return int64(r), nil
+}
+
+// Scan reads integer basis points from the database
+func (r *Rate) Scan(src interface{}) error {
+	var m Money
+	if err := m.Scan(src); err != nil {
+		return err
+	}
+
+	*r = Rate(m.Amount)
+	return nil
+}
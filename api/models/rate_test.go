@@ -0,0 +1,62 @@
+package models_test
+
+import (
+	"dinero/api/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestRateJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{`24.99`, `24.99`, false},
+		{`"24.99"`, `24.99`, false},
+		{`5.00`, `5`, false},
+		{`0`, `0`, false},
+		{`2.499`, ``, true},
+		{`"high"`, ``, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			var r models.Rate
+			err := json.Unmarshal([]byte(test.in), &r)
+			if (err != nil) != test.err {
+				t.Fatalf("\nError:\n\tGot: \t\t%v\n\tExpected error: \t%v\n", err, test.err)
+			}
+			if test.err {
+				return
+			}
+
+			got, _ := json.Marshal(r)
+			if string(got) != test.expected {
+				t.Errorf("\nJSON:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestRateMonthly(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rate     models.Rate
+		expected string
+	}{
+		{2499, "833/40000"},
+		{2400, "1/50"},
+		{100, "1/1200"},
+		{0, "0/1"},
+	}
+
+	for _, test := range tests {
+		if got := test.rate.Monthly().String(); got != test.expected {
+			t.Errorf("\nMonthly %s:\n\tGot: \t\t%s\n\tExpected: \t%s\n", test.rate, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Session is a logged in User's bearer token. Only a hash of the token is
+// stored, so a leaked database can't be used to hijack sessions.
+type Session struct {
+	Token     string    `json:"token"`
+	UserID    int       `json:"userID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// hashToken returns the hex encoded SHA-256 of a session token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken generates a random URL-safe session token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateSession starts a new session for a user that lasts for ttl
+func (db *DB) CreateSession(ctx context.Context, userID int, ttl time.Duration) (*Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &Session{Token: token, UserID: userID, ExpiresAt: now.Add(ttl).UTC().Truncate(time.Second)}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?)`,
+		hashToken(token),
+		userID,
+		now.Unix(),
+		session.ExpiresAt.Unix())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// SessionUser retrieves the user a session token belongs to. Unknown and
+// expired tokens return ErrNotFound.
+func (db *DB) SessionUser(ctx context.Context, token string) (*User, error) {
+	row := db.QueryRowContext(ctx, `
+		SELECT `+qualify("u", userColumns)+`
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.expires_at > ?`,
+		hashToken(token),
+		time.Now().Unix())
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteSession ends a session, along with any other expired sessions
+func (db *DB) DeleteSession(ctx context.Context, token string) error {
+	result, err := db.ExecContext(ctx, `
+		DELETE
+		FROM sessions
+		WHERE token_hash = ?`,
+		hashToken(token))
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows < 1 {
+		return ErrNotFound
+	}
+
+	_, err = db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?", time.Now().Unix())
+	return err
+}
@@ -0,0 +1,310 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+)
+
+// Transaction statuses
+const (
+	StatusPending = "pending"
+	StatusCleared = "cleared"
+)
+
+// Transaction is a single charge or payment posted against an Account.
+// Positive amounts are charges that increase what is owed on the account,
+// negative amounts are payments that reduce it.
+type Transaction struct {
+	ID         int    `json:"ID"`
+	AccountID  int    `json:"accountID"`
+	Amount     Money  `json:"amount"`
+	PostedDate string `json:"postedDate"`
+	Payee      string `json:"payee"`
+	Memo       string `json:"memo"`
+	Status     string `json:"status"`
+	// ImportID identifies a transaction that came from a bank statement, so
+	// importing the same statement twice doesn't post it twice
+	ImportID string `json:"importID,omitempty"`
+}
+
+// Balance is the balance of an Account derived from its transactions
+type Balance struct {
+	AccountID int   `json:"accountID"`
+	Balance   Money `json:"balance"`
+	Cleared   Money `json:"cleared"`
+	Pending   Money `json:"pending"`
+}
+
+// transactionColumns is the column list matching scanTransaction, selected from
+// transactions joined to their accounts with transactionsFrom. Transactions are in
+// the currency of their account.
+const transactionColumns = "t.id, t.account_id, t.amount, t.posted_date, t.payee, t.memo, t.status, t.import_id, a.currency"
+
+// transactionsFrom joins transactions to the accounts they're posted against
+const transactionsFrom = " FROM transactions t JOIN accounts a ON a.id = t.account_id"
+
+// scanTransaction scans a row selected with transactionColumns into a Transaction
+func scanTransaction(row interface{ Scan(...interface{}) error }) (*Transaction, error) {
+	transaction := new(Transaction)
+	var currency string
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.AccountID,
+		&transaction.Amount,
+		&transaction.PostedDate,
+		&transaction.Payee,
+		&transaction.Memo,
+		&transaction.Status,
+		&transaction.ImportID,
+		&currency)
+	transaction.Amount.Currency = currencyOrDefault(currency)
+
+	return transaction, err
+}
+
+// AccountTransactions retrieves all transaction rows for an account, oldest first
+func (db *DB) AccountTransactions(ctx context.Context, accountID int) ([]*Transaction, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+transactionColumns+transactionsFrom+" WHERE t.account_id = ? ORDER BY t.posted_date, t.id", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]*Transaction, 0)
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// Validate validates the fields in a Transaction object, returning a *ValidationError if any are wrong
+func (t *Transaction) Validate() error {
+	statusPattern := regexp.MustCompile(`^(pending|cleared)$`)
+
+	v := new(ValidationError)
+
+	if t.AccountID < 1 {
+		v.Add("accountID", "required", "must be the ID of an existing account")
+	}
+
+	if _, err := time.Parse("2006-01-02", t.PostedDate); err != nil {
+		v.Add("postedDate", "date", "must be a date formatted as YYYY-MM-DD")
+	}
+
+	if t.Payee == "" {
+		v.Add("payee", "required", "is required")
+	}
+
+	if !statusPattern.MatchString(t.Status) {
+		v.Add("status", "oneOf", "must be pending or cleared")
+	}
+
+	return v.Err()
+}
+
+// GetTransaction retrieves a transaction that matches the transactionID parameter
+// from the transactions table, otherwise will return nothing.
+func (db *DB) GetTransaction(ctx context.Context, transactionID int) (*Transaction, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+transactionColumns+transactionsFrom+" WHERE t.id = ?", transactionID)
+
+	transaction, err := scanTransaction(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// CreateTransaction creates a transaction in the database and returns the created transaction
+func (db *DB) CreateTransaction(ctx context.Context, t Transaction) (*Transaction, error) {
+	id, err := db.insert(ctx, `
+		INSERT INTO transactions (account_id, amount, posted_date, payee, memo, status, import_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.AccountID,
+		t.Amount,
+		t.PostedDate,
+		t.Payee,
+		t.Memo,
+		t.Status,
+		t.ImportID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := db.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// ImportedIDs retrieves the import IDs of every transaction imported into an account
+func (db *DB) ImportedIDs(ctx context.Context, accountID int) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT import_id
+		FROM transactions
+		WHERE account_id = ? AND import_id <> ''`,
+		accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// ImportTransactions creates transactions in an account in a single database
+// transaction, so either the whole statement is imported or none of it is
+func (db *DB) ImportTransactions(ctx context.Context, accountID int, transactions []Transaction) ([]*Transaction, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]*Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		id, err := tx.insert(ctx, `
+			INSERT INTO transactions (account_id, amount, posted_date, payee, memo, status, import_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			accountID,
+			t.Amount,
+			t.PostedDate,
+			t.Payee,
+			t.Memo,
+			t.Status,
+			t.ImportID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		transaction := t
+		transaction.ID = id
+		transaction.AccountID = accountID
+		created = append(created, &transaction)
+	}
+
+	var currency string
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE((SELECT currency FROM accounts WHERE id = ?), ?)", accountID, DefaultCurrency).Scan(&currency)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, transaction := range created {
+		transaction.Amount.Currency = currency
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateTransaction updates a full resource in the database and returns an error if something goes wrong
+func (db *DB) UpdateTransaction(ctx context.Context, transactionID int, t *Transaction) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE transactions
+		SET
+			account_id = ?,
+			amount = ?,
+			posted_date = ?,
+			payee = ?,
+			memo = ?,
+			status = ?
+		WHERE id = ?`,
+		t.AccountID,
+		t.Amount,
+		t.PostedDate,
+		t.Payee,
+		t.Memo,
+		t.Status,
+		transactionID)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTransaction removes a resource from the database and returns an error if something goes wrong
+func (db *DB) DeleteTransaction(ctx context.Context, transactionID int) error {
+	result, err := db.ExecContext(ctx, `
+		DELETE
+		FROM transactions
+		WHERE id = ?`,
+		transactionID)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// AccountBalance sums the ledger of an account into its current balance, in the
+// account's currency
+func (db *DB) AccountBalance(ctx context.Context, accountID int) (*Balance, error) {
+	row := db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE((SELECT currency FROM accounts WHERE id = ?), ?),
+			COALESCE(SUM(amount), 0),
+			COALESCE(SUM(CASE WHEN status = ? THEN amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = ? THEN amount ELSE 0 END), 0)
+		FROM transactions
+		WHERE account_id = ?`,
+		accountID,
+		DefaultCurrency,
+		StatusCleared,
+		StatusPending,
+		accountID)
+
+	balance := &Balance{AccountID: accountID}
+	var currency string
+	err := row.Scan(&currency, &balance.Balance, &balance.Cleared, &balance.Pending)
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance.Currency = currency
+	balance.Cleared.Currency = currency
+	balance.Pending.Currency = currency
+
+	return balance, nil
+}
@@ -1,23 +1,76 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
-// User is a user of the applications
+// User is a user of the applications. Their income is in their Currency.
 type User struct {
-	ID             int     `json:"ID"`
-	FirstName      string  `json:"firstName"`
-	LastName       string  `json:"lastName"`
-	FullName       string  `json:"fullName"`
-	Email          string  `json:"email"`
-	BiweeklyIncome float64 `json:"biweeklyIncome"`
+	ID             int    `json:"ID"`
+	FirstName      string `json:"firstName"`
+	LastName       string `json:"lastName"`
+	FullName       string `json:"fullName"`
+	Email          string `json:"email"`
+	BiweeklyIncome Money  `json:"biweeklyIncome"`
+	Currency       string `json:"currency"`
+	Payday         string `json:"payday"`
+	PasswordHash   string `json:"-"`
+	Version        int    `json:"-"`
+}
+
+// MinPasswordLength is the shortest password accepted at registration
+const MinPasswordLength = 8
+
+// userColumns is the column list matching scanUser
+const userColumns = "id, first_name, last_name, full_name, email, biweekly_income, currency, payday, password_hash, version"
+
+// scanUser scans a row selected with userColumns into a User
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	user := new(User)
+	err := row.Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.FullName,
+		&user.Email,
+		&user.BiweeklyIncome,
+		&user.Currency,
+		&user.Payday,
+		&user.PasswordHash,
+		&user.Version)
+	user.SetCurrency(user.Currency)
+
+	return user, err
+}
+
+// SetCurrency sets the currency of the user and of their income. An empty code
+// sets DefaultCurrency.
+func (u *User) SetCurrency(code string) {
+	u.Currency = currencyOrDefault(code)
+	u.BiweeklyIncome.Currency = u.Currency
+}
+
+// UnmarshalJSON decodes a user, putting their income in their currency
+func (u *User) UnmarshalJSON(b []byte) error {
+	type user User
+	if err := json.Unmarshal(b, (*user)(u)); err != nil {
+		return err
+	}
+
+	u.SetCurrency(u.Currency)
+	return nil
 }
 
 // AllUsers retrieves all user rows from the users table
-func (db *DB) AllUsers() ([]*User, error) {
-	rows, err := db.Query("SELECT * FROM users")
+func (db *DB) AllUsers(ctx context.Context) ([]*User, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+userColumns+" FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -25,15 +78,7 @@ func (db *DB) AllUsers() ([]*User, error) {
 
 	users := make([]*User, 0)
 	for rows.Next() {
-		user := new(User)
-		err := rows.Scan(
-			&user.ID,
-			&user.FirstName,
-			&user.LastName,
-			&user.FullName,
-			&user.Email,
-			&user.BiweeklyIncome)
-
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -47,44 +92,127 @@ func (db *DB) AllUsers() ([]*User, error) {
 	return users, nil
 }
 
-// Validate validates the fields in a User object
-func (u *User) Validate() bool {
+// UserFilter narrows the users ListUsers returns. Zero values don't filter.
+type UserFilter struct {
+	ID    int
+	Email string
+}
+
+// userSortColumns are the user fields ListUsers can sort by
+var userSortColumns = map[string]string{
+	"ID":             "id",
+	"firstName":      "first_name",
+	"lastName":       "last_name",
+	"fullName":       "full_name",
+	"email":          "email",
+	"biweeklyIncome": "biweekly_income",
+}
+
+// ListUsers retrieves one page of the user rows matching filter, along with the
+// number of rows that match across all pages
+func (db *DB) ListUsers(ctx context.Context, filter UserFilter, opts ListOptions) ([]*User, int, error) {
+	where := new(whereClause)
+	if filter.ID != 0 {
+		where.add("id = ?", filter.ID)
+	}
+	if filter.Email != "" {
+		where.add("email = ?", filter.Email)
+	}
+
+	order, err := orderBy(opts, userSortColumns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where.String(), where.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+userColumns+" FROM users"+where.String()+order, where.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Validate validates the fields in a User object, returning a *ValidationError if any are wrong
+func (u *User) Validate() error {
 	namePattern := regexp.MustCompile(`^[a-zA-Z ]+$`)
 	emailPattern := regexp.MustCompile(`^([\w-]+(?:\.[\w-]+)*)@((?:[\w-]+\.)*\w[\w-]{0,66})\.([a-z]{2,6}(?:\.[a-z]{2})?)$`)
 
+	v := new(ValidationError)
+
 	if !namePattern.MatchString(u.FirstName) {
-		return false
+		v.Add("firstName", "pattern", "must only contain letters and spaces")
 	}
 
 	if !namePattern.MatchString(u.LastName) {
-		return false
+		v.Add("lastName", "pattern", "must only contain letters and spaces")
 	}
 
 	if !namePattern.MatchString(u.FullName) {
-		return false
+		v.Add("fullName", "pattern", "must only contain letters and spaces")
 	}
 
 	if !emailPattern.MatchString(u.Email) {
-		return false
+		v.Add("email", "email", "must be a valid email address")
+	}
+
+	currency := currencyOrDefault(u.Currency)
+	if !currencyPattern.MatchString(currency) {
+		v.Add("currency", "pattern", "must be a three letter ISO 4217 currency code")
 	}
 
-	return true
+	if u.BiweeklyIncome.currency() != currency {
+		v.Add("biweeklyIncome", "currency", "must be in the user's currency")
+	}
+
+	if u.Payday != "" {
+		if _, err := time.Parse("2006-01-02", u.Payday); err != nil {
+			v.Add("payday", "date", "must be a date formatted as YYYY-MM-DD")
+		}
+	}
+
+	return v.Err()
 }
 
 // GetUser retrieves a user that matches the userID parameter
 // from the users table, otherwise will return nothing.
-func (db *DB) GetUser(userID int) (*User, error) {
-	row := db.QueryRow("SELECT * FROM users WHERE id = ?", userID)
+func (db *DB) GetUser(ctx context.Context, userID int) (*User, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", userID)
 
-	user := new(User)
-	err := row.Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.FullName,
-		&user.Email,
-		&user.BiweeklyIncome)
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UserByEmail retrieves the user with the given email address, otherwise will return nothing
+func (db *DB) UserByEmail(ctx context.Context, email string) (*User, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = ?", email)
 
+	user, err := scanUser(row)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	} else if err != nil {
@@ -94,27 +222,65 @@ func (db *DB) GetUser(userID int) (*User, error) {
 	return user, nil
 }
 
+// ValidateRegistration validates a user being registered along with the password
+// they'll log in with
+func (u *User) ValidateRegistration(password string) error {
+	v := new(ValidationError)
+	if err, ok := u.Validate().(*ValidationError); ok {
+		v.Fields = append(v.Fields, err.Fields...)
+	}
+
+	if len(password) < MinPasswordLength {
+		v.Add("password", "minLength", fmt.Sprintf("must be at least %d characters", MinPasswordLength))
+	}
+
+	return v.Err()
+}
+
+// SetPassword hashes password with bcrypt and stores the hash on the User
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// dummyHash is the bcrypt hash of a random password at the default cost. It's
+// checked when there's no real hash so a login takes as long either way.
+const dummyHash = "$2a$10$lSuSnmau3hksAUNtURqB1.JzYXM5BiAEQCkdCH5WHlKyMtoTos1QW"
+
+// CheckPassword reports whether password matches the User's stored hash. Users
+// without a password can never log in, but still pay for a comparison.
+func (u *User) CheckPassword(password string) bool {
+	if u.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
 // CreateUser creates a user in the database and returns the user in JSON in the response
-func (db *DB) CreateUser(u User) (*User, error) {
-	result, err := db.Exec(`
-		INSERT INTO users (first_name, last_name, full_name, email, biweekly_income)
-		VALUES (?, ?, ?, ?, ?)`,
+func (db *DB) CreateUser(ctx context.Context, u User) (*User, error) {
+	id, err := db.insert(ctx, `
+		INSERT INTO users (first_name, last_name, full_name, email, biweekly_income, currency, payday, password_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		u.FirstName,
 		u.LastName,
 		u.FullName,
 		u.Email,
-		u.BiweeklyIncome)
-
-	if err != nil {
-		return nil, err
-	}
-
-	id, err := result.LastInsertId()
+		u.BiweeklyIncome,
+		currencyOrDefault(u.Currency),
+		u.Payday,
+		u.PasswordHash)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := db.GetUser(int(id))
+	user, err := db.GetUser(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -122,49 +288,193 @@ func (db *DB) CreateUser(u User) (*User, error) {
 	return user, nil
 }
 
-// UpdateUser updates a full resource in the database and returns an error if something goes wrong
-func (db *DB) UpdateUser(userID int, u *User) error {
-	_, err := db.Exec(`
+// UpdateUser updates a full resource in the database and returns an error if something goes wrong.
+// A non-zero u.Version makes the update conditional on the row still being at that
+// version, returning ErrVersionConflict if it isn't.
+func (db *DB) UpdateUser(ctx context.Context, userID int, u *User) error {
+	query := `
 		UPDATE users
 		SET
 			first_name = ?,
 			last_name = ?,
 			full_name = ?,
 			email = ?,
-			biweekly_income = ?
-		WHERE id = ?`,
+			biweekly_income = ?,
+			currency = ?,
+			payday = ?,
+			version = version + 1
+		WHERE id = ?`
+	args := []interface{}{
 		u.FirstName,
 		u.LastName,
 		u.FullName,
 		u.Email,
 		u.BiweeklyIncome,
-		userID)
+		currencyOrDefault(u.Currency),
+		u.Payday,
+		userID,
+	}
+
+	return db.updateVersioned(ctx, query, args, u.Version)
+}
+
+// userColumnsByField maps the JSON names of the User fields that can be patched to their columns
+var userColumnsByField = map[string]string{
+	"firstName":      "first_name",
+	"lastName":       "last_name",
+	"fullName":       "full_name",
+	"email":          "email",
+	"biweeklyIncome": "biweekly_income",
+	"currency":       "currency",
+	"payday":         "payday",
+}
+
+// Changes lists the JSON names of the fields that differ between u and v. Income is
+// compared without its currency, which is reported as the currency field.
+func (u *User) Changes(v *User) []string {
+	fields := make([]string, 0)
+	if u.FirstName != v.FirstName {
+		fields = append(fields, "firstName")
+	}
+	if u.LastName != v.LastName {
+		fields = append(fields, "lastName")
+	}
+	if u.FullName != v.FullName {
+		fields = append(fields, "fullName")
+	}
+	if u.Email != v.Email {
+		fields = append(fields, "email")
+	}
+	if u.BiweeklyIncome.Amount != v.BiweeklyIncome.Amount {
+		fields = append(fields, "biweeklyIncome")
+	}
+	if currencyOrDefault(u.Currency) != currencyOrDefault(v.Currency) {
+		fields = append(fields, "currency")
+	}
+	if u.Payday != v.Payday {
+		fields = append(fields, "payday")
+	}
+
+	return fields
+}
+
+// PatchUser updates only the columns of the named fields of a user, leaving the
+// rest of the row alone
+func (db *DB) PatchUser(ctx context.Context, userID int, u *User, fields []string) error {
+	values := map[string]interface{}{
+		"firstName":      u.FirstName,
+		"lastName":       u.LastName,
+		"fullName":       u.FullName,
+		"email":          u.Email,
+		"biweeklyIncome": u.BiweeklyIncome,
+		"currency":       currencyOrDefault(u.Currency),
+		"payday":         u.Payday,
+	}
+
+	return db.patchRow(ctx, "users", userID, u.Version, userColumnsByField, values, fields)
+}
+
+// User delete policy modes
+const (
+	// DeleteRestrict refuses to delete a user that still has accounts
+	DeleteRestrict = "restrict"
+	// DeleteCascade deletes the user's accounts, and their transactions, along with the user
+	DeleteCascade = "cascade"
+	// DeleteReassign hands the user's accounts over to another user before deleting
+	DeleteReassign = "reassign"
+)
 
+// DeletePolicy configures what DeleteUser does with a user's accounts.
+// The zero value restricts.
+type DeletePolicy struct {
+	Mode       string
+	ReassignTo int
+}
+
+// ParseDeletePolicy builds a DeletePolicy from its mode name, checking that a
+// reassign policy has a user to reassign to
+func ParseDeletePolicy(mode string, reassignTo int) (DeletePolicy, error) {
+	switch mode {
+	case DeleteRestrict, DeleteCascade:
+		return DeletePolicy{Mode: mode}, nil
+	case DeleteReassign:
+		if reassignTo < 1 {
+			return DeletePolicy{}, ErrBadDeletePolicy
+		}
+		return DeletePolicy{Mode: mode, ReassignTo: reassignTo}, nil
+	}
+
+	return DeletePolicy{}, ErrBadDeletePolicy
+}
+
+// DeleteUser removes a resource from the database and returns an error if something goes wrong.
+// The user's accounts are handled according to db.UserDeletePolicy; with the restrict policy
+// ErrHasDependents is returned while the user still has accounts. A non-zero version makes
+// the delete conditional, returning ErrVersionConflict unless the user is still at that version.
+func (db *DB) DeleteUser(ctx context.Context, userID int, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	// id, err := result.LastInsertId()
-	// if err != nil {
-	// 	return err
-	// }
-
-	// rows, err := result.RowsAffected()
-	// if err != nil {
-	// 	return err
-	// }
+	err = deleteUser(ctx, tx, userID, version, db.UserDeletePolicy)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	return nil
+	return tx.Commit()
 }
 
-// DeleteUser removes a resource from the database and returns an error if something goes wrong
-func (db *DB) DeleteUser(userID int) error {
-	result, err := db.Exec(`
+func deleteUser(ctx context.Context, tx *Tx, userID int, version int, policy DeletePolicy) error {
+	var current int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM users WHERE id = ?", userID).Scan(&current)
+	if err == sql.ErrNoRows && version != 0 {
+		return ErrVersionConflict
+	} else if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if version != 0 && current != version {
+		return ErrVersionConflict
+	}
+
+	switch policy.Mode {
+	case DeleteCascade:
+		// Transactions go with their accounts through ON DELETE CASCADE
+		_, err = tx.ExecContext(ctx, "DELETE FROM accounts WHERE user_id = ?", userID)
+	case DeleteReassign:
+		if policy.ReassignTo == userID {
+			return ErrHasDependents
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE accounts SET user_id = ? WHERE user_id = ?", policy.ReassignTo, userID)
+	case DeleteRestrict, "":
+		var accounts int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM accounts WHERE user_id = ?", userID).Scan(&accounts)
+		if err == nil && accounts > 0 {
+			return ErrHasDependents
+		}
+	default:
+		return ErrBadDeletePolicy
+	}
+	if err != nil {
+		return err
+	}
+
+	// The version is checked again in case the user changed since it was read
+	query := `
 		DELETE
 		FROM users
-		WHERE id = ?`,
-		userID)
+		WHERE id = ?`
+	args := []interface{}{userID}
+	if version != 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
 
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -174,8 +484,8 @@ func (db *DB) DeleteUser(userID int) error {
 		return err
 	}
 
-	if rows < 1 {
-		return ErrNotFound
+	if rows < 1 && version != 0 {
+		return ErrVersionConflict
 	}
 
 	return nil
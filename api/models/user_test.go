@@ -0,0 +1,34 @@
+package models_test
+
+import (
+	"dinero/api/models"
+	"testing"
+)
+
+func TestCheckPassword(t *testing.T) {
+	t.Parallel()
+
+	var withPassword models.User
+	if err := withPassword.SetPassword("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		user     models.User
+		password string
+		expected bool
+	}{
+		{name: "CORRECT", user: withPassword, password: "correct horse", expected: true},
+		{name: "WRONG", user: withPassword, password: "battery staple"},
+		// users without a password are checked against a dummy hash
+		{name: "NO_PASSWORD", user: models.User{}, password: "correct horse"},
+		{name: "NO_PASSWORD_EMPTY", user: models.User{}, password: ""},
+	}
+
+	for _, test := range tests {
+		if got := test.user.CheckPassword(test.password); got != test.expected {
+			t.Errorf("\n%s:\n\tGot: \t\t%v\n\tExpected: \t%v\n", test.name, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents to JSON documents.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchType is the media type of a JSON Merge Patch document
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is the media type of a JSON Patch document
+	JSONPatchType = "application/json-patch+json"
+)
+
+var (
+	// ErrMalformed is returned for patch documents that aren't valid JSON or
+	// that contain operations this package doesn't understand
+	ErrMalformed = errors.New("patch: malformed patch document")
+	// ErrTestFailed is returned when a JSON Patch "test" operation doesn't match
+	ErrTestFailed = errors.New("patch: test operation failed")
+)
+
+// PathError is returned when a JSON Patch operation points at a location that
+// doesn't exist in the document
+type PathError struct {
+	Op   string
+	Path string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("patch: %s: path %q does not exist", e.Op, e.Path)
+}
+
+// operation is a single operation of a JSON Patch document
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decode unmarshals JSON keeping numbers as json.Number, so amounts come back
+// out exactly as they went in
+func decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	// Anything after the first value means the document wasn't a single value
+	var extra interface{}
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+// MergePatch applies a JSON Merge Patch document to doc and returns the result
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := decode(patch, &p); err != nil {
+		return nil, ErrMalformed
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+// merge is the MergePatch algorithm from section 2 of RFC 7396
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = merge(t[key], value)
+		}
+	}
+
+	return t
+}
+
+// Apply applies a JSON Patch document to doc and returns the result. The
+// operations are applied in order and either all of them succeed or doc is
+// left as it was.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var ops []operation
+	if err := decode(patch, &ops); err != nil {
+		return nil, ErrMalformed
+	}
+
+	var err error
+	for _, op := range ops {
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+// apply applies one operation to doc, returning the new document
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, ErrMalformed
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, ErrMalformed
+		}
+		if err := decode(op.Value, &value); err != nil {
+			return nil, ErrMalformed
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, ErrMalformed
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value, op)
+
+	case "remove":
+		_, doc, err = remove(doc, path, op)
+		return doc, err
+
+	case "replace":
+		if _, err := get(doc, path, op); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		_, doc, err = remove(doc, path, op)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value, op)
+
+	case "move":
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		// A location can't be moved into one of its own children
+		if len(from) < len(path) && *op.Path != *op.From && strings.HasPrefix(*op.Path, *op.From+"/") {
+			return nil, ErrMalformed
+		}
+		value, doc, err = remove(doc, from, op)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value, op)
+
+	case "copy":
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err = get(doc, from, op)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value), op)
+
+	case "test":
+		current, err := get(doc, path, op)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+
+	return nil, ErrMalformed
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, ErrMalformed
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return tokens, nil
+}
+
+// pathError builds the PathError for op
+func (op operation) pathError() error {
+	return &PathError{Op: op.Op, Path: *op.Path}
+}
+
+// index parses an array index token. With end set, "-" and len(array) are
+// allowed and mean the end of the array.
+func index(token string, length int, end bool) (int, bool) {
+	if end && token == "-" {
+		return length, true
+	}
+
+	// Leading zeros aren't allowed
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !end) {
+		return 0, false
+	}
+
+	return i, true
+}
+
+// get returns the value at path
+func get(doc interface{}, path []string, op operation) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, op.pathError()
+			}
+			doc = value
+		case []interface{}:
+			i, ok := index(token, len(node), false)
+			if !ok {
+				return nil, op.pathError()
+			}
+			doc = node[i]
+		default:
+			return nil, op.pathError()
+		}
+	}
+
+	return doc, nil
+}
+
+// add adds value at path, returning the new document
+func add(doc interface{}, path []string, value interface{}, op operation) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1], op)
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+		return doc, nil
+	case []interface{}:
+		i, ok := index(token, len(node), true)
+		if !ok {
+			return nil, op.pathError()
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return set(doc, path[:len(path)-1], node), nil
+	}
+
+	return nil, op.pathError()
+}
+
+// remove removes the value at path, returning it along with the new document
+func remove(doc interface{}, path []string, op operation) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return doc, nil, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1], op)
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[token]
+		if !ok {
+			return nil, nil, op.pathError()
+		}
+		delete(node, token)
+		return value, doc, nil
+	case []interface{}:
+		i, ok := index(token, len(node), false)
+		if !ok {
+			return nil, nil, op.pathError()
+		}
+		value := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		return value, set(doc, path[:len(path)-1], node), nil
+	}
+
+	return nil, nil, op.pathError()
+}
+
+// set replaces the value at an existing path, which is needed after an array
+// grows or shrinks because the slice header changes
+func set(doc interface{}, path []string, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	parent, _ := get(doc, path[:len(path)-1], operation{})
+	token := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[token] = value
+	case []interface{}:
+		i, _ := index(token, len(node), false)
+		node[i] = value
+	}
+
+	return doc
+}
+
+// deepCopy copies a decoded JSON value so a "copy" doesn't alias its source
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			c[key] = deepCopy(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+	}
+
+	return value
+}
+
+// equal compares two decoded JSON values the way a "test" operation does, where
+// numbers are equal if their values are, however they are written
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		m, okM := new(big.Rat).SetString(string(x))
+		n, okN := new(big.Rat).SetString(string(y))
+		return okM && okN && m.Cmp(n) == 0
+	}
+
+	return a == b
+}
@@ -0,0 +1,111 @@
+package patch_test
+
+import (
+	"dinero/api/patch"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// The examples from appendix A of RFC 7396
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"REPLACE", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"ADD", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"REMOVE", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"REMOVE_ONE", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"ARRAY_TO_STRING", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"STRING_TO_ARRAY", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"NESTED", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"ARRAY_OF_OBJECTS", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"NOT_OBJECT", `["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{"OBJECT_TO_ARRAY", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"NULL", `{"a":"foo"}`, `null`, `null`},
+		{"STRING", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"NULL_VALUE", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{"ARRAY_TO_OBJECT", `[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{"DEEP", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{"EXACT_NUMBERS", `{"amount":217.99}`, `{"amount":1000000000000000.01}`, `{"amount":1000000000000000.01}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := patch.MergePatch([]byte(test.doc), []byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.expected {
+				t.Errorf("\nDocument:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+
+	if _, err := patch.MergePatch([]byte(`{}`), []byte(`{"a":`)); err != patch.ErrMalformed {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, patch.ErrMalformed)
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	// Mostly the examples from appendix A of RFC 6902
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      error
+	}{
+		{"ADD_MEMBER", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"ADD_ELEMENT", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"ADD_END", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, nil},
+		{"REMOVE_MEMBER", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"REMOVE_ELEMENT", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"REPLACE", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, nil},
+		{"MOVE", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"MOVE_ELEMENT", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+		{"COPY", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"add","path":"/baz/bar","value":2}]`, `{"baz":{"bar":2},"foo":{"bar":1}}`, nil},
+		{"TEST", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"TEST_NUMBERS", `{"amount":100}`, `[{"op":"test","path":"/amount","value":100.00}]`, `{"amount":100}`, nil},
+		{"TEST_ESCAPED", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, nil},
+		{"ADD_NULL", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"baz":null,"foo":"bar"}`, nil},
+		{"REPLACE_ROOT", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":"qux"}}]`, `{"baz":"qux"}`, nil},
+		{"TEST_FAILED", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", patch.ErrTestFailed},
+		{"TEST_FAILED_STRING_NUMBER", `{"baz":"10"}`, `[{"op":"test","path":"/baz","value":10}]`, "", patch.ErrTestFailed},
+		{"MISSING_PARENT", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", &patch.PathError{Op: "add", Path: "/baz/bat"}},
+		{"MISSING_REMOVE", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, "", &patch.PathError{Op: "remove", Path: "/baz"}},
+		{"OUT_OF_BOUNDS", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":"qux"}]`, "", &patch.PathError{Op: "add", Path: "/foo/5"}},
+		{"LEADING_ZERO", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, "", &patch.PathError{Op: "remove", Path: "/foo/01"}},
+		{"MISSING_VALUE", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, "", patch.ErrMalformed},
+		{"UNKNOWN_OP", `{"foo":"bar"}`, `[{"op":"frobnicate","path":"/foo"}]`, "", patch.ErrMalformed},
+		{"MOVE_INTO_CHILD", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, "", patch.ErrMalformed},
+		{"NOT_ARRAY", `{"foo":"bar"}`, `{"op":"remove","path":"/foo"}`, "", patch.ErrMalformed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := patch.Apply([]byte(test.doc), []byte(test.patch))
+			if pathErr, ok := test.err.(*patch.PathError); ok {
+				gotErr, ok := err.(*patch.PathError)
+				if !ok || *gotErr != *pathErr {
+					t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, test.err)
+				}
+				return
+			}
+
+			if err != test.err {
+				t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, test.err)
+			}
+
+			if test.err == nil && string(got) != test.expected {
+				t.Errorf("\nDocument:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Package payoff simulates paying off a user's debts month by month, putting any
+// money left after the minimum payments towards one debt at a time.
+package payoff
+
+import (
+	"dinero/api/models"
+	"errors"
+	"sort"
+	"time"
+)
+
+// MonthFormat is the layout months are written in
+const MonthFormat = "2006-01"
+
+// maxMonths bounds simulations where payments barely outpace interest
+const maxMonths = 600
+
+// Strategy decides which debt gets the money left after minimum payments
+type Strategy string
+
+const (
+	// Avalanche pays the debt with the highest APR first, which pays the least interest
+	Avalanche Strategy = "avalanche"
+	// Snowball pays the debt with the smallest balance first, which closes debts soonest
+	Snowball Strategy = "snowball"
+	// Custom pays debts in the order of Options.Order
+	Custom Strategy = "custom"
+)
+
+var (
+	// ErrUnknownStrategy is returned for strategies other than the ones above
+	ErrUnknownStrategy = errors.New("payoff: unknown strategy")
+	// ErrUnknownAccount is returned when a custom order names an account that isn't
+	// one of the debts being paid off
+	ErrUnknownAccount = errors.New("payoff: order names an account that isn't a debt")
+	// ErrMixedCurrencies is returned when the debts aren't all in one currency
+	ErrMixedCurrencies = errors.New("payoff: debts aren't all in one currency")
+)
+
+// Options configures a simulation
+type Options struct {
+	Strategy Strategy
+	// Order lists account IDs from first to last to pay off with the Custom strategy.
+	// Debts it leaves out are paid after, in avalanche order.
+	Order []int
+	// Extra is paid on top of the accounts' payments every month, in the
+	// currency of the debts whatever its own
+	Extra models.Money
+	// Start is the month of the first payment
+	Start time.Time
+}
+
+// Payment is what happened to one debt in one month
+type Payment struct {
+	AccountID int          `json:"accountID"`
+	Payment   models.Money `json:"payment"`
+	Interest  models.Money `json:"interest"`
+	Principal models.Money `json:"principal"`
+	Balance   models.Money `json:"balance"`
+}
+
+// Month is one row of the amortization table
+type Month struct {
+	Month    string    `json:"month"`
+	Payments []Payment `json:"payments"`
+}
+
+// Debt is the outcome for one account
+type Debt struct {
+	AccountID    int          `json:"accountID"`
+	Name         string       `json:"name"`
+	PaidOff      bool         `json:"paidOff"`
+	PayoffDate   string       `json:"payoffDate,omitempty"`
+	Months       int          `json:"months"`
+	InterestPaid models.Money `json:"interestPaid"`
+	TotalPaid    models.Money `json:"totalPaid"`
+}
+
+// Result is the outcome of a simulation
+type Result struct {
+	Strategy Strategy `json:"strategy"`
+	// MonthlyBudget is the total paid each month until the debts are gone
+	MonthlyBudget models.Money `json:"monthlyBudget"`
+	// PaidOff is false when the budget can't keep up with the interest
+	PaidOff       bool         `json:"paidOff"`
+	PayoffDate    string       `json:"payoffDate,omitempty"`
+	Months        int          `json:"months"`
+	TotalInterest models.Money `json:"totalInterest"`
+	TotalPaid     models.Money `json:"totalPaid"`
+	Accounts      []Debt       `json:"accounts"`
+	Schedule      []Month      `json:"schedule"`
+}
+
+// debt is an account being paid off during a simulation
+type debt struct {
+	account *models.Account
+	balance models.Money
+	result  *Debt
+}
+
+// Simulate pays off the accounts with an outstanding FullAmount month by month.
+// The monthly budget is the sum of each account's CurrentPayment, or its
+// MinimumPayment if that's larger, plus opts.Extra. Every month interest is added
+// at exactly a twelfth of the APR, rounded half to even to the cent, each debt
+// gets its minimum payment and the rest of the budget goes to debts in the order
+// of opts.Strategy, so the payments of debts that are paid off roll over to the
+// next. The debts have to be in one currency.
+func Simulate(accounts []*models.Account, opts Options) (*Result, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = Avalanche
+	}
+
+	debts := make([]*debt, 0)
+	for _, account := range accounts {
+		if !account.FullAmount.IsNegative() && !account.FullAmount.IsZero() {
+			debts = append(debts, &debt{account: account, balance: account.FullAmount})
+		}
+	}
+
+	extra := opts.Extra
+	for _, d := range debts {
+		if !d.balance.SameCurrency(debts[0].balance) {
+			return nil, ErrMixedCurrencies
+		}
+		extra.Currency = d.balance.Currency
+	}
+
+	zero := models.NewMoney(0, extra.Currency)
+	result := &Result{
+		Strategy:      opts.Strategy,
+		MonthlyBudget: zero.Add(extra),
+		TotalInterest: zero,
+		TotalPaid:     zero,
+		Accounts:      make([]Debt, 0),
+		Schedule:      make([]Month, 0),
+	}
+
+	if err := order(debts, opts); err != nil {
+		return nil, err
+	}
+
+	for _, d := range debts {
+		payment := d.account.CurrentPayment
+		if d.account.MinimumPayment.Cmp(payment) > 0 {
+			payment = d.account.MinimumPayment
+		}
+		result.MonthlyBudget = result.MonthlyBudget.Add(payment)
+
+		result.Accounts = append(result.Accounts, Debt{AccountID: d.account.ID, Name: d.account.Name, InterestPaid: zero, TotalPaid: zero})
+	}
+	for i, d := range debts {
+		d.result = &result.Accounts[i]
+	}
+
+	start := time.Date(opts.Start.Year(), opts.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month := 0; month < maxMonths; month++ {
+		open := openDebts(debts)
+		if len(open) == 0 {
+			break
+		}
+
+		label := start.AddDate(0, month, 0).Format(MonthFormat)
+		row, progress, err := payMonth(open, result.MonthlyBudget, label)
+		if err != nil {
+			return nil, err
+		}
+		result.Schedule = append(result.Schedule, row)
+		result.Months = month + 1
+
+		// Once a month passes without the debts going down they never will
+		if !progress {
+			break
+		}
+	}
+
+	result.PaidOff = true
+	for _, d := range debts {
+		result.TotalInterest = result.TotalInterest.Add(d.result.InterestPaid)
+		result.TotalPaid = result.TotalPaid.Add(d.result.TotalPaid)
+		if !d.result.PaidOff {
+			result.PaidOff = false
+		}
+	}
+	if result.PaidOff && len(result.Schedule) > 0 {
+		result.PayoffDate = result.Schedule[len(result.Schedule)-1].Month
+	}
+
+	return result, nil
+}
+
+// order sorts debts into the order the strategy pays them off in
+func order(debts []*debt, opts Options) error {
+	avalanche := func(a, b *debt) bool {
+		if a.account.APR != b.account.APR {
+			return a.account.APR > b.account.APR
+		}
+		if c := a.balance.Cmp(b.balance); c != 0 {
+			return c < 0
+		}
+		return a.account.ID < b.account.ID
+	}
+
+	switch opts.Strategy {
+	case Avalanche:
+		sort.SliceStable(debts, func(i, j int) bool { return avalanche(debts[i], debts[j]) })
+
+	case Snowball:
+		sort.SliceStable(debts, func(i, j int) bool {
+			a, b := debts[i], debts[j]
+			if c := a.balance.Cmp(b.balance); c != 0 {
+				return c < 0
+			}
+			return avalanche(a, b)
+		})
+
+	case Custom:
+		rank := make(map[int]int, len(opts.Order))
+		for i, id := range opts.Order {
+			rank[id] = i
+		}
+		for id := range rank {
+			found := false
+			for _, d := range debts {
+				found = found || d.account.ID == id
+			}
+			if !found {
+				return ErrUnknownAccount
+			}
+		}
+
+		sort.SliceStable(debts, func(i, j int) bool {
+			a, b := debts[i], debts[j]
+			rankA, rankedA := rank[a.account.ID]
+			rankB, rankedB := rank[b.account.ID]
+			if rankedA && rankedB {
+				return rankA < rankB
+			} else if rankedA != rankedB {
+				return rankedA
+			}
+			return avalanche(a, b)
+		})
+
+	default:
+		return ErrUnknownStrategy
+	}
+
+	return nil
+}
+
+// openDebts returns the debts that still have a balance, in payoff order
+func openDebts(debts []*debt) []*debt {
+	open := make([]*debt, 0, len(debts))
+	for _, d := range debts {
+		if !d.result.PaidOff {
+			open = append(open, d)
+		}
+	}
+
+	return open
+}
+
+// payMonth charges a month of interest on the open debts and pays budget towards
+// them, reporting whether the total owed went down
+func payMonth(open []*debt, budget models.Money, label string) (Month, bool, error) {
+	zero := models.NewMoney(0, budget.Currency)
+	owedBefore, owedAfter := zero, zero
+	interest := make([]models.Money, len(open))
+	payments := make([]models.Money, len(open))
+
+	for i, d := range open {
+		owedBefore = owedBefore.Add(d.balance)
+		var err error
+		interest[i], err = d.balance.Mul(d.account.APR.Monthly())
+		if err != nil {
+			return Month{}, false, err
+		}
+		d.balance = d.balance.Add(interest[i])
+		payments[i] = zero
+	}
+
+	// Minimum payments first, then whatever is left in payoff order
+	remaining := budget
+	for i, d := range open {
+		pay := least(d.account.MinimumPayment, d.balance, remaining)
+		payments[i] = payments[i].Add(pay)
+		remaining = remaining.Sub(pay)
+	}
+	for i, d := range open {
+		pay := least(d.balance.Sub(payments[i]), remaining)
+		payments[i] = payments[i].Add(pay)
+		remaining = remaining.Sub(pay)
+	}
+
+	row := Month{Month: label, Payments: make([]Payment, len(open))}
+	for i, d := range open {
+		d.balance = d.balance.Sub(payments[i])
+		owedAfter = owedAfter.Add(d.balance)
+
+		d.result.Months++
+		d.result.InterestPaid = d.result.InterestPaid.Add(interest[i])
+		d.result.TotalPaid = d.result.TotalPaid.Add(payments[i])
+		if d.balance.IsZero() {
+			d.result.PaidOff = true
+			d.result.PayoffDate = label
+		}
+
+		row.Payments[i] = Payment{
+			AccountID: d.account.ID,
+			Payment:   payments[i],
+			Interest:  interest[i],
+			Principal: payments[i].Sub(interest[i]),
+			Balance:   d.balance,
+		}
+	}
+
+	return row, owedAfter.Cmp(owedBefore) < 0, nil
+}
+
+// least returns the smallest of amounts, never going below zero
+func least(amounts ...models.Money) models.Money {
+	min := amounts[0]
+	for _, amount := range amounts[1:] {
+		if amount.Cmp(min) < 0 {
+			min = amount
+		}
+	}
+
+	if min.IsNegative() {
+		return models.NewMoney(0, min.Currency)
+	}
+
+	return min
+}
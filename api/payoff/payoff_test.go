@@ -0,0 +1,209 @@
+package payoff_test
+
+import (
+	"dinero/api/models"
+	"dinero/api/payoff"
+	"testing"
+	"time"
+)
+
+// debts are a high interest card with a large balance and a cheaper loan with a small one
+func debts() []*models.Account {
+	return []*models.Account{
+		{ID: 1, Name: "Card", MinimumPayment: models.USD(2500), CurrentPayment: models.USD(2500), FullAmount: models.USD(100000), APR: 2400},
+		{ID: 2, Name: "Loan", MinimumPayment: models.USD(5000), CurrentPayment: models.USD(5000), FullAmount: models.USD(50000), APR: 600},
+		// Nothing is owed on rent, so it isn't a debt
+		{ID: 3, Name: "Rent", MinimumPayment: models.USD(90000), CurrentPayment: models.USD(90000), FullAmount: models.USD(0)},
+	}
+}
+
+var start = time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      payoff.Options
+		firstPaid int
+	}{
+		{"AVALANCHE", payoff.Options{Strategy: payoff.Avalanche, Extra: models.USD(10000), Start: start}, 1},
+		{"SNOWBALL", payoff.Options{Strategy: payoff.Snowball, Extra: models.USD(10000), Start: start}, 2},
+		{"CUSTOM", payoff.Options{Strategy: payoff.Custom, Order: []int{2}, Extra: models.USD(10000), Start: start}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := payoff.Simulate(debts(), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !result.PaidOff || result.MonthlyBudget != models.USD(17500) || len(result.Accounts) != 2 {
+				t.Fatalf("\nResult:\n\tGot: \t\t%v %s %d\n\tExpected: \t%v %s %d\n", result.PaidOff, result.MonthlyBudget, len(result.Accounts), true, "175.00", 2)
+			}
+
+			first := result.Accounts[0]
+			if result.Accounts[1].PayoffDate < first.PayoffDate {
+				first = result.Accounts[1]
+			}
+			if first.AccountID != test.firstPaid {
+				t.Errorf("\nFirst paid off:\n\tGot: \t\t%d\n\tExpected: \t%d\n", first.AccountID, test.firstPaid)
+			}
+
+			// Everything owed plus the interest is paid, and nothing more
+			if result.TotalPaid != models.USD(150000).Add(result.TotalInterest) {
+				t.Errorf("\nTotal paid:\n\tGot: \t\t%s\n\tExpected: \t%s\n", result.TotalPaid, models.USD(150000).Add(result.TotalInterest))
+			}
+
+			if result.PayoffDate != result.Schedule[len(result.Schedule)-1].Month || len(result.Schedule) != result.Months {
+				t.Errorf("\nPayoff date:\n\tGot: \t\t%s after %d months\n\tExpected: \t%s after %d months\n", result.PayoffDate, result.Months, result.Schedule[len(result.Schedule)-1].Month, len(result.Schedule))
+			}
+		})
+	}
+}
+
+func TestSimulateFirstMonth(t *testing.T) {
+	t.Parallel()
+
+	result, err := payoff.Simulate(debts(), payoff.Options{Extra: models.USD(10000), Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The card charges 2% a month and gets everything over the loan's minimum payment
+	expected := []payoff.Payment{
+		{AccountID: 1, Payment: models.USD(12500), Interest: models.USD(2000), Principal: models.USD(10500), Balance: models.USD(89500)},
+		{AccountID: 2, Payment: models.USD(5000), Interest: models.USD(250), Principal: models.USD(4750), Balance: models.USD(45250)},
+	}
+
+	month := result.Schedule[0]
+	if month.Month != "2020-01" || len(month.Payments) != len(expected) {
+		t.Fatalf("\nMonth:\n\tGot: \t\t%v\n\tExpected: \t%v\n", month, expected)
+	}
+	for i := range expected {
+		if month.Payments[i] != expected[i] {
+			t.Errorf("\nPayment:\n\tGot: \t\t%v\n\tExpected: \t%v\n", month.Payments[i], expected[i])
+		}
+	}
+
+	if result.Strategy != payoff.Avalanche {
+		t.Errorf("\nStrategy:\n\tGot: \t\t%s\n\tExpected: \t%s\n", result.Strategy, payoff.Avalanche)
+	}
+}
+
+// TestSimulateExactInterest checks interest is worked out exactly and rounded
+// half to even, where a float monthly rate lands just over the half cent
+func TestSimulateExactInterest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		balance  int64
+		expected int64
+	}{
+		// 1% of 6.00 over 12 months is 0.5 cents
+		{"HALF_DOWN_TO_EVEN", 600, 0},
+		{"HALF_UP_TO_EVEN", 1800, 2},
+		{"HALF_DOWN_TO_EVEN_LARGER", 3000, 2},
+		{"NOT_A_TIE", 3100, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts := []*models.Account{{ID: 1, MinimumPayment: models.USD(100), CurrentPayment: models.USD(100), FullAmount: models.USD(test.balance), APR: 100}}
+			result, err := payoff.Simulate(accounts, payoff.Options{Start: start})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := result.Schedule[0].Payments[0].Interest; got != models.USD(test.expected) {
+				t.Errorf("\nInterest:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, models.USD(test.expected))
+			}
+		})
+	}
+}
+
+func TestSimulateAvalancheSavesInterest(t *testing.T) {
+	t.Parallel()
+
+	avalanche, _ := payoff.Simulate(debts(), payoff.Options{Strategy: payoff.Avalanche, Extra: models.USD(10000), Start: start})
+	snowball, _ := payoff.Simulate(debts(), payoff.Options{Strategy: payoff.Snowball, Extra: models.USD(10000), Start: start})
+
+	if avalanche.TotalInterest.Cmp(snowball.TotalInterest) >= 0 {
+		t.Errorf("\nInterest:\n\tAvalanche: \t%s\n\tSnowball: \t%s\n", avalanche.TotalInterest, snowball.TotalInterest)
+	}
+}
+
+func TestSimulateWithoutInterest(t *testing.T) {
+	t.Parallel()
+
+	accounts := []*models.Account{{ID: 1, MinimumPayment: models.USD(10000), CurrentPayment: models.USD(10000), FullAmount: models.USD(25000)}}
+	result, err := payoff.Simulate(accounts, payoff.Options{Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.PayoffDate != "2020-03" || result.Months != 3 || !result.TotalInterest.IsZero() || result.Schedule[2].Payments[0].Payment != models.USD(5000) {
+		t.Errorf("\nResult:\n\tGot: \t\t%s %d %s\n\tExpected: \t%s %d %s\n", result.PayoffDate, result.Months, result.TotalInterest, "2020-03", 3, "0.00")
+	}
+}
+
+func TestSimulateNeverPaidOff(t *testing.T) {
+	t.Parallel()
+
+	// 2% of 1000.00 is more than the 10.00 paid each month
+	accounts := []*models.Account{{ID: 1, MinimumPayment: models.USD(1000), CurrentPayment: models.USD(1000), FullAmount: models.USD(100000), APR: 2400}}
+	result, err := payoff.Simulate(accounts, payoff.Options{Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.PaidOff || result.PayoffDate != "" || result.Months != 1 || result.Accounts[0].PaidOff {
+		t.Errorf("\nResult:\n\tGot: \t\t%v %q %d\n\tExpected: \t%v %q %d\n", result.PaidOff, result.PayoffDate, result.Months, false, "", 1)
+	}
+}
+
+func TestSimulateErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     payoff.Options
+		expected error
+	}{
+		{"UNKNOWN_STRATEGY", payoff.Options{Strategy: "lottery"}, payoff.ErrUnknownStrategy},
+		{"UNKNOWN_ACCOUNT", payoff.Options{Strategy: payoff.Custom, Order: []int{3}}, payoff.ErrUnknownAccount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := payoff.Simulate(debts(), test.opts); err != test.expected {
+				t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, test.expected)
+			}
+		})
+	}
+}
+
+// TestSimulateCurrencies checks the extra payment is taken in the debts' currency,
+// and debts in different currencies aren't paid off together
+func TestSimulateCurrencies(t *testing.T) {
+	t.Parallel()
+
+	accounts := debts()
+	for _, account := range accounts {
+		account.SetCurrency("EUR")
+	}
+	result, err := payoff.Simulate(accounts, payoff.Options{Extra: models.USD(10000), Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MonthlyBudget != models.NewMoney(17500, "EUR") {
+		t.Errorf("\nMonthly budget:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", result.MonthlyBudget, models.NewMoney(17500, "EUR"))
+	}
+
+	accounts[1].SetCurrency("GBP")
+	if _, err := payoff.Simulate(accounts, payoff.Options{Start: start}); err != payoff.ErrMixedCurrencies {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, payoff.ErrMixedCurrencies)
+	}
+}
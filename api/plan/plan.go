@@ -0,0 +1,169 @@
+// Package plan lines a user's bills up against the biweekly paychecks that have
+// to cover them.
+package plan
+
+import (
+	"dinero/api/models"
+	"dinero/api/schedule"
+	"errors"
+	"sort"
+	"time"
+)
+
+// payPeriod is the number of days between paydays
+const payPeriod = 14
+
+var (
+	// ErrNoPayday is returned for users without a Payday to count paychecks from
+	ErrNoPayday = errors.New("plan: user has no payday")
+	// ErrMixedCurrencies is returned when accounts aren't in the user's currency,
+	// so their bills can't be paid out of the user's paychecks
+	ErrMixedCurrencies = errors.New("plan: accounts aren't in the user's currency")
+)
+
+// Bill is one payment of an account
+type Bill struct {
+	AccountID int          `json:"accountID"`
+	Name      string       `json:"name"`
+	Date      string       `json:"date"`
+	Amount    models.Money `json:"amount"`
+}
+
+// Paycheck is one payday along with the bills due before the next one
+type Paycheck struct {
+	Date     string       `json:"date"`
+	Income   models.Money `json:"income"`
+	Bills    []Bill       `json:"bills"`
+	Total    models.Money `json:"total"`
+	Leftover models.Money `json:"leftover"`
+	// Negative is set when the bills add up to more than the paycheck
+	Negative bool `json:"negative"`
+}
+
+// Unscheduled is an account whose due dates couldn't be worked out
+type Unscheduled struct {
+	AccountID int    `json:"accountID"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+// Plan is a run of consecutive paychecks and the bills each one covers
+type Plan struct {
+	Paychecks   []Paycheck    `json:"paychecks"`
+	Unscheduled []Unscheduled `json:"unscheduled"`
+	Income      models.Money  `json:"income"`
+	Total       models.Money  `json:"total"`
+	Leftover    models.Money  `json:"leftover"`
+	// Shortfalls counts the paychecks that go negative
+	Shortfalls int `json:"shortfalls"`
+}
+
+// Paydays returns count consecutive paydays of a user, starting with the one on or
+// before from, along with the day the pay period after the last one starts.
+// Paydays every 14 days from the user's Payday, and ones that fall on a weekend or
+// holiday are paid the business day before.
+func Paydays(user *models.User, from time.Time, count int, holidays schedule.Calendar) ([]time.Time, time.Time, error) {
+	if user.Payday == "" {
+		return nil, time.Time{}, ErrNoPayday
+	}
+
+	// Paydays are scheduled like a biweekly bill anchored on the user's payday
+	payAccount := &models.Account{AccountType: "biweekly", DueDate: "1", AnchorDate: user.Payday}
+	opts := schedule.Options{Adjustment: schedule.PreviousBusinessDay, Holidays: holidays}
+
+	from = schedule.Day(from)
+	occurrences, err := schedule.Between(payAccount, from.AddDate(0, 0, -2*payPeriod), from.AddDate(0, 0, (count+2)*payPeriod), opts)
+	if err == schedule.ErrBadAccount {
+		return nil, time.Time{}, ErrNoPayday
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	first := 0
+	for i, o := range occurrences {
+		if o.Date.After(from) {
+			break
+		}
+		first = i
+	}
+
+	paydays := make([]time.Time, count)
+	for i := range paydays {
+		paydays[i] = occurrences[first+i].Date
+	}
+
+	return paydays, occurrences[first+count].Date, nil
+}
+
+// Build plans count paychecks of a user starting with the one on or before from.
+// Each bill due from the first payday until the pay period after the last is
+// assigned to the latest paycheck paid on or before the day it's due. The accounts
+// have to be in the user's currency.
+func Build(user *models.User, accounts []*models.Account, from time.Time, count int, opts schedule.Options) (*Plan, error) {
+	for _, account := range accounts {
+		if !account.CurrentPayment.SameCurrency(user.BiweeklyIncome) {
+			return nil, ErrMixedCurrencies
+		}
+	}
+
+	paydays, next, err := Paydays(user, from, count, opts.Holidays)
+	if err != nil {
+		return nil, err
+	}
+
+	zero := models.NewMoney(0, user.BiweeklyIncome.Currency)
+	plan := &Plan{
+		Paychecks:   make([]Paycheck, len(paydays)),
+		Unscheduled: make([]Unscheduled, 0),
+		Income:      zero,
+		Total:       zero,
+		Leftover:    zero,
+	}
+	for i, payday := range paydays {
+		plan.Paychecks[i] = Paycheck{Date: payday.Format(schedule.DateFormat), Income: user.BiweeklyIncome, Bills: make([]Bill, 0), Total: zero}
+	}
+
+	for _, account := range accounts {
+		occurrences, err := schedule.Between(account, paydays[0], next.AddDate(0, 0, -1), opts)
+		if err != nil {
+			plan.Unscheduled = append(plan.Unscheduled, Unscheduled{AccountID: account.ID, Name: account.Name, Reason: unscheduledReason(err)})
+			continue
+		}
+
+		for _, o := range occurrences {
+			i := sort.Search(len(paydays), func(i int) bool { return paydays[i].After(o.Date) }) - 1
+			paycheck := &plan.Paychecks[i]
+			paycheck.Bills = append(paycheck.Bills, Bill{AccountID: account.ID, Name: account.Name, Date: o.Date.Format(schedule.DateFormat), Amount: o.Amount})
+			paycheck.Total = paycheck.Total.Add(o.Amount)
+		}
+	}
+
+	for i := range plan.Paychecks {
+		paycheck := &plan.Paychecks[i]
+		sort.SliceStable(paycheck.Bills, func(a, b int) bool { return paycheck.Bills[a].Date < paycheck.Bills[b].Date })
+
+		paycheck.Leftover = paycheck.Income.Sub(paycheck.Total)
+		paycheck.Negative = paycheck.Leftover.IsNegative()
+		if paycheck.Negative {
+			plan.Shortfalls++
+		}
+
+		plan.Income = plan.Income.Add(paycheck.Income)
+		plan.Total = plan.Total.Add(paycheck.Total)
+	}
+	plan.Leftover = plan.Income.Sub(plan.Total)
+
+	return plan, nil
+}
+
+// unscheduledReason explains why schedule.Between couldn't schedule an account
+func unscheduledReason(err error) string {
+	switch err {
+	case schedule.ErrNoAnchor:
+		return "anchorDate is required to schedule weekly, biweekly and yearly accounts"
+	case schedule.ErrBadAccount:
+		return "accountType or dueDate is invalid"
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,108 @@
+package plan_test
+
+import (
+	"dinero/api/models"
+	"dinero/api/plan"
+	"dinero/api/schedule"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, _ := schedule.ParseDate(s)
+	return d
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	user := &models.User{ID: 1, BiweeklyIncome: models.USD(140000), Payday: "2020-01-03"}
+	accounts := []*models.Account{
+		{ID: 1, Name: "Phone Payment", AccountType: "monthly", CurrentPayment: models.USD(10000), DueDate: "10"},
+		// January 20th is Martin Luther King, Jr. Day, so rent is due on the 21st
+		{ID: 2, Name: "Rent", AccountType: "monthly", CurrentPayment: models.USD(150000), DueDate: "20"},
+		{ID: 3, Name: "Gym", AccountType: "weekly", CurrentPayment: models.USD(2500), DueDate: "1"},
+		// Groceries fall on Saturdays, and are paid the Monday after
+		{ID: 4, Name: "Groceries", AccountType: "weekly", CurrentPayment: models.USD(15000), DueDate: "1", AnchorDate: "2020-01-04"},
+	}
+
+	p, err := plan.Build(user, accounts, date("2020-01-10"), 2, schedule.Options{Holidays: schedule.USFederal})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paychecks := make([]string, len(p.Paychecks))
+	for i, paycheck := range p.Paychecks {
+		bills := make([]string, len(paycheck.Bills))
+		for j, bill := range paycheck.Bills {
+			bills[j] = fmt.Sprintf("%s %s %s", bill.Date, bill.Name, bill.Amount)
+		}
+		paychecks[i] = fmt.Sprintf("%s %s [%s] %s %s %v", paycheck.Date, paycheck.Income, strings.Join(bills, ", "), paycheck.Total, paycheck.Leftover, paycheck.Negative)
+	}
+
+	expected := []string{
+		"2020-01-03 1400.00 [2020-01-06 Groceries 150.00, 2020-01-10 Phone Payment 100.00, 2020-01-13 Groceries 150.00] 400.00 1000.00 false",
+		"2020-01-17 1400.00 [2020-01-21 Rent 1500.00, 2020-01-21 Groceries 150.00, 2020-01-27 Groceries 150.00] 1800.00 -400.00 true",
+	}
+	if got := strings.Join(paychecks, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("\nPaychecks:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, strings.Join(expected, "\n"))
+	}
+
+	if p.Income != models.USD(280000) || p.Total != models.USD(220000) || p.Leftover != models.USD(60000) || p.Shortfalls != 1 {
+		t.Errorf("\nTotals:\n\tGot: \t\t%s %s %s %d\n\tExpected: \t%s %s %s %d\n", p.Income, p.Total, p.Leftover, p.Shortfalls, "2800.00", "2200.00", "600.00", 1)
+	}
+
+	if len(p.Unscheduled) != 1 || p.Unscheduled[0].AccountID != 3 {
+		t.Errorf("\nUnscheduled:\n\tGot: \t\t%v\n\tExpected: \t%s\n", p.Unscheduled, "account 3")
+	}
+}
+
+// TestBuildMixedCurrencies checks bills in another currency than the user's
+// income aren't planned against it
+func TestBuildMixedCurrencies(t *testing.T) {
+	t.Parallel()
+
+	user := &models.User{ID: 1, BiweeklyIncome: models.USD(140000), Payday: "2020-01-03"}
+	accounts := []*models.Account{{ID: 1, Name: "Rent", AccountType: "monthly", CurrentPayment: models.NewMoney(90000, "EUR"), DueDate: "1"}}
+
+	if _, err := plan.Build(user, accounts, date("2020-01-10"), 2, schedule.Options{}); err != plan.ErrMixedCurrencies {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, plan.ErrMixedCurrencies)
+	}
+}
+
+func TestPaydays(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		payday   string
+		from     string
+		expected string
+	}{
+		{"ON_PAYDAY", "2020-01-03", "2020-01-17", "2020-01-17,2020-01-31,2020-02-14"},
+		{"BETWEEN_PAYDAYS", "2020-01-03", "2020-01-16", "2020-01-03,2020-01-17,2020-01-31"},
+		{"FUTURE_ANCHOR", "2020-06-12", "2020-01-16", "2020-01-10,2020-01-24,2020-02-07"},
+		// Christmas Day 2020 is a Friday, so that paycheck comes a day early
+		{"HOLIDAY", "2020-12-11", "2020-12-20", "2020-12-11,2020-12-24,2021-01-08"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paydays, next, err := plan.Paydays(&models.User{Payday: test.payday}, date(test.from), 2, schedule.USFederal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := paydays[0].Format(schedule.DateFormat) + "," + paydays[1].Format(schedule.DateFormat) + "," + next.Format(schedule.DateFormat)
+			if got != test.expected {
+				t.Errorf("\nPaydays:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+
+	if _, _, err := plan.Paydays(&models.User{}, date("2020-01-01"), 2, nil); err != plan.ErrNoPayday {
+		t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, plan.ErrNoPayday)
+	}
+}
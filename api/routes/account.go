@@ -10,21 +10,37 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi"
-	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
 // ContextAccount is a wrapper for the string type to prevent reuse of context
 // types from 3rd party libraries
 type ContextAccount string
 
-// AccountCtx provides a context for all account routes to have access to the account ID
+// AccountCtx provides a context for all account routes to have access to the account ID.
+// Accounts that belong to someone other than the authenticated user are not found.
 func AccountCtx(env *config.Env) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			accountParam := chi.URLParam(r, "accountID")
 			accountID, err := strconv.Atoi(accountParam)
 			if err != nil {
-				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				respondError(w, r, http.StatusBadRequest)
+				return
+			}
+
+			caller, ok := authUser(r)
+			if !ok {
+				respondError(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			// A missing account is left to the handlers, since PUT creates it
+			account, err := env.DB.GetAccount(r.Context(), accountID)
+			if err == nil && account.UserID != caller.ID {
+				respondError(w, r, http.StatusNotFound)
+				return
+			} else if err != nil && err != models.ErrNotFound {
+				respondError(w, r, http.StatusInternalServerError)
 				return
 			}
 
@@ -34,17 +50,47 @@ func AccountCtx(env *config.Env) func(http.Handler) http.Handler {
 	}
 }
 
-// AllAccounts gets all Account records within the accounts table in the database
+// AllAccounts gets a page of the Account records that belong to the caller, filtered
+// and sorted by the query parameters
 func AllAccounts(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		accounts, err := env.DB.AllAccounts()
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		opts, err := listOptions(q)
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+		filter, err := accountFilter(q)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondBadQuery(w, r, err)
 			return
 		}
 
+		// Callers only ever see their own accounts, so filtering on anyone else finds nothing
+		accounts, total := make([]*models.Account, 0), 0
+		if filter.UserID == 0 || filter.UserID == caller.ID {
+			filter.UserID = caller.ID
+
+			accounts, total, err = env.DB.ListAccounts(r.Context(), filter, opts)
+			if _, invalid := err.(*models.ValidationError); invalid {
+				respondBadQuery(w, r, err)
+				return
+			} else if err != nil {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+		}
+
 		accountsJSON, _ := json.Marshal(accounts)
 
+		setPageHeaders(w, r, opts, total)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(accountsJSON)
 	}
@@ -56,16 +102,20 @@ func GetAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		ctx := r.Context()
 		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
 			return
 		}
 
-		account, err := env.DB.GetAccount(accountID)
+		account, err := env.DB.GetAccount(r.Context(), accountID)
 		if err == models.ErrNotFound {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			respondError(w, r, http.StatusNotFound)
 			return
 		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if notModified(w, r, account.Version) {
 			return
 		}
 
@@ -81,10 +131,16 @@ func GetAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 // CreateAccount creates an account record in the database and returns that created record
 func CreateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
 		// Read POST request body
 		newAccount, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
@@ -93,26 +149,25 @@ func CreateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		var account models.Account
 		err = json.Unmarshal(newAccount, &account)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest)
 			return
 		}
 
+		// Accounts are always created for the caller
+		account.UserID = caller.ID
+
 		// Validate Account fields
-		valid := account.Validate()
-		if !valid {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		err = account.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
 			return
 		}
 
 		// Create User in database
-		createdAccount, err := env.DB.CreateAccount(account)
-		if sqliteErr, ok := err.(sqlite3.Error); ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-				return
-			}
-		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		createdAccount, err := env.DB.CreateAccount(r.Context(), account)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
 			return
 		}
 
@@ -131,14 +186,19 @@ func UpdateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		ctx := r.Context()
 		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
 			return
 		}
 
 		// Read PUT request body
 		editedAccount, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
@@ -147,28 +207,41 @@ func UpdateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		var newAccount models.Account
 		err = json.Unmarshal(editedAccount, &newAccount)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest)
 			return
 		}
 
+		// Accounts can't be handed to another user
+		newAccount.UserID = caller.ID
+
 		// Validate Account fields
-		valid := newAccount.Validate()
-		if !valid {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		err = newAccount.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
 			return
 		}
 
 		// Check if Account is already in database and if not, create it
-		_, err = env.DB.GetAccount(accountID)
-		if err == models.ErrNotFound {
-			_, err := env.DB.CreateAccount(newAccount)
-			if sqliteErr, ok := err.(sqlite3.Error); ok {
-				if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-					http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-					return
-				}
-			} else if err != nil {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		existing, err := env.DB.GetAccount(r.Context(), accountID)
+		if err != nil && err != models.ErrNotFound {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		current := 0
+		if existing != nil {
+			current = existing.Version
+		}
+		newAccount.Version, ok = checkIfMatch(w, r, current)
+		if !ok {
+			return
+		}
+
+		if existing == nil {
+			_, err := env.DB.CreateAccount(r.Context(), newAccount)
+			if err != nil {
+				status := dbErrorStatus(err)
+				respondError(w, r, status)
 				return
 			}
 
@@ -179,17 +252,15 @@ func UpdateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		}
 
 		// Update user in database
-		err = env.DB.UpdateAccount(accountID, &newAccount)
-		if sqliteErr, ok := err.(sqlite3.Error); ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-				return
-			}
-		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		err = env.DB.UpdateAccount(r.Context(), accountID, &newAccount)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
 			return
 		}
 
+		w.Header().Set("ETag", etag(existing.Version+1))
+
 		// Send a Status No Content response
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusNoContent)
@@ -197,22 +268,115 @@ func UpdateAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
-// DeleteAccount deletes an account record in the database
-func DeleteAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+// PatchAccount applies a JSON Merge Patch or JSON Patch to an account record in the database,
+// writing only the fields that changed, and returns the patched record
+func PatchAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
 			return
 		}
 
-		err := env.DB.DeleteAccount(accountID)
+		account, err := env.DB.GetAccount(r.Context(), accountID)
 		if err == models.ErrNotFound {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			respondError(w, r, http.StatusNotFound)
 			return
 		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		version, ok := checkIfMatch(w, r, account.Version)
+		if !ok {
+			return
+		}
+
+		var patched models.Account
+		err = readPatch(r, account, &patched)
+		if err != nil {
+			respondPatchError(w, r, err)
+			return
+		}
+
+		// The ID and owner of an account are fixed
+		readOnly := make([]string, 0)
+		if patched.ID != account.ID {
+			readOnly = append(readOnly, "ID")
+		}
+		if patched.UserID != account.UserID {
+			readOnly = append(readOnly, "userID")
+		}
+		if err = readOnlyError(readOnly...); err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		// Validate the patched Account fields
+		err = patched.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		changes := account.Changes(&patched)
+		patched.Version = version
+		err = env.DB.PatchAccount(r.Context(), accountID, &patched, changes)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		// Patches that change nothing don't write, so the version stays the same
+		if len(changes) > 0 {
+			w.Header().Set("ETag", etag(account.Version+1))
+		} else {
+			w.Header().Set("ETag", etag(account.Version))
+		}
+
+		patchedJSON, _ := json.Marshal(patched)
+
+		// Send the patched account JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(patchedJSON)
+		return
+	}
+}
+
+// DeleteAccount deletes an account record in the database
+func DeleteAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Deletes are only checked against the version when asked to be. The
+		// store checks it again as it deletes, in case of a write in between.
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			current := 0
+			account, err := env.DB.GetAccount(r.Context(), accountID)
+			if err == nil {
+				current = account.Version
+			} else if err != models.ErrNotFound {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			if version, ok = checkIfMatch(w, r, current); !ok {
+				return
+			}
+		}
+
+		err := env.DB.DeleteAccount(r.Context(), accountID, version)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
 			return
 		}
 
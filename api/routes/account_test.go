@@ -2,32 +2,34 @@ package routes_test
 
 import (
 	"bytes"
+	"context"
 	"dinero/api/config"
 	"dinero/api/models"
 	"dinero/api/routes"
 	"errors"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
-func (mdb *MockDB) AllAccounts() ([]*models.Account, error) {
+func (mdb *MockDB) AllAccounts(ctx context.Context) ([]*models.Account, error) {
 	if mdb.dbErr {
 		return nil, errors.New("Database error")
 	}
 
 	accounts := make([]*models.Account, 0)
-	accounts = append(accounts, &models.Account{ID: 1, UserID: 1, Name: "Car Payment", AccountType: "monthly", MinimumPayment: 217.99, CurrentPayment: 217.99, DueDate: "12"})
-	accounts = append(accounts, &models.Account{ID: 2, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: 42.83, CurrentPayment: 100.00, FullAmount: 728.00, DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"})
+	accounts = append(accounts, &models.Account{ID: 1, UserID: 1, Name: "Car Payment", AccountType: "monthly", MinimumPayment: models.USD(21799), CurrentPayment: models.USD(21799), DueDate: "12"})
+	accounts = append(accounts, &models.Account{ID: 2, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), Currency: "USD", DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"})
 
 	return accounts, nil
 }
 
-func (mdb *MockDB) GetAccount(accountID int) (*models.Account, error) {
-	if accountID != 1 {
+func (mdb *MockDB) GetAccount(ctx context.Context, accountID int) (*models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if accountID != 1 && accountID != 2 && accountID != 4 {
 		return nil, models.ErrNotFound
 	}
 
@@ -35,44 +37,64 @@ func (mdb *MockDB) GetAccount(accountID int) (*models.Account, error) {
 		return nil, errors.New("Database error")
 	}
 
-	account := &models.Account{ID: 1, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: 42.83, CurrentPayment: 100.00, FullAmount: 728.00, DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}
+	// Account 4 is weekly, but has no anchor date to schedule it from
+	if accountID == 4 {
+		return &models.Account{ID: 4, UserID: 1, Name: "Gym", AccountType: "weekly", MinimumPayment: models.USD(2500), CurrentPayment: models.USD(2500), FullAmount: models.USD(2500), Currency: "USD", DueDate: "1"}, nil
+	}
+
+	// Account 2 belongs to a different user
+	if accountID == 2 {
+		return &models.Account{ID: 2, UserID: 2, Name: "Rent", AccountType: "monthly", MinimumPayment: models.USD(90000), CurrentPayment: models.USD(90000), FullAmount: models.USD(90000), Currency: "USD", DueDate: "1"}, nil
+	}
+
+	account := &models.Account{ID: 1, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), Currency: "USD", DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action", Version: 3}
 
 	return account, nil
 }
 
-func (mdb *MockDB) CreateAccount(a models.Account) (*models.Account, error) {
+func (mdb *MockDB) CreateAccount(ctx context.Context, a models.Account) (*models.Account, error) {
 	if mdb.dbErr {
 		return nil, errors.New("Database error")
 	}
 
 	if a.Name == "Already here" {
-		return nil, sqlite3.Error{
-			Code:         sqlite3.ErrConstraint,
-			ExtendedCode: sqlite3.ErrConstraintUnique,
-		}
+		return nil, models.ErrConflict
+	}
+
+	if a.Name == "Orphan" {
+		return nil, models.ErrForeignKey
 	}
 
-	account := &models.Account{ID: 1, UserID: 1, Name: "Car Payment", AccountType: "monthly", MinimumPayment: 217.99, CurrentPayment: 217.99, FullAmount: 21000, DueDate: "10", URL: "ford.com"}
+	account := &models.Account{ID: 1, UserID: 1, Name: "Car Payment", AccountType: "monthly", MinimumPayment: models.USD(21799), CurrentPayment: models.USD(21799), FullAmount: models.USD(2100000), Currency: "USD", DueDate: "10", URL: "ford.com"}
 
 	return account, nil
 }
 
-func (mdb *MockDB) UpdateAccount(accountID int, a *models.Account) error {
+func (mdb *MockDB) UpdateAccount(ctx context.Context, accountID int, a *models.Account) error {
 	if mdb.dbErr {
 		return errors.New("Database error")
 	}
 
 	if a.Name == "Already here" {
-		return sqlite3.Error{
-			Code:         sqlite3.ErrConstraint,
-			ExtendedCode: sqlite3.ErrConstraintUnique,
-		}
+		return models.ErrConflict
+	}
+
+	return nil
+}
+
+func (mdb *MockDB) PatchAccount(ctx context.Context, accountID int, a *models.Account, fields []string) error {
+	if mdb.dbErr {
+		return errors.New("Database error")
+	}
+
+	if a.Name == "Already here" {
+		return models.ErrConflict
 	}
 
 	return nil
 }
 
-func (mdb *MockDB) DeleteAccount(accountID int) error {
+func (mdb *MockDB) DeleteAccount(ctx context.Context, accountID int, version int) error {
 	if accountID != 1 {
 		return models.ErrNotFound
 	}
@@ -84,6 +106,46 @@ func (mdb *MockDB) DeleteAccount(accountID int) error {
 	return nil
 }
 
+// ListAccounts filters, sorts by name and pages a fixed set of accounts for user 1
+func (mdb *MockDB) ListAccounts(ctx context.Context, filter models.AccountFilter, opts models.ListOptions) ([]*models.Account, int, error) {
+	if mdb.dbErr {
+		return nil, 0, errors.New("Database error")
+	}
+
+	for _, field := range opts.Sort {
+		if field.Field != "name" {
+			return nil, 0, &models.ValidationError{Fields: []models.FieldError{{Field: "sort", Rule: "oneOf", Message: "cannot sort by " + field.Field}}}
+		}
+	}
+
+	all := []*models.Account{
+		{ID: 1, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), Currency: "USD", DueDate: "10"},
+		{ID: 3, UserID: 1, Name: "Groceries", AccountType: "weekly", MinimumPayment: models.USD(15000), CurrentPayment: models.USD(15000), FullAmount: models.USD(15000), Currency: "USD", DueDate: "5"},
+	}
+	if len(opts.Sort) > 0 && !opts.Sort[0].Desc {
+		all[0], all[1] = all[1], all[0]
+	}
+
+	accounts := make([]*models.Account, 0)
+	for _, account := range all {
+		if account.UserID != filter.UserID || (filter.AccountType != "" && account.AccountType != filter.AccountType) {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	total := len(accounts)
+	if opts.Offset > total {
+		opts.Offset = total
+	}
+	accounts = accounts[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(accounts) {
+		accounts = accounts[:opts.Limit]
+	}
+
+	return accounts, total, nil
+}
+
 func TestAllAccounts(t *testing.T) {
 	t.Parallel()
 
@@ -95,30 +157,87 @@ func TestAllAccounts(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            must(http.NewRequest("GET", "/accounts", nil)),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `[{"ID":1,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":0,"dueDate":"12","URL":""},{"ID":2,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"dueDate":"10","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}]`,
+			req:            must(http.NewRequest("GET", "/api/v1/accounts", nil)),
+			env:            seededEnv(t),
+			expectedBody:   `[{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"},{"ID":3,"userID":1,"name":"Groceries","accountType":"weekly","minimumPayment":150,"currentPayment":150,"fullAmount":150,"currency":"USD","apr":0,"dueDate":"5","anchorDate":"","URL":""}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_FILTER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?accountType=weekly", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[{"ID":3,"userID":1,"name":"Groceries","accountType":"weekly","minimumPayment":150,"currentPayment":150,"fullAmount":150,"currency":"USD","apr":0,"dueDate":"5","anchorDate":"","URL":""}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_SORT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?sort=name", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[{"ID":3,"userID":1,"name":"Groceries","accountType":"weekly","minimumPayment":150,"currentPayment":150,"fullAmount":150,"currency":"USD","apr":0,"dueDate":"5","anchorDate":"","URL":""},{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_PAGE",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?limit=1&offset=1", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[{"ID":3,"userID":1,"name":"Groceries","accountType":"weekly","minimumPayment":150,"currentPayment":150,"fullAmount":150,"currency":"USD","apr":0,"dueDate":"5","anchorDate":"","URL":""}]`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
+		{
+			// returns nothing because callers can only list their own accounts
+			name:           "OK_OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?userID=2", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because accounts can't be sorted by "color"
+			name:           "BAD_SORT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?sort=-color", nil),
+			env:            seededEnv(t),
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"sort","rule":"oneOf","message":"cannot sort by color"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because limit is over the maximum
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts?limit=1000", nil),
+			env:            seededEnv(t),
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"limit","rule":"range","message":"must be a number from 1 to 200"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/accounts", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/accounts", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            must(http.NewRequest("GET", "/accounts", nil)),
+			req:            must(http.NewRequest("GET", "/api/v1/accounts", nil)),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 	}
@@ -126,9 +245,22 @@ func TestAllAccounts(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
 			r.ServeHTTP(test.rec, test.req)
 
 			RunTest(&test, t)
+
+			if test.name == "OK_PAGE" {
+				if got := test.rec.Header().Get("X-Total-Count"); got != "2" {
+					t.Errorf("\nX-Total-Count:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, "2")
+				}
+
+				link := `</api/v1/accounts?limit=1&offset=0>; rel="first", </api/v1/accounts?limit=1&offset=0>; rel="prev", </api/v1/accounts?limit=1&offset=1>; rel="last"`
+				if got := test.rec.Header().Get("Link"); got != link {
+					t.Errorf("\nLink:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, link)
+				}
+			}
 		})
 	}
 }
@@ -140,9 +272,9 @@ func TestGetAccount(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/accounts/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"dueDate":"10","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
@@ -150,62 +282,94 @@ func TestGetAccount(t *testing.T) {
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/accounts/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/accounts/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
-			// breaks the test because an account with the ID of 3 is not being found
+			// breaks the test because an account with the ID of 9 is not being found
 			name:           "NOT_FOUND",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/accounts/3", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusNotFound)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/9", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusNotFound,
 		},
 		{
 			// breaks the test because "test" is not an integer
 			name:           "BAD_REQUEST",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/accounts/test", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/test", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/accounts/1", nil),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/accounts/1", nil),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			// breaks the test because the request is past its deadline
+			name:           "DEADLINE",
+			rec:            httptest.NewRecorder(),
+			req:            expired(httptest.NewRequest("GET", "/api/v1/accounts/1", nil)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusServiceUnavailable),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "NOT_MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/accounts/1", nil), "If-None-Match", `W/"1"`),
+			env:            seededEnv(t),
+			expectedBody:   "",
+			expectedHeader: "",
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			// the tag isn't the account's version, so it's sent again
+			name:           "MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/accounts/1", nil), "If-None-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.GetAccount(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetAccount(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
@@ -221,70 +385,130 @@ func TestCreateAccount(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `{"ID":1,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`,
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":4,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"ford.com"}`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
+		{
+			// money amounts may also be sent as decimal strings
+			name:           "OK_STRING_AMOUNTS",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":"217.99","currentPayment":"217.99","fullAmount":"21000.00","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":4,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"ford.com"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because "minimumPayment" has a fraction of a cent
+			name:           "BAD_REQUEST_SUB_CENT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.999,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the body is larger than the server accepts
+			name:           "TOO_LARGE",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log, MaxBodyBytes: 64},
+			expectedBody:   errorJSON(http.StatusRequestEntityTooLarge),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
 		{
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/accounts", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/accounts", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the request body is set to produce an error
 			name:           "BAD_REQUEST_IOUTIL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", ErrReader(0)),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", ErrReader(0)),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "accountType" key in the request body is not a string
 			name:           "BAD_REQUEST_UNMARSHAL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":123,minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":123,minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "minimumPayment" key in the request body is not a float64
 			name:           "INVALID",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"bad","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"bad","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "accountType", Rule: "oneOf", Message: "must be one of daily, weekly, biweekly, monthly or yearly"}),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
 		{
-			// breaks the test because the "name" key in the request body ("Already here") is set to cause a conflict
+			// breaks the test because "minimumPayment" is more than "fullAmount" and "dueDate" isn't a day of the month
+			name:           "INVALID_FIELDS",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":0,"fullAmount":100,"currency":"USD","dueDate":"32","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "minimumPayment", Rule: "lteFullAmount", Message: "must not exceed fullAmount"}, models.FieldError{Field: "dueDate", Rule: "range", Message: "must be a day of the month from 1 to 31"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because "currency" isn't an ISO 4217 code
+			name:           "INVALID_CURRENCY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"euros","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "currency", Rule: "pattern", Message: "must be a three letter ISO 4217 currency code"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the "name" key in the request body ("Groceries") is taken by another of the caller's accounts
 			name:           "SQLITE_CONFLICT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Already here","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Groceries","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusConflict,
 		},
+		{
+			// breaks the test because the "Orphan" account is set to violate the user foreign key
+			name:           "SQLITE_FOREIGN_KEY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Orphan","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 	}
@@ -292,6 +516,8 @@ func TestCreateAccount(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
 			r.ServeHTTP(test.rec, test.req)
 
 			RunTest(&test, t)
@@ -306,8 +532,8 @@ func TestUpdateAccount(t *testing.T) {
 		{
 			name:           "OK_NO_CONTENT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
 			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
 			expectedStatus: http.StatusNoContent,
@@ -315,8 +541,8 @@ func TestUpdateAccount(t *testing.T) {
 		{
 			name:           "OK_CREATED",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/3", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/9", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
 			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
 			expectedStatus: http.StatusCreated,
@@ -325,100 +551,132 @@ func TestUpdateAccount(t *testing.T) {
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the request body is set to produce an error
 			name:           "BAD_REQUEST_IOUTIL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", ErrReader(0)),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", ErrReader(0)),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "Name" key in the request body is not a string
 			name:           "BAD_REQUEST_UNMARSHAL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":12345,"accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":12345,"accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "accountType" key in the request body is not a valid account type
 			name:           "INVALID",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"bad","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"bad","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "accountType", Rule: "oneOf", Message: "must be one of daily, weekly, biweekly, monthly or yearly"}),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
 		{
-			// breaks the test because the "name" key in the request body ("Already here") is set to cause a conflict
+			// breaks the test because the "name" key in the request body ("Groceries") is taken by another of the caller's accounts
 			name:           "SQLITE_CONFLICT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Already here","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Groceries","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusConflict,
 		},
 		{
-			// breaks the test because the "name" key in the request body ("Already here") is set to cause a conflict
+			// breaks the test because the "name" key in the request body ("Groceries") is taken by another of the caller's accounts
 			name:           "SQLITE_CONFLICT_CREATED",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/3", bytes.NewBuffer([]byte(`{"userID":1,"name":"Already here","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/9", bytes.NewBuffer([]byte(`{"userID":1,"name":"Groceries","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusConflict,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			name:           "DB_ERR_CREATED",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/3", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/9", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"dueDate":"10","URL":"ford.com"}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", `"1"`),
+			env:            seededEnv(t),
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the account is at version 1, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/accounts/1", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			// breaks the test because If-Match can't match an account that doesn't exist
+			name:           "PRECONDITION_FAILED_MISSING",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/accounts/9", bytes.NewBuffer([]byte(`{"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", "*"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.UpdateAccount(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.UpdateAccount(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
@@ -427,6 +685,180 @@ func TestUpdateAccount(t *testing.T) {
 	}
 }
 
+func TestPatchAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK_MERGE_PATCH",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":150}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":150,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_JSON_PATCH",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `[{"op":"test","path":"/currentPayment","value":100},{"op":"replace","path":"/currentPayment","value":"150.50"}]`, "application/json-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":150.5,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_JSON",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":150}`, "application/json"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":150,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the patch is larger than the server accepts
+			name:           "TOO_LARGE",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":150,"name":"Phone Payment"}`, "application/merge-patch+json"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log, MaxBodyBytes: 16},
+			expectedBody:   errorJSON(http.StatusRequestEntityTooLarge),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// breaks the test because an account with the ID of 9 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/9", `{"currentPayment":150}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the body isn't a patch format
+			name:           "UNSUPPORTED_MEDIA_TYPE",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `currentPayment=150`, "application/x-www-form-urlencoded"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnsupportedMediaType),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			// breaks the test because the patch isn't valid JSON
+			name:           "BAD_REQUEST_MALFORMED",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the patch makes "name" a number
+			name:           "BAD_REQUEST_TYPE",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"name":123}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the current payment isn't 99
+			name:           "TEST_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `[{"op":"test","path":"/currentPayment","value":99},{"op":"replace","path":"/currentPayment","value":150}]`, "application/json-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			// breaks the test because the account has no "balance" to remove
+			name:           "PATH_NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `[{"op":"remove","path":"/balance"}]`, "application/json-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because accounts can't be given to another user
+			name:           "READ_ONLY",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"userID":2}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "userID", Rule: "readOnly", Message: "cannot be changed"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the patched current payment is more than the full amount
+			name:           "INVALID",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":1000}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "currentPayment", Rule: "lteFullAmount", Message: "must not exceed fullAmount"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the "name" key in the patch ("Groceries") is taken by another of the caller's accounts
+			name:           "SQLITE_CONFLICT",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"name":"Groceries"}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/accounts/1", `{"currentPayment":150}`, "application/merge-patch+json"),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(patchRequest("/api/v1/accounts/1", `{"currentPayment":100}`, "application/merge-patch+json"), "If-Match", `"1", "3"`),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because weak tags never match If-Match
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(patchRequest("/api/v1/accounts/1", `{"currentPayment":150}`, "application/merge-patch+json"), "If-Match", `W/"1"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
 func TestDeleteAccount(t *testing.T) {
 	t.Parallel()
 
@@ -434,8 +866,8 @@ func TestDeleteAccount(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/accounts/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil),
+			env:            seededEnv(t),
 			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
 			expectedStatus: http.StatusNoContent,
@@ -443,40 +875,62 @@ func TestDeleteAccount(t *testing.T) {
 		{
 			name:           "NOT_FOUND",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/accounts/3", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusNotFound)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/9", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusNotFound,
 		},
 		{
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/accounts/1", nil),
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/accounts/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil), "If-Match", `"1"`),
+			env:            seededEnv(t),
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the account is at version 1, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil), "If-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.DeleteAccount(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.DeleteAccount(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
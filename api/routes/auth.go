@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionCookie is the cookie browsers carry the session token in
+	sessionCookie = "dinero_session"
+	// sessionTTL is how long a login lasts
+	sessionTTL = 30 * 24 * time.Hour
+)
+
+// ContextAuth is a wrapper for the string type to prevent reuse of context
+// types from 3rd party libraries
+type ContextAuth string
+
+// credentials is the body of a login request
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// sessionToken reads the session token from the Authorization header, falling
+// back to the session cookie
+func sessionToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// authUser returns the authenticated user attached to the request by Authenticate
+func authUser(r *http.Request) (*models.User, bool) {
+	user, ok := r.Context().Value(ContextAuth("user")).(*models.User)
+	return user, ok
+}
+
+// Authenticate is a middleware that rejects requests without a valid session and
+// attaches the session's user to the request context for the handlers after it
+func Authenticate(env *config.Env) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := sessionToken(r)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="dinero"`)
+				respondError(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			user, err := env.DB.SessionUser(r.Context(), token)
+			if err == models.ErrNotFound {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="dinero", error="invalid_token"`)
+				respondError(w, r, http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextAuth("user"), user)
+			ctx = context.WithValue(ctx, ContextAuth("token"), token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Login checks a user's email and password and starts a session, returning its
+// token in the response body and in a cookie
+func Login(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Read POST request body
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		var creds credentials
+		err = json.Unmarshal(body, &creds)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		// Unknown emails and wrong passwords get the same response, after the
+		// same bcrypt work, so the endpoint can't be used to find out who has
+		// an account
+		user, err := env.DB.UserByEmail(r.Context(), creds.Email)
+		if err == models.ErrNotFound {
+			(&models.User{}).CheckPassword(creds.Password)
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if !user.CheckPassword(creds.Password) {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		session, err := env.DB.CreateSession(r.Context(), user.ID, sessionTTL)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    session.Token,
+			Path:     "/",
+			Expires:  session.ExpiresAt,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+
+		sessionJSON, _ := json.Marshal(session)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(sessionJSON)
+	}
+}
+
+// Logout ends the caller's current session
+func Logout(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := r.Context().Value(ContextAuth("token")).(string)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		err := env.DB.DeleteSession(r.Context(), token)
+		if err != nil && err != models.ErrNotFound {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,275 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword is the password of the user MockDB.UserByEmail finds
+const testPassword = "correct horse"
+
+func (mdb *MockDB) UserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email != "lptoth55@gmail.com" {
+		return nil, models.ErrNotFound
+	}
+
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD", PasswordHash: string(hash)}
+
+	return user, nil
+}
+
+func (mdb *MockDB) CreateSession(ctx context.Context, userID int, ttl time.Duration) (*models.Session, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	session := &models.Session{Token: "new-token", UserID: userID, ExpiresAt: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)}
+
+	return session, nil
+}
+
+// SessionUser ignores dbErr so the database errors of the routes behind
+// Authenticate can still be tested
+func (mdb *MockDB) SessionUser(ctx context.Context, token string) (*models.User, error) {
+	switch token {
+	case testToken:
+		return &models.User{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD"}, nil
+	case "user-2-token":
+		return &models.User{ID: 2, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: models.USD(186000), Currency: "USD"}, nil
+	}
+
+	return nil, models.ErrNotFound
+}
+
+func (mdb *MockDB) DeleteSession(ctx context.Context, token string) error {
+	if mdb.dbErr {
+		return errors.New("Database error")
+	}
+
+	if token != testToken {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(`{"email":"lptoth55@gmail.com","password":"correct horse"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"token":"new-token","userID":1,"expiresAt":"2020-01-31T00:00:00Z"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the password is wrong
+			name:           "WRONG_PASSWORD",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(`{"email":"lptoth55@gmail.com","password":"battery staple"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			// breaks the test because nobody has this email
+			name:           "UNKNOWN_EMAIL",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(`{"email":"nobody@gmail.com","password":"correct horse"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			// breaks the test because the request body is set to produce an error
+			name:           "BAD_REQUEST_IOUTIL",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", ErrReader(0)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the "password" key in the request body is not a string
+			name:           "BAD_REQUEST_UNMARSHAL",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(`{"email":"lptoth55@gmail.com","password":123}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(`{"email":"lptoth55@gmail.com","password":"correct horse"}`))),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+
+			if test.name == "OK" {
+				cookie := test.rec.Result().Cookies()
+				if len(cookie) != 1 || cookie[0].Value != "new-token" || !cookie[0].HttpOnly {
+					t.Errorf("\nCookie:\n\tGot: \t\t%v\n\tExpected: \t%s\n", cookie, "new-token")
+				}
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("POST", "/api/v1/auth/logout", nil), testToken),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the request has no session
+			name:           "UNAUTHORIZED",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/auth/logout", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("POST", "/api/v1/auth/logout", nil), testToken),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	cookieReq := httptest.NewRequest("GET", "/api/v1/users/1", nil)
+	cookieReq.AddCookie(&http.Cookie{Name: "dinero_session", Value: testToken})
+
+	tests := []TestCase{
+		{
+			name:           "OK_COOKIE",
+			rec:            httptest.NewRecorder(),
+			req:            cookieReq,
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the request has no session
+			name:           "NO_TOKEN",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			// breaks the test because the token doesn't belong to a session
+			name:           "BAD_TOKEN",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("DELETE", "/api/v1/users/1", nil), "expired-token"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			// breaks the test because user 2 can't see user 1
+			name:           "OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("GET", "/api/v1/users/1", nil), "user-2-token"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because account 1 belongs to user 1
+			name:           "OTHER_USERS_ACCOUNT",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil), "user-2-token"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+
+			if test.name == "NO_TOKEN" && test.rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("\nWWW-Authenticate header missing\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"bytes"
+	"dinero/api/backup"
+	"dinero/api/config"
+	"dinero/api/models"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// maxBackupSize is the largest backup that can be restored
+const maxBackupSize = 50 << 20
+
+// zipMagic starts every zip file
+var zipMagic = []byte("PK\x03\x04")
+
+// Export exports the caller's user, accounts and transactions, as a JSON document
+// or with format=csv as a zip of CSV files. The API has no administrators, so a
+// backup of every user is made against the database with dinero export -all.
+func Export(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			v := new(models.ValidationError)
+			v.Add("format", "oneOf", "must be json or csv")
+			respondBadQuery(w, r, v)
+			return
+		}
+
+		b, err := env.DB.Export(r.Context(), caller.ID)
+		if err != nil {
+			respondError(w, r, dbErrorStatus(err))
+			return
+		}
+
+		if format == "csv" {
+			var archive bytes.Buffer
+			if err = backup.WriteZip(&archive, b); err != nil {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", `attachment; filename="dinero-export.zip"`)
+			w.Write(archive.Bytes())
+			return
+		}
+
+		backupJSON, _ := json.Marshal(b)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="dinero-export.json"`)
+		w.Write(backupJSON)
+	}
+}
+
+// readBackup reads the backup in a request body, which is either the JSON document
+// or the zip Export writes
+func readBackup(r *http.Request) (*models.Backup, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/zip" || bytes.HasPrefix(data, zipMagic) {
+		return backup.ReadZip(data)
+	}
+
+	b := new(models.Backup)
+	if err = json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Import restores a backup made by Export in a single database transaction. Rows
+// that already exist and differ are conflicts, which by default restore nothing
+// and respond 409 listing them; onConflict=skip keeps the existing rows and
+// onConflict=overwrite replaces them. Backups can only hold the caller's own user.
+func Import(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		mode := r.URL.Query().Get("onConflict")
+		switch mode {
+		case "":
+			mode = models.ConflictFail
+		case models.ConflictFail, models.ConflictSkip, models.ConflictOverwrite:
+		default:
+			v := new(models.ValidationError)
+			v.Add("onConflict", "oneOf", "must be one of fail, skip or overwrite")
+			respondBadQuery(w, r, v)
+			return
+		}
+
+		limitBody(w, r, maxBackupSize)
+		b, err := readBackup(r)
+		if err == backup.ErrTooLarge || tooLarge(err) {
+			respondError(w, r, http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if err = b.Validate(); err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		v := new(models.ValidationError)
+		for i, user := range b.Users {
+			if user.ID != caller.ID {
+				v.Add(fmt.Sprintf("users[%d].ID", i), "owner", "must be your own user ID")
+			}
+		}
+		if err = v.Err(); err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		report, err := env.DB.Restore(r.Context(), b, mode)
+		if err == models.ErrRestoreConflict {
+			details := make([]models.FieldError, 0, len(report.Conflicts))
+			for _, conflict := range report.Conflicts {
+				details = append(details, models.FieldError{
+					Field:   conflict.Table + "/" + strconv.Itoa(conflict.ID),
+					Rule:    "conflict",
+					Message: conflict.Reason,
+				})
+			}
+
+			writeError(w, r, http.StatusConflict, errorBody{
+				Code:    "restore_conflict",
+				Message: "The backup conflicts with existing rows",
+				Details: details,
+			})
+			return
+		} else if err != nil {
+			respondError(w, r, dbErrorStatus(err))
+			return
+		}
+
+		reportJSON, _ := json.Marshal(report)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(reportJSON)
+	}
+}
@@ -0,0 +1,223 @@
+package routes_test
+
+import (
+	"bytes"
+	"dinero/api/backup"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mockBackupJSON is mockBackup as JSON, with edit applied to it first
+func mockBackupJSON(edit func(*models.Backup)) string {
+	b := mockBackup()
+	if edit != nil {
+		edit(b)
+	}
+
+	backupJSON, _ := json.Marshal(b)
+	return string(backupJSON)
+}
+
+// mockBackupZip is mockBackup written by backup.WriteZip
+func mockBackupZip() string {
+	var archive bytes.Buffer
+	backup.WriteZip(&archive, mockBackup())
+	return archive.String()
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/export", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"version":1,"exportedAt":"2020-01-01T00:00:00Z","users":[{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}],"accounts":[{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":""}],"transactions":[{"ID":1,"accountID":1,"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_CSV",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/export?format=csv", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   mockBackupZip(),
+			expectedHeader: "application/zip",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because xml isn't an export format
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/export?format=xml", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"format","rule":"oneOf","message":"must be json or csv"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/export", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "NO_AUTH",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/export", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnauthorized),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "NO_AUTH" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.Export(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	renamed := mockBackupJSON(func(b *models.Backup) { b.Accounts[0].Name = "Phone" })
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(mockBackupJSON(nil))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"created":{"users":0,"accounts":1,"transactions":1},"updated":{"users":0,"accounts":0,"transactions":0},"unchanged":{"users":1,"accounts":0,"transactions":0},"conflicts":[]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_ZIP",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(mockBackupZip())), "Content-Type", "application/zip"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"created":{"users":0,"accounts":1,"transactions":1},"updated":{"users":0,"accounts":0,"transactions":0},"unchanged":{"users":1,"accounts":0,"transactions":0},"conflicts":[]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_SKIP",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import?onConflict=skip", strings.NewReader(renamed)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"created":{"users":0,"accounts":0,"transactions":1},"updated":{"users":0,"accounts":0,"transactions":0},"unchanged":{"users":1,"accounts":0,"transactions":0},"conflicts":[{"table":"accounts","ID":1,"reason":"differs in name"}]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because account 1 already exists under another name
+			name:           "CONFLICT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(renamed)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"restore_conflict","message":"The backup conflicts with existing rows","details":[{"field":"accounts/1","rule":"conflict","message":"differs in name"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			// breaks the test because the backup is from a newer version and its transaction has no account
+			name: "INVALID",
+			rec:  httptest.NewRecorder(),
+			req:  httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(mockBackupJSON(func(b *models.Backup) { b.Version = 2; b.Transactions[0].AccountID = 9 }))),
+			env:  &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody: errorJSON(http.StatusUnprocessableEntity,
+				models.FieldError{Field: "version", Rule: "oneOf", Message: "must be 1"},
+				models.FieldError{Field: "transactions[0].accountID", Rule: "exists", Message: "must be the ID of an account in the backup"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the backup holds somebody else's user
+			name:           "NOT_OWNER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(mockBackupJSON(func(b *models.Backup) { b.Users[0].ID = 2; b.Accounts[0].UserID = 2 }))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "users[0].ID", Rule: "owner", Message: "must be your own user ID"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because onConflict isn't a conflict mode
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import?onConflict=maybe", strings.NewReader(mockBackupJSON(nil))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"onConflict","rule":"oneOf","message":"must be one of fail, skip or overwrite"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the body isn't a backup
+			name:           "BAD_BODY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(`{"version":`)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the body is larger than the server accepts
+			name:           "TOO_LARGE",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", Endless(0)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusRequestEntityTooLarge),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/import", strings.NewReader(mockBackupJSON(nil))),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package routes_test
 
 import (
+	"context"
 	"dinero/api/config"
+	"dinero/api/models"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 // MockDB is a dinero/api/models Store implementation,
@@ -33,6 +38,85 @@ func (ErrReader) Read(p []byte) (n int, err error) {
 	return 0, errors.New("ioutil.ReadAll error")
 }
 
+// Endless is a request body that never ends, so it's
+// over any size limit a route puts on it
+type Endless int
+
+func (Endless) Read(p []byte) (n int, err error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
+
+// testToken is the session token MockDB.SessionUser accepts for user 1
+const testToken = "test-token"
+
+// withToken sets the session token a request is made with
+func withToken(req *http.Request, token string) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// authorize logs a request in as user 1, unless the test case already chose a token
+func authorize(req *http.Request) {
+	if req.Header.Get("Authorization") == "" {
+		withToken(req, testToken)
+	}
+}
+
+// testRequestID is the request ID every test request is sent with
+const testRequestID = "test-request"
+
+// prepare tags a request with testRequestID so error bodies are predictable
+func prepare(req *http.Request) {
+	req.Header.Set("X-Request-Id", testRequestID)
+}
+
+// errorJSON is the error envelope the routes respond with for status. Passing
+// details makes it a validation error.
+func errorJSON(status int, details ...models.FieldError) string {
+	body := struct {
+		Code      string              `json:"code"`
+		Message   string              `json:"message"`
+		Details   []models.FieldError `json:"details,omitempty"`
+		RequestID string              `json:"requestID"`
+	}{
+		Code:      strings.Replace(strings.ToLower(http.StatusText(status)), " ", "_", -1),
+		Message:   http.StatusText(status),
+		Details:   details,
+		RequestID: testRequestID,
+	}
+
+	if len(details) > 0 {
+		body.Code = "validation_failed"
+		body.Message = "One or more fields are invalid"
+	}
+
+	bodyJSON, _ := json.Marshal(map[string]interface{}{"error": body})
+	return string(bodyJSON)
+}
+
+// patchRequest builds a PATCH request with a patch body of the given media type
+func patchRequest(target string, body string, contentType string) *http.Request {
+	req := httptest.NewRequest("PATCH", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+// withHeader sets a header on a request
+func withHeader(req *http.Request, key string, value string) *http.Request {
+	req.Header.Set(key, value)
+	return req
+}
+
+// expired gives a request a context that is already past its deadline
+func expired(req *http.Request) *http.Request {
+	ctx, cancel := context.WithDeadline(req.Context(), time.Now().Add(-time.Second))
+	cancel()
+	return req.WithContext(ctx)
+}
+
 // Test runs test cases
 func RunTest(c *TestCase, t *testing.T) {
 	if c.expectedBody != c.rec.Body.String() {
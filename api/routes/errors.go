@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"context"
+	"dinero/api/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// errorEnvelope is the JSON body of every error response
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+// errorBody describes what went wrong. Code is a stable machine-readable name,
+// Message is for humans, and Details lists the fields that failed validation.
+type errorBody struct {
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	Details   []models.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"requestID,omitempty"`
+}
+
+// errorCode turns a status code into a snake case code, e.g. 404 is "not_found"
+func errorCode(status int) string {
+	return strings.Replace(strings.ToLower(http.StatusText(status)), " ", "_", -1)
+}
+
+// writeError writes an error envelope with the given status
+func writeError(w http.ResponseWriter, r *http.Request, status int, body errorBody) {
+	body.RequestID = middleware.GetReqID(r.Context())
+	bodyJSON, _ := json.Marshal(errorEnvelope{Error: body})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(bodyJSON)
+}
+
+// respondError responds with the error envelope for a status code. A server error
+// on a request that ran past its deadline was the datastore giving up, so it's
+// reported as unavailable rather than broken.
+func respondError(w http.ResponseWriter, r *http.Request, status int) {
+	if status == http.StatusInternalServerError && r.Context().Err() == context.DeadlineExceeded {
+		status = http.StatusServiceUnavailable
+	}
+	writeError(w, r, status, errorBody{Code: errorCode(status), Message: http.StatusText(status)})
+}
+
+// respondInvalid responds to a failed Validate call, listing the fields that are wrong
+func respondInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	verr, ok := err.(*models.ValidationError)
+	if !ok {
+		respondError(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	writeError(w, r, http.StatusUnprocessableEntity, errorBody{
+		Code:    "validation_failed",
+		Message: "One or more fields are invalid",
+		Details: verr.Fields,
+	})
+}
+
+// RequestID is a middleware that echoes the request ID chi's middleware.RequestID
+// assigned back to the client, so it can be quoted in bug reports
+func RequestID(next http.Handler) http.Handler {
+	return middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// respondBadQuery responds to query parameters that failed to parse, listing the ones that are wrong
+func respondBadQuery(w http.ResponseWriter, r *http.Request, err error) {
+	verr, ok := err.(*models.ValidationError)
+	if !ok {
+		respondError(w, r, http.StatusBadRequest)
+		return
+	}
+
+	writeError(w, r, http.StatusBadRequest, errorBody{
+		Code:    "invalid_query",
+		Message: "One or more query parameters are invalid",
+		Details: verr.Fields,
+	})
+}
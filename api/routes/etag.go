@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag returns the entity tag of a record at version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches reports whether a comma separated If-Match or If-None-Match header
+// names tag. Weak comparison ignores W/ prefixes, while strong comparison never
+// matches a weak tag.
+func etagMatches(header string, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified sets the ETag header for a record at version and reports whether the
+// request's If-None-Match header already names it, in which case it has responded
+// with 304 Not Modified
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	tag := etag(version)
+	w.Header().Set("ETag", tag)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagMatches(header, tag, true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch checks the request's If-Match header against the current version of
+// the record it writes to, where 0 means the record doesn't exist. It returns the
+// version the write should be made conditional on, which is 0 when there is no
+// If-Match header, or false once it has responded with 412 Precondition Failed.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current int) (int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	if current == 0 || !etagMatches(header, etag(current), false) {
+		respondError(w, r, http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	return current, true
+}
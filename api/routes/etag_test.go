@@ -0,0 +1,83 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		req          *http.Request
+		expectedETag string
+	}{
+		{
+			name:         "GET_ACCOUNT",
+			req:          httptest.NewRequest("GET", "/api/v1/accounts/1", nil),
+			expectedETag: `"3"`,
+		},
+		{
+			name:         "GET_USER",
+			req:          httptest.NewRequest("GET", "/api/v1/users/1", nil),
+			expectedETag: `"3"`,
+		},
+		{
+			name:         "NOT_MODIFIED",
+			req:          withHeader(httptest.NewRequest("GET", "/api/v1/accounts/1", nil), "If-None-Match", `"3"`),
+			expectedETag: `"3"`,
+		},
+		{
+			// the patch changes the account, so it moves on to the next version
+			name:         "PATCH_ACCOUNT",
+			req:          patchRequest("/api/v1/accounts/1", `{"currentPayment":150}`, "application/merge-patch+json"),
+			expectedETag: `"4"`,
+		},
+		{
+			// the patch changes nothing, so nothing is written
+			name:         "PATCH_USER_UNCHANGED",
+			req:          patchRequest("/api/v1/users/1", `{"firstName":"Luke"}`, "application/merge-patch+json"),
+			expectedETag: `"3"`,
+		},
+		{
+			name:         "PUT_USER",
+			req:          withHeader(httptest.NewRequest("PUT", "/api/v1/users/1", strings.NewReader(`{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99,"currency":"USD"}`)), "If-Match", `"3"`),
+			expectedETag: `"4"`,
+		},
+		{
+			name:         "GET_USER_ACCOUNT",
+			req:          httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil),
+			expectedETag: `"3"`,
+		},
+		{
+			name:         "PUT_USER_ACCOUNT",
+			req:          withHeader(httptest.NewRequest("PUT", "/api/v1/users/1/accounts/1", strings.NewReader(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`)), "If-Match", `"3"`),
+			expectedETag: `"4"`,
+		},
+		{
+			// a failed precondition doesn't describe any version
+			name:         "PRECONDITION_FAILED",
+			req:          withHeader(httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil), "If-Match", `"2"`),
+			expectedETag: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r := routes.NewRouter(&config.Env{DB: &MockDB{}, Log: config.Log})
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(rec, test.req)
+
+			if etag := rec.Header().Get("ETag"); etag != test.expectedETag {
+				t.Errorf("\nETag:\n\tGot: \t\t%s\n\tExpected: \t%s\n", etag, test.expectedETag)
+			}
+		})
+	}
+}
@@ -2,13 +2,91 @@ package routes
 
 import (
 	"dinero/api/config"
+	"dinero/api/models"
+	"io"
 	"net/http"
+	"strings"
 )
 
 // MethodNotAllowed is a route handler for catching requests in unallowed methods
 func MethodNotAllowed(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		respondError(w, r, http.StatusMethodNotAllowed)
 		return
 	}
 }
+
+// NotFound is a route handler for catching requests to routes that don't exist
+func NotFound(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, r, http.StatusNotFound)
+		return
+	}
+}
+
+// dbErrorStatus maps an error returned by the datastore to the HTTP status code
+// the client should see. Unique constraint violations are conflicts, while foreign
+// key violations mean the request referenced a record that doesn't exist.
+func dbErrorStatus(err error) int {
+	switch err {
+	case models.ErrNotFound:
+		return http.StatusNotFound
+	case models.ErrHasDependents, models.ErrConflict:
+		return http.StatusConflict
+	case models.ErrVersionConflict:
+		return http.StatusPreconditionFailed
+	case models.ErrForeignKey:
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
+// limitedBody is a request body cut off at a size limit. It keeps the body it
+// wraps, so a handler that accepts larger uploads can swap the limit out.
+type limitedBody struct {
+	io.ReadCloser
+	original io.ReadCloser
+}
+
+// limitBody caps the request body at n bytes, replacing any limit already on it
+func limitBody(w http.ResponseWriter, r *http.Request, n int64) {
+	body := r.Body
+	if limited, ok := body.(*limitedBody); ok {
+		body = limited.original
+	}
+	if body == nil {
+		return
+	}
+
+	r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, body, n), original: body}
+}
+
+// tooLarge reports whether err came from reading a request body past the limit
+// limitBody put on it. Before Go 1.19 http.MaxBytesReader's error has no type of
+// its own, so it's recognised by its message, which form parsing wraps.
+func tooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+// bodyErrorStatus is the status to respond with when reading a request body
+// fails: 413 for a body over the size limit, and 400 for anything else
+func bodyErrorStatus(err error) int {
+	if tooLarge(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// LimitBody is a middleware capping the size of request bodies at n bytes, or
+// leaving them unlimited when n is zero
+func LimitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if n > 0 {
+				limitBody(w, r, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/nowhere", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"not_found","message":"Not Found","requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+
+			if got := test.rec.Header().Get("X-Request-Id"); got != testRequestID {
+				t.Errorf("\nX-Request-Id:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, testRequestID)
+			}
+		})
+	}
+}
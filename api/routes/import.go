@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/importer"
+	"dinero/api/models"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxStatementSize is the largest statement that can be imported
+const maxStatementSize = 10 << 20
+
+// importResult is the response to an import. Committed is false for previews,
+// where nothing was created.
+type importResult struct {
+	Format    importer.Format `json:"format"`
+	Committed bool            `json:"committed"`
+	*importer.Preview
+}
+
+// importQuery is what the query parameters of an import ask for
+type importQuery struct {
+	format  importer.Format
+	preview bool
+	mapping importer.Mapping
+	opts    importer.Options
+}
+
+// importOptions parses the query parameters of an import. The format is detected
+// from the statement when it isn't given.
+func importOptions(q url.Values) (importQuery, error) {
+	v := new(models.ValidationError)
+	var query importQuery
+
+	if param := q.Get("format"); param != "" {
+		format, err := importer.ParseFormat(param)
+		if err != nil {
+			v.Add("format", "oneOf", "must be one of csv, ofx, qfx or qif")
+		}
+		query.format = format
+	}
+
+	if param := q.Get("preview"); param != "" {
+		preview, err := strconv.ParseBool(param)
+		if err != nil {
+			v.Add("preview", "boolean", "must be true or false")
+		}
+		query.preview = preview
+	}
+
+	switch q.Get("sign") {
+	case "", "statement":
+	case "ledger":
+		query.opts.Ledger = true
+	default:
+		v.Add("sign", "oneOf", "must be statement or ledger")
+	}
+
+	query.mapping = importer.Mapping{
+		Date:       q.Get("date"),
+		Amount:     q.Get("amount"),
+		Debit:      q.Get("debit"),
+		Credit:     q.Get("credit"),
+		Payee:      q.Get("payee"),
+		Memo:       q.Get("memo"),
+		ID:         q.Get("id"),
+		DateFormat: q.Get("dateFormat"),
+	}
+
+	if param := q.Get("header"); param != "" {
+		header, err := strconv.ParseBool(param)
+		if err != nil {
+			v.Add("header", "boolean", "must be true or false")
+		}
+		query.mapping.NoHeader = !header
+	}
+
+	return query, v.Err()
+}
+
+// readStatement reads the statement from an upload, which is either the request
+// body or the "file" part of a multipart form. It returns the statement's media type
+// along with it.
+func readStatement(r *http.Request) ([]byte, string, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "multipart/form-data" {
+		data, err := ioutil.ReadAll(r.Body)
+		return data, contentType, err
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	return data, header.Header.Get("Content-Type"), err
+}
+
+// ImportTransactions imports a bank statement into the account in the URL. Rows
+// imported before are reported as duplicates rather than imported again, and with
+// preview=true nothing is imported at all.
+func ImportTransactions(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		query, err := importOptions(r.URL.Query())
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+
+		limitBody(w, r, maxStatementSize)
+		data, contentType, err := readStatement(r)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		_, err = env.DB.GetAccount(r.Context(), accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if query.format == "" {
+			query.format = importer.Detect(contentType, data)
+		}
+
+		records, rowErrors, err := importer.Parse(query.format, data, query.mapping)
+		if err == importer.ErrMalformed {
+			v := new(models.ValidationError)
+			v.Add("statement", "format", "must be a readable "+strings.ToUpper(string(query.format))+" statement")
+			respondInvalid(w, r, v)
+			return
+		} else if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		imported, err := env.DB.ImportedIDs(r.Context(), accountID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		preview := importer.Build(accountID, records, imported, query.opts)
+		preview.Errors = append(rowErrors, preview.Errors...)
+		sort.SliceStable(preview.Errors, func(i, j int) bool {
+			return preview.Errors[i].Line < preview.Errors[j].Line
+		})
+
+		if !query.preview && len(preview.Transactions) > 0 {
+			created, err := env.DB.ImportTransactions(r.Context(), accountID, preview.Transactions)
+			if err != nil {
+				respondError(w, r, dbErrorStatus(err))
+				return
+			}
+
+			for i, transaction := range created {
+				preview.Transactions[i] = *transaction
+			}
+		}
+
+		resultJSON, _ := json.Marshal(importResult{
+			Format:    query.format,
+			Committed: !query.preview,
+			Preview:   preview,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resultJSON)
+	}
+}
@@ -0,0 +1,174 @@
+package routes_test
+
+import (
+	"bytes"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const importCSV = "Date,Description,Amount,ID\n2020-01-03,Coffee,-5.00,N1\n2020-01-04,Refund,2.00,OLD1\nsoon,Broken,1.00,N2\n"
+
+const importOFX = `<?xml version="1.0" encoding="UTF-8"?>
+<OFX><BANKTRANLIST>
+<STMTTRN><DTPOSTED>20200105</DTPOSTED><TRNAMT>-45.10</TRNAMT><FITID>A1</FITID><NAME>Grocer</NAME></STMTTRN>
+</BANKTRANLIST></OFX>`
+
+// uploadRequest builds a multipart form upload of a statement
+func uploadRequest(target string, filename string, statement string) *http.Request {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write([]byte(statement))
+	form.Close()
+
+	req := httptest.NewRequest("POST", target, body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestImportTransactions(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "PREVIEW",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("POST", "/api/v1/accounts/1/imports?preview=true", strings.NewReader(importCSV)), "Content-Type", "text/csv"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"format":"csv","committed":false,"transactions":[{"ID":0,"accountID":1,"amount":5,"postedDate":"2020-01-03","payee":"Coffee","memo":"","status":"cleared","importID":"fitid:N1"}],"duplicates":[{"ID":0,"accountID":1,"amount":-2,"postedDate":"2020-01-04","payee":"Refund","memo":"","status":"cleared","importID":"fitid:OLD1"}],"errors":[{"line":4,"message":"has an invalid date \"soon\""}]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "COMMIT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports", strings.NewReader(importOFX)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"format":"ofx","committed":true,"transactions":[{"ID":10,"accountID":1,"amount":45.1,"postedDate":"2020-01-05","payee":"Grocer","memo":"","status":"cleared","importID":"fitid:A1"}],"duplicates":[],"errors":[]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// statements have their own size limit, larger than other request bodies
+			name:           "LARGER_THAN_BODY_LIMIT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports", strings.NewReader(importOFX)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log, MaxBodyBytes: 64},
+			expectedBody:   `{"format":"ofx","committed":true,"transactions":[{"ID":10,"accountID":1,"amount":45.1,"postedDate":"2020-01-05","payee":"Grocer","memo":"","status":"cleared","importID":"fitid:A1"}],"duplicates":[],"errors":[]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "UPLOAD",
+			rec:            httptest.NewRecorder(),
+			req:            uploadRequest("/api/v1/accounts/1/imports?format=qfx&sign=ledger", "statement.qfx", importOFX),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"format":"ofx","committed":true,"transactions":[{"ID":10,"accountID":1,"amount":-45.1,"postedDate":"2020-01-05","payee":"Grocer","memo":"","status":"cleared","importID":"fitid:A1"}],"duplicates":[],"errors":[]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the query parameters aren't a format or a boolean
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports?format=pdf&preview=maybe", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"format","rule":"oneOf","message":"must be one of csv, ofx, qfx or qif"},{"field":"preview","rule":"boolean","message":"must be true or false"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the statement isn't OFX
+			name:           "MALFORMED",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports?format=ofx", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "statement", Rule: "format", Message: "must be a readable OFX statement"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the statement is larger than the server accepts
+			name:           "TOO_LARGE",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("POST", "/api/v1/accounts/1/imports", Endless(0)), "Content-Type", "text/csv"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusRequestEntityTooLarge),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// breaks the test because the uploaded statement is larger than the server accepts
+			name:           "TOO_LARGE_UPLOAD",
+			rec:            httptest.NewRecorder(),
+			req:            uploadRequest("/api/v1/accounts/1/imports", "statement.csv", strings.Repeat(" ", 10<<20+1)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusRequestEntityTooLarge),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// breaks the test because the mapping names a column the statement doesn't have
+			name:           "BAD_MAPPING",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports?payee=Merchant", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "payee", Rule: "column", Message: "must name a column in the statement"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because account 2 belongs to a different user
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/2/imports", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/imports", strings.NewReader(importCSV)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.ImportTransactions(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
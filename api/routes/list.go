@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"dinero/api/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultLimit is the page size of list endpoints when no limit is asked for
+	defaultLimit = 50
+	// maxLimit is the largest page a list endpoint returns
+	maxLimit = 200
+)
+
+// listOptions parses the limit, offset and sort query parameters of a list request
+func listOptions(q url.Values) (models.ListOptions, error) {
+	v := new(models.ValidationError)
+	opts := models.ListOptions{Limit: defaultLimit, Sort: models.ParseSort(q.Get("sort"))}
+
+	if param := q.Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxLimit {
+			v.Add("limit", "range", fmt.Sprintf("must be a number from 1 to %d", maxLimit))
+		} else {
+			opts.Limit = limit
+		}
+	}
+
+	if param := q.Get("offset"); param != "" {
+		offset, err := strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			v.Add("offset", "range", "must be a number of 0 or more")
+		} else {
+			opts.Offset = offset
+		}
+	}
+
+	return opts, v.Err()
+}
+
+// accountFilter parses the query parameters that filter a list of accounts
+func accountFilter(q url.Values) (models.AccountFilter, error) {
+	v := new(models.ValidationError)
+	filter := models.AccountFilter{
+		AccountType: q.Get("accountType"),
+		DueDate:     q.Get("dueDate"),
+	}
+
+	if param := q.Get("userID"); param != "" {
+		userID, err := strconv.Atoi(param)
+		if err != nil || userID < 1 {
+			v.Add("userID", "type", "must be a user ID")
+		} else {
+			filter.UserID = userID
+		}
+	}
+
+	if param := q.Get("minFullAmount"); param != "" {
+		amount, err := models.ParseMoney(param)
+		if err != nil {
+			v.Add("minFullAmount", "money", "must be an amount like 1234.56")
+		} else {
+			filter.MinFullAmount = &amount
+		}
+	}
+
+	if param := q.Get("maxFullAmount"); param != "" {
+		amount, err := models.ParseMoney(param)
+		if err != nil {
+			v.Add("maxFullAmount", "money", "must be an amount like 1234.56")
+		} else {
+			filter.MaxFullAmount = &amount
+		}
+	}
+
+	return filter, v.Err()
+}
+
+// setPageHeaders sets the X-Total-Count header and a Link header pointing at the
+// first, previous, next and last pages of a list response
+func setPageHeaders(w http.ResponseWriter, r *http.Request, opts models.ListOptions, total int) {
+	links := make([]string, 0, 4)
+	link := func(offset int, rel string) {
+		q := r.URL.Query()
+		q.Set("limit", strconv.Itoa(opts.Limit))
+		q.Set("offset", strconv.Itoa(offset))
+
+		u := *r.URL
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+
+	link(0, "first")
+	if opts.Offset > 0 {
+		prev := opts.Offset - opts.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link(prev, "prev")
+	}
+	if opts.Offset+opts.Limit < total {
+		link(opts.Offset+opts.Limit, "next")
+	}
+	if total > 0 {
+		link((total-1)/opts.Limit*opts.Limit, "last")
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
@@ -0,0 +1,151 @@
+package routes_test
+
+import (
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedStore fills a MemoryStore with two users, Luke with a monthly and a weekly
+// account and John with one, and returns it along with a session token for each
+func seedStore(t *testing.T) (*models.MemoryStore, string, string) {
+	ctx := context.Background()
+	store := models.NewMemoryStore()
+	luke, _ := store.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Payday: "2020-01-03"})
+	john, _ := store.CreateUser(ctx, models.User{FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: models.USD(186000)})
+	store.CreateAccount(ctx, models.Account{UserID: luke.ID, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"})
+	store.CreateAccount(ctx, models.Account{UserID: john.ID, Name: "Gym", AccountType: "monthly", FullAmount: models.USD(3000), DueDate: "1"})
+	store.CreateAccount(ctx, models.Account{UserID: luke.ID, Name: "Groceries", AccountType: "weekly", MinimumPayment: models.USD(15000), CurrentPayment: models.USD(15000), FullAmount: models.USD(15000), DueDate: "5"})
+
+	lukeSession, err := store.CreateSession(ctx, luke.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	johnSession, err := store.CreateSession(ctx, john.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, lukeSession.Token, johnSession.Token
+}
+
+// seededDB is a seeded MemoryStore that also takes the tokens MockDB does, for
+// Luke and John's sessions, so test cases can be logged in with authorize
+type seededDB struct {
+	*models.MemoryStore
+	luke string
+	john string
+}
+
+func (s *seededDB) SessionUser(ctx context.Context, token string) (*models.User, error) {
+	switch token {
+	case testToken:
+		token = s.luke
+	case "user-2-token":
+		token = s.john
+	}
+
+	return s.MemoryStore.SessionUser(ctx, token)
+}
+
+// seededEnv is an Env on a freshly seeded store, so each test case starts from
+// the seed rather than from what the cases before it wrote
+func seededEnv(t *testing.T) *config.Env {
+	store, luke, john := seedStore(t)
+	return &config.Env{DB: &seededDB{MemoryStore: store, luke: luke, john: john}, Log: config.Log}
+}
+
+// TestMemoryStore runs requests in order against one seeded MemoryStore, so the
+// constraints are the store's rather than canned mock errors
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	store, luke, john := seedStore(t)
+	env := &config.Env{DB: store, Log: config.Log}
+
+	tests := []TestCase{
+		{
+			name:           "CREATE",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{"name":"Car Payment","accountType":"monthly","fullAmount":21000,"currency":"USD","dueDate":"3"}`)), luke),
+			env:            env,
+			expectedBody:   `{"ID":4,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":0,"currentPayment":0,"fullAmount":21000,"currency":"USD","apr":0,"dueDate":"3","anchorDate":"","URL":""}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the caller already has an account with that name
+			name:           "DUPLICATE_NAME",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{"name":"Car Payment","accountType":"monthly","fullAmount":1,"currency":"USD","dueDate":"3"}`)), luke),
+			env:            env,
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "SAME_NAME_OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{"name":"Car Payment","accountType":"monthly","fullAmount":1,"currency":"USD","dueDate":"3"}`)), john),
+			env:            env,
+			expectedBody:   `{"ID":5,"userID":2,"name":"Car Payment","accountType":"monthly","minimumPayment":0,"currentPayment":0,"fullAmount":1,"currency":"USD","apr":0,"dueDate":"3","anchorDate":"","URL":""}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because account 2 belongs to John
+			name:           "NOT_OWNER",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("GET", "/api/v1/accounts/2", nil), luke),
+			env:            env,
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "LIST",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("GET", "/api/v1/accounts?sort=-dueDate&limit=1", nil), luke),
+			env:            env,
+			expectedBody:   `[{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the email belongs to John
+			name:           "TAKEN_EMAIL",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(withToken(httptest.NewRequest("PATCH", "/api/v1/users/1", strings.NewReader(`{"email":"ide.johnc@gmail.com"}`)), luke), "Content-Type", "application/merge-patch+json"),
+			env:            env,
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			// breaks the test because Luke still has accounts
+			name:           "DELETE_WITH_ACCOUNTS",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("DELETE", "/api/v1/users/1", nil), luke),
+			env:            env,
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
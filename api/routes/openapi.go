@@ -0,0 +1,470 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/importer"
+	"dinero/api/models"
+	"dinero/api/patch"
+	"dinero/api/payoff"
+	"dinero/api/plan"
+	"dinero/api/schedule"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// jsonObject is a JSON object in the OpenAPI document
+type jsonObject map[string]interface{}
+
+// parameter is a query parameter of an operation
+type parameter struct {
+	name        string
+	description string
+	// kind is the JSON schema type of the parameter, string when empty
+	kind string
+	enum []string
+}
+
+// operation documents one route. Path parameters are read from the route itself.
+type operation struct {
+	summary string
+	// public operations can be called without a session
+	public bool
+	query  []parameter
+	// request is a value of the type of the request body, nil for no body. A
+	// string is an uploaded file.
+	request interface{}
+	// requestTypes are the media types the body can be sent as, JSON when empty
+	requestTypes []string
+	// status is the status of a successful response, 200 when zero
+	status int
+	// response is a value of the type of the successful response body, nil for no body
+	response interface{}
+	// responses are the other statuses the operation responds with. 401, 404 and
+	// 500 are added where they apply, and 400 and 422 when there's a request body.
+	responses []int
+}
+
+// listParams are the query parameters of every list endpoint
+var listParams = []parameter{
+	{name: "limit", description: fmt.Sprintf("page size from 1 to %d, %d by default", maxLimit, defaultLimit), kind: "integer"},
+	{name: "offset", description: "number of records to skip", kind: "integer"},
+	{name: "sort", description: "comma separated fields to sort by, each descending when prefixed with -"},
+}
+
+// accountParams are the query parameters filtering a list of accounts
+var accountParams = append(listParams[:len(listParams):len(listParams)],
+	parameter{name: "accountType", description: "only accounts of this type"},
+	parameter{name: "dueDate", description: "only accounts due on this day"},
+	parameter{name: "userID", description: "only accounts belonging to this user", kind: "integer"},
+	parameter{name: "minFullAmount", description: "only accounts owing at least this amount", kind: "number"},
+	parameter{name: "maxFullAmount", description: "only accounts owing at most this amount", kind: "number"},
+)
+
+// dueDateParams say what happens to due dates on weekends and holidays
+var dueDateParams = []parameter{
+	{name: "adjust", description: "where due dates on weekends and holidays move to", enum: []string{"next", "previous", "none"}},
+	{name: "holidays", description: "holiday calendar to skip", enum: []string{"us", "none"}},
+}
+
+// patchTypes are the media types a PATCH body can be sent as
+var patchTypes = []string{patch.MergePatchType, patch.JSONPatchType}
+
+// importResponse is the response to ImportTransactions
+var importResponse = importResult{Preview: &importer.Preview{}}
+
+// operations documents every route in NewAPI, keyed by method and path
+var operations = map[string]operation{
+	"GET /openapi.json": {summary: "This OpenAPI document", public: true, response: jsonObject{}},
+	"GET /docs":         {summary: "Browsable documentation of the API", public: true, response: ""},
+
+	"POST /auth/login":  {summary: "Log in, starting a session", public: true, request: credentials{}, response: models.Session{}, responses: []int{http.StatusUnauthorized}},
+	"POST /auth/logout": {summary: "Log out, ending the session", status: http.StatusNoContent},
+
+	"GET /accounts":                {summary: "List the caller's accounts", query: accountParams, response: []models.Account{}, responses: []int{http.StatusBadRequest}},
+	"POST /accounts":               {summary: "Create an account for the caller", request: models.Account{}, response: models.Account{}, responses: []int{http.StatusConflict}},
+	"GET /accounts/{accountID}":    {summary: "Get an account", response: models.Account{}, responses: []int{http.StatusNotModified}},
+	"PUT /accounts/{accountID}":    {summary: "Replace an account, or create it with this ID", request: models.Account{}, status: http.StatusNoContent, responses: []int{http.StatusCreated, http.StatusConflict, http.StatusPreconditionFailed}},
+	"PATCH /accounts/{accountID}":  {summary: "Change some fields of an account", request: jsonObject{}, requestTypes: patchTypes, response: models.Account{}, responses: []int{http.StatusConflict, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType}},
+	"DELETE /accounts/{accountID}": {summary: "Delete an account and its transactions", status: http.StatusNoContent, responses: []int{http.StatusPreconditionFailed}},
+
+	"GET /accounts/{accountID}/balance":  {summary: "Get an account's balance, derived from its transactions", response: models.Balance{}},
+	"GET /accounts/{accountID}/schedule": {summary: "List when an account's payments are due", query: append([]parameter{{name: "from", description: "first day, YYYY-MM-DD, today by default"}, {name: "to", description: "last day, YYYY-MM-DD"}}, dueDateParams...), response: []schedule.Occurrence{}, responses: []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	"POST /accounts/{accountID}/imports": {
+		summary: "Import a CSV, OFX, QFX or QIF bank statement into an account",
+		query: []parameter{
+			{name: "format", description: "statement format, detected when not given", enum: []string{"csv", "ofx", "qfx", "qif"}},
+			{name: "preview", description: "report what would be imported without importing it", kind: "boolean"},
+			{name: "sign", description: "ledger when the statement already signs charges positive", enum: []string{"statement", "ledger"}},
+			{name: "date", description: "CSV column of the posted date"},
+			{name: "amount", description: "CSV column of the signed amount"},
+			{name: "debit", description: "CSV column of debits"},
+			{name: "credit", description: "CSV column of credits"},
+			{name: "payee", description: "CSV column of the payee"},
+			{name: "memo", description: "CSV column of the memo"},
+			{name: "id", description: "CSV column of the bank's transaction ID"},
+			{name: "dateFormat", description: "Go layout of CSV dates"},
+			{name: "header", description: "whether the CSV has a header row", kind: "boolean"},
+		},
+		request:      "",
+		requestTypes: []string{"text/csv", "application/x-ofx", "application/x-qif", "multipart/form-data"},
+		response:     importResponse,
+		responses:    []int{http.StatusConflict},
+	},
+
+	"GET /accounts/{accountID}/transactions":                    {summary: "List an account's transactions, oldest first", response: []models.Transaction{}},
+	"POST /accounts/{accountID}/transactions":                   {summary: "Post a transaction to an account", request: models.Transaction{}, response: models.Transaction{}, responses: []int{http.StatusConflict}},
+	"GET /accounts/{accountID}/transactions/{transactionID}":    {summary: "Get a transaction", response: models.Transaction{}},
+	"PUT /accounts/{accountID}/transactions/{transactionID}":    {summary: "Replace a transaction, or create it with this ID", request: models.Transaction{}, status: http.StatusNoContent, responses: []int{http.StatusCreated, http.StatusConflict}},
+	"DELETE /accounts/{accountID}/transactions/{transactionID}": {summary: "Delete a transaction", status: http.StatusNoContent},
+
+	"GET /export": {summary: "Export the caller with their accounts and transactions, as JSON or zipped CSV files", query: []parameter{{name: "format", enum: []string{"json", "csv"}}}, response: models.Backup{}, responses: []int{http.StatusBadRequest}},
+	"POST /import": {
+		summary:   "Restore an export",
+		query:     []parameter{{name: "onConflict", description: "what to do with records that differ from the ones stored", enum: []string{models.ConflictFail, models.ConflictSkip, models.ConflictOverwrite}}},
+		request:   models.Backup{},
+		response:  models.RestoreReport{},
+		responses: []int{http.StatusConflict},
+	},
+
+	"GET /users":               {summary: "List users visible to the caller", query: append(listParams[:len(listParams):len(listParams)], parameter{name: "email", description: "only the user with this email"}), response: []models.User{}, responses: []int{http.StatusBadRequest}},
+	"POST /users":              {summary: "Register a user", public: true, request: registration{}, response: models.User{}, responses: []int{http.StatusConflict}},
+	"GET /users/{userID}":      {summary: "Get a user", response: models.User{}, responses: []int{http.StatusNotModified}},
+	"PUT /users/{userID}":      {summary: "Replace a user", request: models.User{}, status: http.StatusNoContent, responses: []int{http.StatusCreated, http.StatusConflict, http.StatusPreconditionFailed}},
+	"PATCH /users/{userID}":    {summary: "Change some fields of a user", request: jsonObject{}, requestTypes: patchTypes, response: models.User{}, responses: []int{http.StatusConflict, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType}},
+	"DELETE /users/{userID}":   {summary: "Delete a user, handling their accounts by the server's delete policy", status: http.StatusNoContent, responses: []int{http.StatusConflict, http.StatusPreconditionFailed}},
+	"GET /users/{userID}/plan": {summary: "Plan which paycheck covers each upcoming bill", query: append([]parameter{{name: "from", description: "plan from the paycheck on or before this day, YYYY-MM-DD"}, {name: "paychecks", description: fmt.Sprintf("number of paychecks from 1 to %d", maxPaychecks), kind: "integer"}}, dueDateParams...), response: plan.Plan{}, responses: []int{http.StatusBadRequest, http.StatusUnprocessableEntity}},
+	"GET /users/{userID}/payoff": {
+		summary: "Simulate paying off the user's debts month by month",
+		query: []parameter{
+			{name: "strategy", enum: []string{string(payoff.Avalanche), string(payoff.Snowball), string(payoff.Custom)}},
+			{name: "order", description: "comma separated account IDs to pay off first with the custom strategy"},
+			{name: "extra", description: "amount paid on top of the accounts' payments every month", kind: "number"},
+			{name: "from", description: "month of the first payment, YYYY-MM"},
+		},
+		response:  payoff.Result{},
+		responses: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+	},
+
+	"GET /users/{userID}/accounts":                {summary: "List a user's accounts", query: accountParams, response: []models.Account{}, responses: []int{http.StatusBadRequest}},
+	"POST /users/{userID}/accounts":               {summary: "Create an account for a user", request: models.Account{}, response: models.Account{}, responses: []int{http.StatusConflict}},
+	"GET /users/{userID}/accounts/{accountID}":    {summary: "Get one of a user's accounts", response: models.Account{}, responses: []int{http.StatusNotModified}},
+	"PUT /users/{userID}/accounts/{accountID}":    {summary: "Replace one of a user's accounts, or create it with this ID", request: models.Account{}, status: http.StatusNoContent, responses: []int{http.StatusCreated, http.StatusConflict, http.StatusPreconditionFailed}},
+	"DELETE /users/{userID}/accounts/{accountID}": {summary: "Delete one of a user's accounts", status: http.StatusNoContent, responses: []int{http.StatusPreconditionFailed}},
+}
+
+// pathParam matches the parameters in a route pattern
+var pathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// routePath turns a pattern chi.Walk reports, like /accounts/*/{accountID}/*/,
+// into the path it serves, /accounts/{accountID}
+func routePath(pattern string) string {
+	for strings.Contains(pattern, "/*/") {
+		pattern = strings.Replace(pattern, "/*/", "/", -1)
+	}
+	if pattern != "/" {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// OpenAPI describes every route registered on r as an OpenAPI 3 document. It
+// fails if a route has no entry in operations, or an entry has no route, so the
+// document can't fall behind the router.
+func OpenAPI(r chi.Routes) (jsonObject, error) {
+	paths := make(map[string]jsonObject)
+	schemas := make(jsonObject)
+	documented := make(map[string]bool)
+	var undocumented []string
+
+	err := chi.Walk(r, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := routePath(pattern)
+		key := method + " " + path
+		op, ok := operations[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			return nil
+		}
+		documented[key] = true
+
+		if paths[path] == nil {
+			paths[path] = make(jsonObject)
+		}
+		paths[path][strings.ToLower(method)] = op.document(path, schemas)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range operations {
+		if !documented[key] {
+			undocumented = append(undocumented, key+" (no such route)")
+		}
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("openapi: routes and operations don't match: %s", strings.Join(undocumented, ", "))
+	}
+
+	return jsonObject{
+		"openapi": "3.0.3",
+		"info": jsonObject{
+			"title":       "Dinero",
+			"description": "Track accounts, bills and transactions, and plan paying them off. Money is sent and returned as decimal numbers in the currency of the user or account it belongs to; amounts may also be sent as strings like \"217.99\".",
+			"version":     "1",
+		},
+		"servers": []jsonObject{{"url": APIPrefix}},
+		"paths":   paths,
+		"components": jsonObject{
+			"schemas": schemas,
+			"securitySchemes": jsonObject{
+				"bearer": jsonObject{"type": "http", "scheme": "bearer"},
+				"cookie": jsonObject{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+			},
+		},
+		"security": []jsonObject{{"bearer": []string{}}, {"cookie": []string{}}},
+	}, nil
+}
+
+// document describes the operation at path, adding the schemas it uses to schemas
+func (op operation) document(path string, schemas jsonObject) jsonObject {
+	doc := jsonObject{"summary": op.summary}
+	if op.public {
+		doc["security"] = []jsonObject{}
+	}
+
+	var params []jsonObject
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, jsonObject{"name": match[1], "in": "path", "required": true, "schema": jsonObject{"type": "integer"}})
+	}
+	for _, p := range op.query {
+		schema := jsonObject{"type": "string"}
+		if p.kind != "" {
+			schema["type"] = p.kind
+		}
+		if p.enum != nil {
+			schema["enum"] = p.enum
+		}
+		param := jsonObject{"name": p.name, "in": "query", "schema": schema}
+		if p.description != "" {
+			param["description"] = p.description
+		}
+		params = append(params, param)
+	}
+	if params != nil {
+		doc["parameters"] = params
+	}
+
+	statuses := append([]int{}, op.responses...)
+	if op.request != nil {
+		content := make(jsonObject)
+		types := op.requestTypes
+		if types == nil {
+			types = []string{"application/json"}
+		}
+		for _, t := range types {
+			content[t] = jsonObject{"schema": requestSchema(op.request, t, schemas)}
+		}
+		doc["requestBody"] = jsonObject{"required": true, "content": content}
+		statuses = append(statuses, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity)
+	}
+	if !op.public {
+		statuses = append(statuses, http.StatusUnauthorized)
+	}
+	if strings.Contains(path, "{") {
+		statuses = append(statuses, http.StatusNotFound)
+	}
+	statuses = append(statuses, http.StatusInternalServerError)
+
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := jsonObject{"description": http.StatusText(status)}
+	if op.response != nil {
+		success["content"] = jsonObject{mediaType(op.response): jsonObject{"schema": schemaOf(reflect.TypeOf(op.response), schemas)}}
+	}
+	responses := jsonObject{fmt.Sprint(status): success}
+	for _, s := range statuses {
+		response := jsonObject{"description": http.StatusText(s)}
+		if s >= http.StatusBadRequest {
+			response["content"] = jsonObject{"application/json": jsonObject{"schema": schemaOf(reflect.TypeOf(errorEnvelope{}), schemas)}}
+		}
+		responses[fmt.Sprint(s)] = response
+	}
+	doc["responses"] = responses
+
+	return doc
+}
+
+// requestSchema describes a request body of the same type as v sent as mediaType
+func requestSchema(v interface{}, mediaType string, schemas jsonObject) jsonObject {
+	if _, ok := v.(string); !ok {
+		return schemaOf(reflect.TypeOf(v), schemas)
+	}
+
+	file := jsonObject{"type": "string", "format": "binary"}
+	if mediaType == "multipart/form-data" {
+		return jsonObject{"type": "object", "properties": jsonObject{"file": file}}
+	}
+	return file
+}
+
+// mediaType is the media type of a response body of the same type as v
+func mediaType(v interface{}) string {
+	if _, ok := v.(string); ok {
+		return "text/html"
+	}
+	return "application/json"
+}
+
+// schemaOf describes the JSON encoding of t. Structs are added to schemas under
+// their name and referred to, so each is only described once.
+func schemaOf(t reflect.Type, schemas jsonObject) jsonObject {
+	switch t {
+	case reflect.TypeOf(models.Money{}):
+		return jsonObject{"type": "number", "description": "amount of money, exact to the cent"}
+	case reflect.TypeOf(models.Rate(0)):
+		return jsonObject{"type": "number", "description": "percentage, exact to a hundredth of a percent"}
+	case reflect.TypeOf(time.Time{}):
+		return jsonObject{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(schedule.Occurrence{}):
+		return schemaOf(reflect.TypeOf(struct {
+			Date    string       `json:"date"`
+			Nominal string       `json:"nominalDate"`
+			Amount  models.Money `json:"amount"`
+		}{}), schemas)
+	case reflect.TypeOf(jsonObject{}):
+		return jsonObject{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem(), schemas)
+	case reflect.Bool:
+		return jsonObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonObject{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonObject{"type": "number"}
+	case reflect.String:
+		return jsonObject{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return jsonObject{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return jsonObject{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, schemas)
+		}
+		name := schemaName(t)
+		if _, ok := schemas[name]; !ok {
+			// Set before describing the fields, in case a field refers back to t
+			schemas[name] = jsonObject{}
+			schemas[name] = structSchema(t, schemas)
+		}
+		return jsonObject{"$ref": "#/components/schemas/" + name}
+	}
+
+	return jsonObject{}
+}
+
+// schemaName names the schema of a struct type. Types from outside models and
+// routes are prefixed with their package, so payoff.Result is PayoffResult,
+// unless the name already starts with it like plan.Plan.
+func schemaName(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	pkg = strings.ToUpper(pkg[:1]) + pkg[1:]
+	if pkg == "Models" || pkg == "Routes" || strings.HasPrefix(name, pkg) {
+		return name
+	}
+	return pkg + name
+}
+
+// structSchema describes the fields of a struct the way encoding/json encodes them
+func structSchema(t reflect.Type, schemas jsonObject) jsonObject {
+	properties := make(jsonObject)
+	addFields(t, properties, schemas)
+	return jsonObject{"type": "object", "properties": properties}
+}
+
+// addFields adds the JSON fields of struct t to properties, including the fields
+// of structs it embeds
+func addFields(t reflect.Type, properties jsonObject, schemas jsonObject) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			addFields(embedded, properties, schemas)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaOf(field.Type, schemas)
+	}
+}
+
+// OpenAPISpec serves the OpenAPI document describing the routes of r. It's built
+// on the first request, once every route has been registered.
+func OpenAPISpec(env *config.Env, r chi.Routes) func(http.ResponseWriter, *http.Request) {
+	var once sync.Once
+	var spec []byte
+	var specErr error
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		once.Do(func() {
+			var doc jsonObject
+			doc, specErr = OpenAPI(r)
+			if specErr == nil {
+				spec, specErr = json.Marshal(doc)
+			}
+		})
+		if specErr != nil {
+			env.Log.WithError(specErr).Error("Failed to build the OpenAPI document")
+			respondError(w, req, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}
+}
+
+// docsPage renders the OpenAPI document. It's built in, rather than loading a
+// viewer from a CDN, so the docs work without a connection to the internet.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// APIDocs serves a page for browsing the OpenAPI document
+func APIDocs(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	}
+}
@@ -0,0 +1,121 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/routes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPICoversRoutes fails when a route is added to NewAPI without
+// documenting it in the OpenAPI document, or a documented route is removed
+func TestOpenAPICoversRoutes(t *testing.T) {
+	t.Parallel()
+
+	env := &config.Env{DB: &MockDB{}, Log: config.Log}
+	if _, err := routes.OpenAPI(routes.NewAPI(env)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A route registered without a spec entry is caught
+	r := routes.NewAPI(env)
+	r.Get("/undocumented", routes.NotFound(env))
+	_, err := routes.OpenAPI(r)
+	if err == nil || !strings.Contains(err.Error(), "GET /undocumented") {
+		t.Errorf("\nUndocumented route:\n\tGot: \t\t%v\n\tExpected: \tan error naming GET /undocumented\n", err)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	routes.NewRouter(&config.Env{DB: &MockDB{}, Log: config.Log}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("\nCode:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, http.StatusOK)
+	}
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]struct {
+			Parameters []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			Responses map[string]interface{} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("\nOpenAPI version:\n\tGot: \t\t%s\n\tExpected: \t%s\n", spec.OpenAPI, "3.0.3")
+	}
+
+	if len(spec.Servers) != 1 || spec.Servers[0].URL != routes.APIPrefix {
+		t.Errorf("\nServers:\n\tGot: \t\t%+v\n\tExpected: \t%s\n", spec.Servers, routes.APIPrefix)
+	}
+
+	get := spec.Paths["/users/{userID}/accounts/{accountID}"]["get"]
+	if len(get.Parameters) != 2 || get.Parameters[0].Name != "userID" || get.Parameters[1].In != "path" {
+		t.Errorf("\nPath parameters:\n\tGot: \t\t%+v\n", get.Parameters)
+	}
+	for _, status := range []string{"200", "401", "404", "500"} {
+		if _, ok := get.Responses[status]; !ok {
+			t.Errorf("\nResponses:\n\tGot: \t\t%v\n\tExpected: \t%s among them\n", get.Responses, status)
+		}
+	}
+
+	// Schemas follow the JSON tags, leaving out fields that are never sent
+	account := spec.Components.Schemas["Account"].Properties
+	for _, field := range []string{"ID", "userID", "fullAmount", "apr", "URL"} {
+		if _, ok := account[field]; !ok {
+			t.Errorf("\nAccount schema:\n\tGot: \t\t%v\n\tExpected: \t%s among the properties\n", account, field)
+		}
+	}
+	if _, ok := spec.Components.Schemas["User"].Properties["PasswordHash"]; ok {
+		t.Error("\nUser schema:\n\tGot: \t\tPasswordHash\n\tExpected: \tno password hash\n")
+	}
+	if _, ok := spec.Components.Schemas["Registration"].Properties["password"]; !ok {
+		t.Error("\nRegistration schema:\n\tGot: \t\tno password\n\tExpected: \tpassword\n")
+	}
+}
+
+// TestAPIDocs checks the docs page is served without sending the browser to
+// another site for the viewer
+func TestAPIDocs(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("GET", "/api/v1/docs", nil)
+	rec := httptest.NewRecorder()
+	routes.NewRouter(&config.Env{DB: &MockDB{}, Log: config.Log}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("\nCode:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("\nContent-Type:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, "text/html; charset=utf-8")
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "fetch('openapi.json')") {
+		t.Error("\nBody:\n\tGot: \t\ta page that doesn't load openapi.json\n\tExpected: \ta page rendering openapi.json\n")
+	}
+	if strings.Contains(body, "http://") || strings.Contains(body, "https://") {
+		t.Error("\nBody:\n\tGot: \t\ta page loading from another site\n\tExpected: \teverything served by the API\n")
+	}
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"dinero/api/models"
+	"dinero/api/patch"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+// acceptPatch lists the patch formats PATCH requests can be sent in
+const acceptPatch = patch.MergePatchType + ", " + patch.JSONPatchType
+
+// errUnsupportedPatch is returned for PATCH bodies that aren't a known patch format
+var errUnsupportedPatch = errors.New("error: unsupported patch media type")
+
+// readPatch applies the PATCH request body to the JSON of current and reads the
+// result into patched. The Content-Type picks JSON Patch or JSON Merge Patch, with
+// plain JSON treated as a merge patch.
+func readPatch(r *http.Request, current interface{}, patched interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return errUnsupportedPatch
+	}
+
+	apply := patch.MergePatch
+	switch mediaType {
+	case patch.MergePatchType, "application/json":
+	case patch.JSONPatchType:
+		apply = patch.Apply
+	default:
+		return errUnsupportedPatch
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if tooLarge(err) {
+		return err
+	} else if err != nil {
+		return patch.ErrMalformed
+	}
+	defer r.Body.Close()
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patchedJSON, err := apply(currentJSON, body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(patchedJSON, patched)
+}
+
+// respondPatchError responds to a PATCH body that couldn't be applied
+func respondPatchError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(*patch.PathError); ok {
+		respondError(w, r, http.StatusUnprocessableEntity)
+		return
+	}
+
+	switch err {
+	case errUnsupportedPatch:
+		w.Header().Set("Accept-Patch", acceptPatch)
+		respondError(w, r, http.StatusUnsupportedMediaType)
+	case patch.ErrTestFailed:
+		respondError(w, r, http.StatusConflict)
+	default:
+		// Bodies over the size limit, malformed patches, and patches that leave a
+		// field with the wrong type
+		respondError(w, r, bodyErrorStatus(err))
+	}
+}
+
+// readOnlyError is the validation error for a patch that changed fields that can't be changed
+func readOnlyError(fields ...string) error {
+	v := new(models.ValidationError)
+	for _, field := range fields {
+		v.Add(field, "readOnly", "cannot be changed")
+	}
+
+	return v.Err()
+}
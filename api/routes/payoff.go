@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/payoff"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// payoffOptions parses the strategy, order, extra and from query parameters of a
+// payoff request. from is the month of the first payment and defaults to this month.
+func payoffOptions(q url.Values) (payoff.Options, error) {
+	v := new(models.ValidationError)
+	opts := payoff.Options{Strategy: payoff.Avalanche, Extra: models.NewMoney(0, models.DefaultCurrency), Start: time.Now()}
+
+	switch strategy := payoff.Strategy(q.Get("strategy")); strategy {
+	case "":
+	case payoff.Avalanche, payoff.Snowball, payoff.Custom:
+		opts.Strategy = strategy
+	default:
+		v.Add("strategy", "oneOf", "must be one of avalanche, snowball or custom")
+	}
+
+	if param := q.Get("order"); param != "" {
+		if opts.Strategy != payoff.Custom {
+			v.Add("order", "strategy", "can only be used with the custom strategy")
+		}
+
+		for _, field := range strings.Split(param, ",") {
+			accountID, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || accountID < 1 {
+				v.Add("order", "type", "must be a comma separated list of account IDs")
+				break
+			}
+			opts.Order = append(opts.Order, accountID)
+		}
+	}
+
+	if param := q.Get("extra"); param != "" {
+		extra, err := models.ParseMoney(param)
+		if err != nil || extra.IsNegative() {
+			v.Add("extra", "money", "must be an amount of 0 or more like 1234.56")
+		} else {
+			opts.Extra = extra
+		}
+	}
+
+	if param := q.Get("from"); param != "" {
+		month, err := time.Parse(payoff.MonthFormat, param)
+		if err != nil {
+			v.Add("from", "month", "must be a month formatted as YYYY-MM")
+		} else {
+			opts.Start = month
+		}
+	}
+
+	return opts, v.Err()
+}
+
+// GetUserPayoff simulates paying off the user's accounts month by month with the
+// strategy in the query parameters
+func GetUserPayoff(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		opts, err := payoffOptions(r.URL.Query())
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+
+		accounts, err := env.DB.UserAccounts(r.Context(), userID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		result, err := payoff.Simulate(accounts, opts)
+		if err == payoff.ErrUnknownAccount {
+			v := new(models.ValidationError)
+			v.Add("order", "oneOf", "must only list accounts with a fullAmount left to pay")
+			respondBadQuery(w, r, v)
+			return
+		} else if err == payoff.ErrMixedCurrencies {
+			v := new(models.ValidationError)
+			v.Add("currency", "same", "must be the same for every account to pay them off together")
+			respondInvalid(w, r, v)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		resultJSON, _ := json.Marshal(result)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resultJSON)
+	}
+}
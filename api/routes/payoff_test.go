@@ -0,0 +1,103 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUserPayoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			// 100.00 a month plus 528.00 extra clears the 728.00 phone payment in two months
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff?strategy=snowball&extra=528&from=2020-01", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"strategy":"snowball","monthlyBudget":628,"paidOff":true,"payoffDate":"2020-02","months":2,"totalInterest":0,"totalPaid":728,"accounts":[{"accountID":1,"name":"Phone Payment","paidOff":true,"payoffDate":"2020-02","months":2,"interestPaid":0,"totalPaid":728}],"schedule":[{"month":"2020-01","payments":[{"accountID":1,"payment":628,"interest":0,"principal":628,"balance":100}]},{"month":"2020-02","payments":[{"accountID":1,"payment":100,"interest":0,"principal":100,"balance":0}]}]}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the query parameters aren't valid options
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff?strategy=lottery&extra=-5&from=January", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"strategy","rule":"oneOf","message":"must be one of avalanche, snowball or custom"},{"field":"extra","rule":"money","message":"must be an amount of 0 or more like 1234.56"},{"field":"from","rule":"month","message":"must be a month formatted as YYYY-MM"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because an order only applies to the custom strategy
+			name:           "BAD_QUERY_ORDER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff?order=1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"order","rule":"strategy","message":"can only be used with the custom strategy"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because account 2 isn't one of the user's debts
+			name:           "UNKNOWN_ACCOUNT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff?strategy=custom&order=2,1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"order","rule":"oneOf","message":"must only list accounts with a fullAmount left to pay"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because callers can only simulate their own debts
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/2/payoff", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/payoff", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetUserPayoff(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/plan"
+	"dinero/api/schedule"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultPaychecks is the number of paychecks planned when none is asked for
+	defaultPaychecks = 6
+	// maxPaychecks is the most paychecks a plan covers, a year's worth
+	maxPaychecks = 26
+)
+
+// planOptions parses the from, paychecks, adjust and holidays query parameters of a
+// plan request. from defaults to today.
+func planOptions(q url.Values) (time.Time, int, schedule.Options, error) {
+	v := new(models.ValidationError)
+	from := schedule.Day(time.Now())
+	paychecks := defaultPaychecks
+
+	if param := q.Get("from"); param != "" {
+		date, err := schedule.ParseDate(param)
+		if err != nil {
+			v.Add("from", "date", "must be a date formatted as YYYY-MM-DD")
+		} else {
+			from = date
+		}
+	}
+
+	if param := q.Get("paychecks"); param != "" {
+		count, err := strconv.Atoi(param)
+		if err != nil || count < 1 || count > maxPaychecks {
+			v.Add("paychecks", "range", fmt.Sprintf("must be a number from 1 to %d", maxPaychecks))
+		} else {
+			paychecks = count
+		}
+	}
+
+	opts := scheduleOptions(q, v)
+
+	return from, paychecks, opts, v.Err()
+}
+
+// GetUserPlan lays out the user's upcoming paychecks and the bills each one has to
+// cover, starting with the paycheck on or before the from query parameter
+func GetUserPlan(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		from, paychecks, opts, err := planOptions(r.URL.Query())
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+
+		user, err := env.DB.GetUser(r.Context(), userID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		accounts, err := env.DB.UserAccounts(r.Context(), userID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		p, err := plan.Build(user, accounts, from, paychecks, opts)
+		if err == plan.ErrNoPayday {
+			v := new(models.ValidationError)
+			v.Add("payday", "required", "is required to plan paychecks")
+			respondInvalid(w, r, v)
+			return
+		} else if err == plan.ErrMixedCurrencies {
+			v := new(models.ValidationError)
+			v.Add("currency", "accounts", "must be the currency of every account to plan paychecks")
+			respondInvalid(w, r, v)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		planJSON, _ := json.Marshal(p)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(planJSON)
+	}
+}
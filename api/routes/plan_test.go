@@ -0,0 +1,93 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetUserPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/plan?from=2020-01-10&paychecks=2", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"paychecks":[{"date":"2020-01-03","income":1400,"bills":[{"accountID":1,"name":"Phone Payment","date":"2020-01-10","amount":100}],"total":100,"leftover":1300,"negative":false},{"date":"2020-01-17","income":1400,"bills":[],"total":0,"leftover":1400,"negative":false}],"unscheduled":[],"income":2800,"total":100,"leftover":2700,"shortfalls":0}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the query parameters are out of range
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/plan?paychecks=100&holidays=uk", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"paychecks","rule":"range","message":"must be a number from 1 to 26"},{"field":"holidays","rule":"oneOf","message":"must be us or none"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because user 2 has no payday to plan from
+			name:           "NO_PAYDAY",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("GET", "/api/v1/users/2/plan", nil), "user-2-token"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "payday", Rule: "required", Message: "is required to plan paychecks"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because callers can only plan for themselves
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/2/plan", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/plan", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/plan", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetUserPlan(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
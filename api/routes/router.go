@@ -2,22 +2,52 @@ package routes
 
 import (
 	"dinero/api/config"
+	"dinero/api/web"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
-// NewRouter sets up a chi Mux router
+// APIPrefix is the path NewRouter serves the API under
+const APIPrefix = "/api/v1"
+
+// NewRouter sets up a chi Mux router serving the API under APIPrefix and the
+// web UI at every other path
 func NewRouter(env *config.Env) *chi.Mux {
 	r := chi.NewRouter()
 
+	// Middleware to tag each request with an ID that error responses include
+	r.Use(RequestID)
 	// Middleware to log each route using Logrus
 	r.Use(config.RouteLogger(env))
 	// Middleware to recover gracefully from panics
 	r.Use(middleware.Recoverer)
+	// Middleware to refuse oversized request bodies
+	r.Use(LimitBody(env.MaxBodyBytes))
+
+	r.Mount(APIPrefix, NewAPI(env))
+	r.HandleFunc("/*", WebUI(env, web.Dist())) // GET /, /assets/app.3f2a1b9c.js, /accounts/123...
+
+	// chi answers methods it doesn't know before routing, so this is needed
+	// here as well as in the API
+	r.MethodNotAllowed(MethodNotAllowed(env))
+
+	return r
+}
+
+// NewAPI sets up the API routes, which NewRouter serves under APIPrefix
+func NewAPI(env *config.Env) *chi.Mux {
+	r := chi.NewRouter()
 
 	// Define routes
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/login", Login(env)) // POST /auth/login
+
+		r.With(Authenticate(env)).Post("/logout", Logout(env)) // POST /auth/logout
+	})
+
 	r.Route("/accounts", func(r chi.Router) {
+		r.Use(Authenticate(env))
 		r.Get("/", AllAccounts(env))    // GET /accounts
 		r.Post("/", CreateAccount(env)) // POST /accounts
 
@@ -25,22 +55,71 @@ func NewRouter(env *config.Env) *chi.Mux {
 			r.Use(AccountCtx(env))
 			r.Get("/", GetAccount(env))       // GET /accounts/123
 			r.Put("/", UpdateAccount(env))    // PUT /accounts/123
+			r.Patch("/", PatchAccount(env))   // PATCH /accounts/123
 			r.Delete("/", DeleteAccount(env)) // DELETE /accounts/123
+
+			r.Get("/balance", GetAccountBalance(env))   // GET /accounts/123/balance
+			r.Get("/schedule", GetAccountSchedule(env)) // GET /accounts/123/schedule
+			r.Post("/imports", ImportTransactions(env)) // POST /accounts/123/imports
+
+			r.Route("/transactions", func(r chi.Router) {
+				r.Get("/", AllTransactions(env))    // GET /accounts/123/transactions
+				r.Post("/", CreateTransaction(env)) // POST /accounts/123/transactions
+
+				r.Route("/{transactionID}", func(r chi.Router) {
+					r.Use(TransactionCtx(env))
+					r.Get("/", GetTransaction(env))       // GET /accounts/123/transactions/456
+					r.Put("/", UpdateTransaction(env))    // PUT /accounts/123/transactions/456
+					r.Delete("/", DeleteTransaction(env)) // DELETE /accounts/123/transactions/456
+				})
+			})
 		})
 	})
 
+	r.Group(func(r chi.Router) {
+		r.Use(Authenticate(env))
+		r.Get("/export", Export(env))  // GET /export
+		r.Post("/import", Import(env)) // POST /import
+	})
+
 	r.Route("/users", func(r chi.Router) {
-		r.Get("/", AllUsers(env))    // GET /users
+		// Registration is the only way in without a session
 		r.Post("/", CreateUser(env)) // POST /users
 
-		r.Route("/{userID}", func(r chi.Router) {
-			r.Use(UserCtx(env))
-			r.Get("/", GetUser(env))       // GET /users/123
-			r.Put("/", UpdateUser(env))    // PUT /users/123
-			r.Delete("/", DeleteUser(env)) // DELETE /users/123
+		r.Group(func(r chi.Router) {
+			r.Use(Authenticate(env))
+			r.Get("/", AllUsers(env)) // GET /users
+
+			r.Route("/{userID}", func(r chi.Router) {
+				r.Use(UserCtx(env))
+				r.Get("/", GetUser(env))       // GET /users/123
+				r.Put("/", UpdateUser(env))    // PUT /users/123
+				r.Patch("/", PatchUser(env))   // PATCH /users/123
+				r.Delete("/", DeleteUser(env)) // DELETE /users/123
+
+				r.Get("/plan", GetUserPlan(env))     // GET /users/123/plan
+				r.Get("/payoff", GetUserPayoff(env)) // GET /users/123/payoff
+
+				r.Route("/accounts", func(r chi.Router) {
+					r.Get("/", UserAccounts(env))       // GET /users/123/accounts
+					r.Post("/", CreateUserAccount(env)) // POST /users/123/accounts
+
+					r.Route("/{accountID}", func(r chi.Router) {
+						r.Use(AccountCtx(env))
+						r.Get("/", GetUserAccount(env))       // GET /users/123/accounts/456
+						r.Put("/", UpdateUserAccount(env))    // PUT /users/123/accounts/456
+						r.Delete("/", DeleteUserAccount(env)) // DELETE /users/123/accounts/456
+					})
+				})
+			})
 		})
 	})
 
+	// Describe the API, including these two routes
+	r.Get("/openapi.json", OpenAPISpec(env, r)) // GET /openapi.json
+	r.Get("/docs", APIDocs(env))                // GET /docs
+
+	r.NotFound(NotFound(env))
 	r.MethodNotAllowed(MethodNotAllowed(env))
 
 	return r
@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/schedule"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// defaultScheduleMonths is how far ahead a schedule looks when no end date is asked for
+	defaultScheduleMonths = 3
+	// maxScheduleDays is the longest range a schedule can be asked for
+	maxScheduleDays = 731
+)
+
+// scheduleRange parses the from, to, adjust and holidays query parameters of a
+// schedule request. from defaults to today and to defaults to a few months after from.
+func scheduleRange(q url.Values) (time.Time, time.Time, schedule.Options, error) {
+	v := new(models.ValidationError)
+	from := schedule.Day(time.Now())
+
+	if param := q.Get("from"); param != "" {
+		date, err := schedule.ParseDate(param)
+		if err != nil {
+			v.Add("from", "date", "must be a date formatted as YYYY-MM-DD")
+		} else {
+			from = date
+		}
+	}
+
+	to := from.AddDate(0, defaultScheduleMonths, 0)
+	if param := q.Get("to"); param != "" {
+		date, err := schedule.ParseDate(param)
+		if err != nil {
+			v.Add("to", "date", "must be a date formatted as YYYY-MM-DD")
+		} else {
+			to = date
+		}
+	}
+
+	// The range is only checked once both ends of it are known
+	if len(v.Fields) == 0 {
+		if to.Before(from) {
+			v.Add("to", "range", "must not be before from")
+		} else if to.Sub(from) > maxScheduleDays*24*time.Hour {
+			v.Add("to", "range", fmt.Sprintf("must be within %d days of from", maxScheduleDays))
+		}
+	}
+
+	opts := scheduleOptions(q, v)
+
+	return from, to, opts, v.Err()
+}
+
+// scheduleOptions parses the adjust and holidays query parameters, which say what
+// happens to due dates on weekends and holidays, adding any errors to v
+func scheduleOptions(q url.Values, v *models.ValidationError) schedule.Options {
+	opts := schedule.Options{Adjustment: schedule.NextBusinessDay, Holidays: schedule.USFederal}
+
+	switch q.Get("adjust") {
+	case "", "next":
+	case "previous":
+		opts.Adjustment = schedule.PreviousBusinessDay
+	case "none":
+		opts.Adjustment = schedule.NoAdjustment
+	default:
+		v.Add("adjust", "oneOf", "must be one of next, previous or none")
+	}
+
+	switch q.Get("holidays") {
+	case "", "us":
+	case "none":
+		opts.Holidays = nil
+	default:
+		v.Add("holidays", "oneOf", "must be us or none")
+	}
+
+	return opts
+}
+
+// GetAccountSchedule gets the dates an account's payments are due on between the
+// from and to query parameters, moved off weekends and holidays
+func GetAccountSchedule(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		from, to, opts, err := scheduleRange(r.URL.Query())
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+
+		account, err := env.DB.GetAccount(r.Context(), accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		occurrences, err := schedule.Between(account, from, to, opts)
+		if err == schedule.ErrNoAnchor {
+			v := new(models.ValidationError)
+			v.Add("anchorDate", "required", "is required to schedule weekly, biweekly and yearly accounts")
+			respondInvalid(w, r, v)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		occurrencesJSON, _ := json.Marshal(occurrences)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(occurrencesJSON)
+	}
+}
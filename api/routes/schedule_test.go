@@ -0,0 +1,122 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetAccountSchedule(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			// May 10th 2020 is a Sunday, so that payment is due the Monday after
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule?from=2020-05-01&to=2020-07-31", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `[{"date":"2020-05-11","nominalDate":"2020-05-10","amount":100},{"date":"2020-06-10","nominalDate":"2020-06-10","amount":100},{"date":"2020-07-10","nominalDate":"2020-07-10","amount":100}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_PREVIOUS",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule?from=2020-05-01&to=2020-05-31&adjust=previous", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `[{"date":"2020-05-08","nominalDate":"2020-05-10","amount":100}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_EMPTY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule?from=2020-05-12&to=2020-05-31", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `[]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the query parameters aren't dates or options
+			name:           "BAD_QUERY",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule?from=today&to=2020-05-31&adjust=sideways", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"from","rule":"date","message":"must be a date formatted as YYYY-MM-DD"},{"field":"adjust","rule":"oneOf","message":"must be one of next, previous or none"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the range ends before it starts
+			name:           "BAD_RANGE",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule?from=2020-05-31&to=2020-05-01", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"to","rule":"range","message":"must not be before from"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because a weekly account can't be scheduled without an anchor date
+			name:           "NO_ANCHOR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/4/schedule?from=2020-05-01&to=2020-05-31", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "anchorDate", Rule: "required", Message: "is required to schedule weekly, biweekly and yearly accounts"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because account 2 belongs to a different user
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/2/schedule", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/schedule", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetAccountSchedule(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package routes
+
+import (
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// ContextTransaction is a wrapper for the string type to prevent reuse of context
+// types from 3rd party libraries
+type ContextTransaction string
+
+// TransactionCtx provides a context for all transaction routes to have access to the transaction ID
+func TransactionCtx(env *config.Env) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			transactionParam := chi.URLParam(r, "transactionID")
+			transactionID, err := strconv.Atoi(transactionParam)
+			if err != nil {
+				respondError(w, r, http.StatusBadRequest)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextTransaction("transactionID"), transactionID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// accountTransaction looks up a transaction and makes sure it belongs to the account,
+// returning models.ErrNotFound when it belongs to a different one
+func accountTransaction(ctx context.Context, env *config.Env, accountID int, transactionID int) (*models.Transaction, error) {
+	transaction, err := env.DB.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.AccountID != accountID {
+		return nil, models.ErrNotFound
+	}
+
+	return transaction, nil
+}
+
+// AllTransactions gets all Transaction records for the account in the URL
+func AllTransactions(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		_, err := env.DB.GetAccount(r.Context(), accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		transactions, err := env.DB.AccountTransactions(r.Context(), accountID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		transactionsJSON, _ := json.Marshal(transactions)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(transactionsJSON)
+	}
+}
+
+// GetTransaction gets a transaction from the database based on the Account and Transaction IDs in the URL
+func GetTransaction(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		transactionID, ok := ctx.Value(ContextTransaction("transactionID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		transaction, err := accountTransaction(r.Context(), env, accountID, transactionID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		transactionJSON, _ := json.Marshal(transaction)
+
+		// Send the found transaction JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(transactionJSON)
+		return
+	}
+}
+
+// CreateTransaction creates a transaction record against the account in the URL and returns that created record
+func CreateTransaction(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Read POST request body
+		newTransaction, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		// Read request body into Transaction object
+		var transaction models.Transaction
+		err = json.Unmarshal(newTransaction, &transaction)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		// The account in the URL always wins over the one in the body
+		transaction.AccountID = accountID
+
+		// Validate Transaction fields
+		err = transaction.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		_, err = env.DB.GetAccount(r.Context(), accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// Create Transaction in database
+		createdTransaction, err := env.DB.CreateTransaction(r.Context(), transaction)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		createdTransactionJSON, _ := json.Marshal(createdTransaction)
+
+		// Send the created transaction JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(createdTransactionJSON)
+		return
+	}
+}
+
+// UpdateTransaction updates a transaction record in the database, creating it if it doesn't exist
+func UpdateTransaction(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		transactionID, ok := ctx.Value(ContextTransaction("transactionID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Read PUT request body
+		editedTransaction, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		// Read request body into Transaction object
+		var newTransaction models.Transaction
+		err = json.Unmarshal(editedTransaction, &newTransaction)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		newTransaction.AccountID = accountID
+
+		// Validate Transaction fields
+		err = newTransaction.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		// Check if Transaction is already in database and if not, create it
+		_, err = accountTransaction(r.Context(), env, accountID, transactionID)
+		if err == models.ErrNotFound {
+			_, err = env.DB.GetAccount(r.Context(), accountID)
+			if err == models.ErrNotFound {
+				respondError(w, r, http.StatusNotFound)
+				return
+			} else if err != nil {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			_, err = env.DB.CreateTransaction(r.Context(), newTransaction)
+			if err != nil {
+				status := dbErrorStatus(err)
+				respondError(w, r, status)
+				return
+			}
+
+			// Send a Status Created response
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusCreated)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// Update transaction in database
+		err = env.DB.UpdateTransaction(r.Context(), transactionID, &newTransaction)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		// Send a Status No Content response
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
+
+// DeleteTransaction deletes a transaction record in the database
+func DeleteTransaction(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		transactionID, ok := ctx.Value(ContextTransaction("transactionID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		_, err := accountTransaction(r.Context(), env, accountID, transactionID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		err = env.DB.DeleteTransaction(r.Context(), transactionID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
+
+// GetAccountBalance derives the balance of the account in the URL from its transactions
+func GetAccountBalance(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		_, err := env.DB.GetAccount(r.Context(), accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		balance, err := env.DB.AccountBalance(r.Context(), accountID)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		balanceJSON, _ := json.Marshal(balance)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(balanceJSON)
+	}
+}
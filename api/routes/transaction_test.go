@@ -0,0 +1,448 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func (mdb *MockDB) AccountTransactions(ctx context.Context, accountID int) ([]*models.Transaction, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	transactions := make([]*models.Transaction, 0)
+	transactions = append(transactions, &models.Transaction{ID: 1, AccountID: 1, Amount: models.USD(72800), PostedDate: "2019-04-01", Payee: "Synchrony", Memo: "Phone", Status: models.StatusCleared})
+	transactions = append(transactions, &models.Transaction{ID: 3, AccountID: 1, Amount: models.USD(-10000), PostedDate: "2019-04-10", Payee: "Synchrony", Status: models.StatusPending})
+
+	return transactions, nil
+}
+
+func (mdb *MockDB) GetTransaction(ctx context.Context, transactionID int) (*models.Transaction, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	switch transactionID {
+	case 1:
+		return &models.Transaction{ID: 1, AccountID: 1, Amount: models.USD(72800), PostedDate: "2019-04-01", Payee: "Synchrony", Memo: "Phone", Status: models.StatusCleared}, nil
+	case 2:
+		return &models.Transaction{ID: 2, AccountID: 2, Amount: models.USD(1500), PostedDate: "2019-04-02", Payee: "Ford", Status: models.StatusCleared}, nil
+	}
+
+	return nil, models.ErrNotFound
+}
+
+func (mdb *MockDB) CreateTransaction(ctx context.Context, t models.Transaction) (*models.Transaction, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	t.ID = 4
+
+	return &t, nil
+}
+
+func (mdb *MockDB) UpdateTransaction(ctx context.Context, transactionID int, t *models.Transaction) error {
+	if mdb.dbErr {
+		return errors.New("Database error")
+	}
+
+	return nil
+}
+
+func (mdb *MockDB) DeleteTransaction(ctx context.Context, transactionID int) error {
+	if transactionID != 1 {
+		return models.ErrNotFound
+	}
+
+	if mdb.dbErr {
+		return errors.New("Database error")
+	}
+
+	return nil
+}
+
+func (mdb *MockDB) AccountBalance(ctx context.Context, accountID int) (*models.Balance, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	return &models.Balance{AccountID: accountID, Balance: models.USD(62800), Cleared: models.USD(72800), Pending: models.USD(-10000)}, nil
+}
+
+func (mdb *MockDB) ImportedIDs(ctx context.Context, accountID int) (map[string]bool, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	return map[string]bool{"fitid:OLD1": true}, nil
+}
+
+func (mdb *MockDB) ImportTransactions(ctx context.Context, accountID int, transactions []models.Transaction) ([]*models.Transaction, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	created := make([]*models.Transaction, 0, len(transactions))
+	for i := range transactions {
+		transaction := transactions[i]
+		transaction.ID = 10 + i
+		created = append(created, &transaction)
+	}
+
+	return created, nil
+}
+
+func TestAllTransactions(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `[{"ID":1,"accountID":1,"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"},{"ID":3,"accountID":1,"amount":-100,"postedDate":"2019-04-10","payee":"Synchrony","memo":"","status":"pending"}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because an account with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/3/transactions", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":1,"accountID":1,"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because transaction 2 belongs to account 2
+			name:           "OTHER_ACCOUNT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions/2", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because "test" is not an integer
+			name:           "BAD_REQUEST",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions/test", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions/1", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			// breaks the test because the handler is called without the route context
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/transactions/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetTransaction(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
+
+func TestCreateTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/transactions", bytes.NewBuffer([]byte(`{"amount":"-42.83","postedDate":"2019-04-10","payee":"Synchrony","memo":"April","status":"pending"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":4,"accountID":1,"amount":-42.83,"postedDate":"2019-04-10","payee":"Synchrony","memo":"April","status":"pending"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the request body is set to produce an error
+			name:           "BAD_REQUEST_IOUTIL",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/transactions", ErrReader(0)),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the "payee" key in the request body is not a string
+			name:           "BAD_REQUEST_UNMARSHAL",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/transactions", bytes.NewBuffer([]byte(`{"amount":"-42.83","postedDate":"2019-04-10","payee":123,"status":"pending"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the "postedDate" key in the request body is not a date
+			name:           "INVALID",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/transactions", bytes.NewBuffer([]byte(`{"amount":"-42.83","postedDate":"04/10/2019","payee":"Synchrony","status":"pending"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "postedDate", Rule: "date", Message: "must be a date formatted as YYYY-MM-DD"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because an account with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/3/transactions", bytes.NewBuffer([]byte(`{"amount":"-42.83","postedDate":"2019-04-10","payee":"Synchrony","status":"pending"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/accounts/1/transactions", bytes.NewBuffer([]byte(`{"amount":"-42.83","postedDate":"2019-04-10","payee":"Synchrony","status":"pending"}`))),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK_NO_CONTENT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1/transactions/1", bytes.NewBuffer([]byte(`{"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "OK_CREATED",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1/transactions/5", bytes.NewBuffer([]byte(`{"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			// breaks the test because the "status" key in the request body is not a valid status
+			name:           "INVALID",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1/transactions/1", bytes.NewBuffer([]byte(`{"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"bounced"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "status", Rule: "oneOf", Message: "must be pending or cleared"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because an account with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/3/transactions/5", bytes.NewBuffer([]byte(`{"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/accounts/1/transactions/1", bytes.NewBuffer([]byte(`{"amount":728,"postedDate":"2019-04-01","payee":"Synchrony","memo":"Phone","status":"cleared"}`))),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1/transactions/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because transaction 2 belongs to account 2
+			name:           "OTHER_ACCOUNT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1/transactions/2", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/accounts/1/transactions/1", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestGetAccountBalance(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/1/balance", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"accountID":1,"balance":628,"cleared":728,"pending":-100}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because an account with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/accounts/3/balance", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
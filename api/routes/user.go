@@ -10,21 +10,32 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi"
-	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
 // ContextUser is a wrapper for the string type to prevent reuse of context
 // types from 3rd party libraries
 type ContextUser string
 
-// UserCtx provides a context for all user routes to have access to that user ID
+// UserCtx provides a context for all user routes to have access to that user ID,
+// making sure it is the authenticated user
 func UserCtx(env *config.Env) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userParam := chi.URLParam(r, "userID")
 			userID, err := strconv.Atoi(userParam)
 			if err != nil {
-				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				respondError(w, r, http.StatusBadRequest)
+				return
+			}
+
+			// Callers can only see themselves; anyone else doesn't exist as far as they know
+			caller, ok := authUser(r)
+			if !ok {
+				respondError(w, r, http.StatusUnauthorized)
+				return
+			}
+			if caller.ID != userID {
+				respondError(w, r, http.StatusNotFound)
 				return
 			}
 
@@ -34,17 +45,37 @@ func UserCtx(env *config.Env) func(http.Handler) http.Handler {
 	}
 }
 
-// AllUsers gets all User records within the users tablein the database
+// AllUsers gets a page of the User records the caller can see, which is only their own,
+// filtered and sorted by the query parameters
 func AllUsers(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		users, err := env.DB.AllUsers()
+		caller, ok := authUser(r)
+		if !ok {
+			respondError(w, r, http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		opts, err := listOptions(q)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondBadQuery(w, r, err)
+			return
+		}
+
+		filter := models.UserFilter{ID: caller.ID, Email: q.Get("email")}
+		users, total, err := env.DB.ListUsers(r.Context(), filter, opts)
+		if _, invalid := err.(*models.ValidationError); invalid {
+			respondBadQuery(w, r, err)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
 			return
 		}
 
 		usersJSON, _ := json.Marshal(users)
 
+		setPageHeaders(w, r, opts, total)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(usersJSON)
 	}
@@ -56,16 +87,20 @@ func GetUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		ctx := r.Context()
 		userID, ok := ctx.Value(ContextUser("userID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
 			return
 		}
 
-		user, err := env.DB.GetUser(userID)
+		user, err := env.DB.GetUser(r.Context(), userID)
 		if err == models.ErrNotFound {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			respondError(w, r, http.StatusNotFound)
 			return
 		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if notModified(w, r, user.Version) {
 			return
 		}
 
@@ -78,41 +113,71 @@ func GetUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
-// CreateUser creates a user record in the database and returns that created record
+// registration is the body of a CreateUser request: a User plus the password
+// they will log in with
+type registration struct {
+	models.User
+	Password string `json:"password"`
+}
+
+// UnmarshalJSON decodes the password apart from the user, as the User's own
+// UnmarshalJSON would otherwise be used for the whole body and drop it
+func (reg *registration) UnmarshalJSON(b []byte) error {
+	var password struct {
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal(b, &password); err != nil {
+		return err
+	}
+
+	reg.Password = password.Password
+	return json.Unmarshal(b, &reg.User)
+}
+
+// Validate validates the user being registered along with their password
+func (reg *registration) Validate() error {
+	return reg.User.ValidateRegistration(reg.Password)
+}
+
+// CreateUser registers a new user in the database and returns that created record.
+// This is the only user route that doesn't need a session.
 func CreateUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Read POST request body
 		newUser, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
 
 		// Read request body into User object
-		var user models.User
-		err = json.Unmarshal(newUser, &user)
+		var reg registration
+		err = json.Unmarshal(newUser, &reg)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest)
 			return
 		}
+		user := reg.User
 
 		// Validate User fields
-		valid := user.Validate()
-		if valid != true {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		err = reg.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		err = user.SetPassword(reg.Password)
+		if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
 			return
 		}
 
 		// Create User in database
-		createdUser, err := env.DB.CreateUser(user)
-		if sqliteErr, ok := err.(sqlite3.Error); ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-				return
-			}
-		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		createdUser, err := env.DB.CreateUser(r.Context(), user)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
 			return
 		}
 
@@ -131,14 +196,14 @@ func UpdateUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		ctx := r.Context()
 		userID, ok := ctx.Value(ContextUser("userID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
 			return
 		}
 
 		// Read PUT request body
 		editedUser, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, bodyErrorStatus(err))
 			return
 		}
 		defer r.Body.Close()
@@ -147,28 +212,38 @@ func UpdateUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		var newUser models.User
 		err = json.Unmarshal(editedUser, &newUser)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			respondError(w, r, http.StatusBadRequest)
 			return
 		}
 
 		// Validate User fields
-		valid := newUser.Validate()
-		if !valid {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		err = newUser.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
 			return
 		}
 
 		// Check if User is already in database and if not, create it
-		_, err = env.DB.GetUser(userID)
-		if err == models.ErrNotFound {
-			_, err := env.DB.CreateUser(newUser)
-			if sqliteErr, ok := err.(sqlite3.Error); ok {
-				if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-					http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-					return
-				}
-			} else if err != nil {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		existing, err := env.DB.GetUser(r.Context(), userID)
+		if err != nil && err != models.ErrNotFound {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		current := 0
+		if existing != nil {
+			current = existing.Version
+		}
+		newUser.Version, ok = checkIfMatch(w, r, current)
+		if !ok {
+			return
+		}
+
+		if existing == nil {
+			_, err := env.DB.CreateUser(r.Context(), newUser)
+			if err != nil {
+				status := dbErrorStatus(err)
+				respondError(w, r, status)
 				return
 			}
 
@@ -178,17 +253,15 @@ func UpdateUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 		}
 
 		// Update user in database
-		err = env.DB.UpdateUser(userID, &newUser)
-		if sqliteErr, ok := err.(sqlite3.Error); ok {
-			if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-				http.Error(w, http.StatusText(http.StatusConflict), http.StatusConflict)
-				return
-			}
-		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		err = env.DB.UpdateUser(r.Context(), userID, &newUser)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
 			return
 		}
 
+		w.Header().Set("ETag", etag(existing.Version+1))
+
 		// Send a Status No Content response
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusNoContent)
@@ -196,22 +269,112 @@ func UpdateUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
-// DeleteUser deletes a user record in the database
-func DeleteUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
+// PatchUser applies a JSON Merge Patch or JSON Patch to a user record in the database,
+// writing only the fields that changed, and returns the patched record
+func PatchUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		userID, ok := ctx.Value(ContextUser("userID")).(int)
 		if !ok {
-			http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+			respondError(w, r, http.StatusUnprocessableEntity)
 			return
 		}
 
-		err := env.DB.DeleteUser(userID)
+		user, err := env.DB.GetUser(r.Context(), userID)
 		if err == models.ErrNotFound {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			respondError(w, r, http.StatusNotFound)
 			return
 		} else if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		version, ok := checkIfMatch(w, r, user.Version)
+		if !ok {
+			return
+		}
+
+		var patched models.User
+		err = readPatch(r, user, &patched)
+		if err != nil {
+			respondPatchError(w, r, err)
+			return
+		}
+
+		if patched.ID != user.ID {
+			respondInvalid(w, r, readOnlyError("ID"))
+			return
+		}
+
+		// Validate the patched User fields
+		err = patched.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		changes := user.Changes(&patched)
+		patched.Version = version
+		err = env.DB.PatchUser(r.Context(), userID, &patched, changes)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		// Patches that change nothing don't write, so the version stays the same
+		if len(changes) > 0 {
+			w.Header().Set("ETag", etag(user.Version+1))
+		} else {
+			w.Header().Set("ETag", etag(user.Version))
+		}
+
+		patchedJSON, _ := json.Marshal(patched)
+
+		// Send the patched user JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(patchedJSON)
+		return
+	}
+}
+
+// DeleteUser deletes a user record in the database
+func DeleteUser(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Deletes are only checked against the version when asked to be. The
+		// store checks it again as it deletes, in case of a write in between.
+		version := 0
+		if r.Header.Get("If-Match") != "" {
+			current := 0
+			user, err := env.DB.GetUser(r.Context(), userID)
+			if err == nil {
+				current = user.Version
+			} else if err != models.ErrNotFound {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			if version, ok = checkIfMatch(w, r, current); !ok {
+				return
+			}
+		}
+
+		err := env.DB.DeleteUser(r.Context(), userID, version)
+		if err != nil {
+			status := dbErrorStatus(err)
+			// A constraint failing here means the user's accounts couldn't be
+			// reassigned, which conflicts with the current state rather than the request
+			if status == http.StatusUnprocessableEntity {
+				status = http.StatusConflict
+			}
+			respondError(w, r, status)
 			return
 		}
 
@@ -0,0 +1,306 @@
+package routes
+
+import (
+	"dinero/api/config"
+	"dinero/api/models"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// UserAccounts gets a page of the Account records that belong to the user in the URL,
+// filtered and sorted by the query parameters
+func UserAccounts(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		_, err := env.DB.GetUser(r.Context(), userID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		q := r.URL.Query()
+		opts, err := listOptions(q)
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+		filter, err := accountFilter(q)
+		if err != nil {
+			respondBadQuery(w, r, err)
+			return
+		}
+		filter.UserID = userID
+
+		accounts, total, err := env.DB.ListAccounts(r.Context(), filter, opts)
+		if _, invalid := err.(*models.ValidationError); invalid {
+			respondBadQuery(w, r, err)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		accountsJSON, _ := json.Marshal(accounts)
+
+		setPageHeaders(w, r, opts, total)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(accountsJSON)
+	}
+}
+
+// GetUserAccount gets an account that belongs to the user in the URL and returns it
+func GetUserAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		account, err := env.DB.GetUserAccount(r.Context(), userID, accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if notModified(w, r, account.Version) {
+			return
+		}
+
+		accountJSON, _ := json.Marshal(account)
+
+		// Send the found account JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(accountJSON)
+		return
+	}
+}
+
+// CreateUserAccount creates an account record for the user in the URL and returns that created record
+func CreateUserAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Read POST request body
+		newAccount, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		// Read request body into Account object
+		var account models.Account
+		err = json.Unmarshal(newAccount, &account)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		// The user in the URL always wins over the one in the body
+		account.UserID = userID
+
+		// Validate Account fields
+		err = account.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		_, err = env.DB.GetUser(r.Context(), userID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// Create Account in database
+		createdAccount, err := env.DB.CreateAccount(r.Context(), account)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		createdAccountJSON, _ := json.Marshal(createdAccount)
+
+		// Send the created account JSON back in the response
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(createdAccountJSON)
+		return
+	}
+}
+
+// UpdateUserAccount updates an account that belongs to the user in the URL, creating it if
+// no account with that ID exists. An account owned by a different user is not found.
+func UpdateUserAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Read PUT request body
+		editedAccount, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			respondError(w, r, bodyErrorStatus(err))
+			return
+		}
+		defer r.Body.Close()
+
+		// Read request body into Account object
+		var newAccount models.Account
+		err = json.Unmarshal(editedAccount, &newAccount)
+		if err != nil {
+			respondError(w, r, http.StatusBadRequest)
+			return
+		}
+
+		newAccount.UserID = userID
+
+		// Validate Account fields
+		err = newAccount.Validate()
+		if err != nil {
+			respondInvalid(w, r, err)
+			return
+		}
+
+		existing, err := env.DB.GetAccount(r.Context(), accountID)
+		if err != nil && err != models.ErrNotFound {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// An account owned by a different user is as good as missing, so it
+		// can't be matched either
+		if existing != nil && existing.UserID != userID {
+			respondError(w, r, http.StatusNotFound)
+			return
+		}
+
+		current := 0
+		if existing != nil {
+			current = existing.Version
+		}
+		newAccount.Version, ok = checkIfMatch(w, r, current)
+		if !ok {
+			return
+		}
+
+		if existing == nil {
+			_, err = env.DB.GetUser(r.Context(), userID)
+			if err == models.ErrNotFound {
+				respondError(w, r, http.StatusNotFound)
+				return
+			} else if err != nil {
+				respondError(w, r, http.StatusInternalServerError)
+				return
+			}
+
+			_, err = env.DB.CreateAccount(r.Context(), newAccount)
+			if err != nil {
+				status := dbErrorStatus(err)
+				respondError(w, r, status)
+				return
+			}
+
+			// Send a Status Created response
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		// Update account in database
+		err = env.DB.UpdateAccount(r.Context(), accountID, &newAccount)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		w.Header().Set("ETag", etag(existing.Version+1))
+
+		// Send a Status No Content response
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
+
+// DeleteUserAccount deletes an account that belongs to the user in the URL
+func DeleteUserAccount(env *config.Env) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, ok := ctx.Value(ContextUser("userID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+		accountID, ok := ctx.Value(ContextAccount("accountID")).(int)
+		if !ok {
+			respondError(w, r, http.StatusUnprocessableEntity)
+			return
+		}
+
+		account, err := env.DB.GetUserAccount(r.Context(), userID, accountID)
+		if err == models.ErrNotFound {
+			respondError(w, r, http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		// Deletes are only checked against the version when asked to be, and
+		// the store checks it again as it deletes
+		version, ok := checkIfMatch(w, r, account.Version)
+		if !ok {
+			return
+		}
+
+		err = env.DB.DeleteAccount(r.Context(), accountID, version)
+		if err != nil {
+			status := dbErrorStatus(err)
+			respondError(w, r, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
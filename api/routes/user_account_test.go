@@ -0,0 +1,392 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"dinero/api/config"
+	"dinero/api/models"
+	"dinero/api/routes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func (mdb *MockDB) UserAccounts(ctx context.Context, userID int) ([]*models.Account, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	accounts := make([]*models.Account, 0)
+	if userID == 1 {
+		accounts = append(accounts, &models.Account{ID: 1, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), Currency: "USD", DueDate: "10", URL: "https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"})
+	}
+
+	return accounts, nil
+}
+
+func (mdb *MockDB) GetUserAccount(ctx context.Context, userID int, accountID int) (*models.Account, error) {
+	if userID != 1 || accountID != 1 {
+		return nil, models.ErrNotFound
+	}
+
+	return mdb.GetAccount(ctx, accountID)
+}
+
+func TestUserAccounts(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `[{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":""},{"ID":3,"userID":1,"name":"Groceries","accountType":"weekly","minimumPayment":150,"currentPayment":150,"fullAmount":150,"currency":"USD","apr":0,"dueDate":"5","anchorDate":"","URL":""}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because a user with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/3/accounts", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestGetUserAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because account 2 exists but belongs to user 2
+			name:           "OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts/2", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because "test" is not an integer
+			name:           "BAD_REQUEST",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts/test", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			// breaks the test because the handler is called without the route context
+			name:           "CTX_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "NOT_MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil), "If-None-Match", `W/"3"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "",
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			// the account has changed since version 2, so it's sent again
+			name:           "MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/users/1/accounts/1", nil), "If-None-Match", `"2"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":1,"userID":1,"name":"Phone Payment","accountType":"monthly","minimumPayment":42.83,"currentPayment":100,"fullAmount":728,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"https://www.synchronycredit.com/eService/AccountSummary/initiateAccSummaryAction.action"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.name == "CTX_ERR" {
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetUserAccount(test.env))).ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			} else {
+				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
+				r.ServeHTTP(test.rec, test.req)
+
+				RunTest(&test, t)
+			}
+		})
+	}
+}
+
+func TestCreateUserAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			// the userID in the body is ignored in favour of the one in the URL
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/users/1/accounts", bytes.NewBuffer([]byte(`{"userID":99,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   `{"ID":1,"userID":1,"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","apr":0,"dueDate":"10","anchorDate":"","URL":"ford.com"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because a user with the ID of 3 is not being found
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/users/3/accounts", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the "accountType" key in the request body is not a valid account type
+			name:           "INVALID",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/users/1/accounts", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"bad","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "accountType", Rule: "oneOf", Message: "must be one of daily, weekly, biweekly, monthly or yearly"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the "name" key in the request body ("Already here") is set to cause a conflict
+			name:           "SQLITE_CONFLICT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/users/1/accounts", bytes.NewBuffer([]byte(`{"name":"Already here","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestUpdateUserAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK_NO_CONTENT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1/accounts/1", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "OK_CREATED",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1/accounts/3", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			// breaks the test because account 2 exists but belongs to user 2
+			name:           "OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1/accounts/2", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because a user with the ID of 3 is not being found
+			name:           "NOT_FOUND_CREATED",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/3/accounts/3", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1/accounts/1", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/users/1/accounts/1", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", `"3"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the account is at version 3, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/users/1/accounts/1", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", `"2"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			// breaks the test because If-Match can't match an account that doesn't exist
+			name:           "PRECONDITION_FAILED_MISSING",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/users/1/accounts/3", bytes.NewBuffer([]byte(`{"name":"Car Payment","accountType":"monthly","minimumPayment":217.99,"currentPayment":217.99,"fullAmount":21000,"currency":"USD","dueDate":"10","URL":"ford.com"}`))), "If-Match", "*"),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
+func TestDeleteUserAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/1/accounts/1", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because account 2 exists but belongs to user 2
+			name:           "OTHER_USER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/1/accounts/2", nil),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/1/accounts/1", nil),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("DELETE", "/api/v1/users/1/accounts/1", nil), "If-Match", `"3"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the account is at version 3, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("DELETE", "/api/v1/users/1/accounts/1", nil), "If-Match", `"2"`),
+			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
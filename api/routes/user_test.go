@@ -2,32 +2,30 @@ package routes_test
 
 import (
 	"bytes"
+	"context"
 	"dinero/api/config"
 	"dinero/api/models"
 	"dinero/api/routes"
 	"errors"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
-func (mdb *MockDB) AllUsers() ([]*models.User, error) {
+func (mdb *MockDB) AllUsers(ctx context.Context) ([]*models.User, error) {
 	if mdb.dbErr {
 		return nil, errors.New("Database error")
 	}
 
 	users := make([]*models.User, 0)
-	users = append(users, &models.User{ID: 1, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: 1860.00})
-	users = append(users, &models.User{ID: 2, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: 1400.00})
+	users = append(users, &models.User{ID: 1, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: models.USD(186000), Currency: "USD"})
+	users = append(users, &models.User{ID: 2, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD"})
 
 	return users, nil
 }
 
-func (mdb *MockDB) GetUser(userID int) (*models.User, error) {
-	if userID != 1 {
+func (mdb *MockDB) GetUser(ctx context.Context, userID int) (*models.User, error) {
+	if userID != 1 && userID != 2 {
 		return nil, models.ErrNotFound
 	}
 
@@ -35,44 +33,47 @@ func (mdb *MockDB) GetUser(userID int) (*models.User, error) {
 		return nil, errors.New("Database error")
 	}
 
-	user := &models.User{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: 1400.00}
+	// User 2 hasn't said when they get paid
+	if userID == 2 {
+		return &models.User{ID: 2, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: models.USD(186099), Currency: "USD", Version: 1}, nil
+	}
+
+	user := &models.User{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD", Payday: "2020-01-03", Version: 3}
 
 	return user, nil
 }
 
-func (mdb *MockDB) CreateUser(u models.User) (*models.User, error) {
+func (mdb *MockDB) CreateUser(ctx context.Context, u models.User) (*models.User, error) {
 	if mdb.dbErr {
 		return nil, errors.New("Database error")
 	}
 
-	if u.Email == "already-here@gmail.com" {
-		return nil, sqlite3.Error{
-			Code:         sqlite3.ErrConstraint,
-			ExtendedCode: sqlite3.ErrConstraintUnique,
-		}
-	}
-
-	user := &models.User{ID: 1, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: 1860.99}
+	user := &models.User{ID: 1, FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com", BiweeklyIncome: models.USD(186099), Currency: "USD"}
 
 	return user, nil
 }
 
-func (mdb *MockDB) UpdateUser(userID int, u *models.User) error {
+func (mdb *MockDB) UpdateUser(ctx context.Context, userID int, u *models.User) error {
 	if mdb.dbErr {
 		return errors.New("Database error")
 	}
 
-	if u.Email == "already-here@gmail.com" {
-		return sqlite3.Error{
-			Code:         sqlite3.ErrConstraint,
-			ExtendedCode: sqlite3.ErrConstraintUnique,
-		}
+	return nil
+}
+
+func (mdb *MockDB) PatchUser(ctx context.Context, userID int, u *models.User, fields []string) error {
+	if mdb.dbErr {
+		return errors.New("Database error")
 	}
 
 	return nil
 }
 
-func (mdb *MockDB) DeleteUser(userID int) error {
+func (mdb *MockDB) DeleteUser(ctx context.Context, userID int, version int) error {
+	if userID == 2 {
+		return models.ErrHasDependents
+	}
+
 	if userID != 1 {
 		return models.ErrNotFound
 	}
@@ -84,6 +85,27 @@ func (mdb *MockDB) DeleteUser(userID int) error {
 	return nil
 }
 
+func (mdb *MockDB) ListUsers(ctx context.Context, filter models.UserFilter, opts models.ListOptions) ([]*models.User, int, error) {
+	for _, field := range opts.Sort {
+		if field.Field != "email" {
+			return nil, 0, &models.ValidationError{Fields: []models.FieldError{{Field: "sort", Rule: "oneOf", Message: "cannot sort by " + field.Field}}}
+		}
+	}
+
+	user, err := mdb.GetUser(ctx, filter.ID)
+	if err == models.ErrNotFound {
+		return []*models.User{}, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Email != "" && user.Email != filter.Email {
+		return []*models.User{}, 0, nil
+	}
+
+	return []*models.User{user}, 1, nil
+}
+
 func TestAllUsers(t *testing.T) {
 	t.Parallel()
 
@@ -91,30 +113,50 @@ func TestAllUsers(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `[{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860},{"ID":2,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400}]`,
+			req:            httptest.NewRequest("GET", "/api/v1/users", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}]`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// returns nothing because the caller has a different email
+			name:           "OK_FILTER",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users?email=ide.johnc@gmail.com", nil),
+			env:            seededEnv(t),
+			expectedBody:   `[]`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
+		{
+			// breaks the test because users can't be sorted by "password"
+			name:           "BAD_SORT",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("GET", "/api/v1/users?sort=password", nil),
+			env:            seededEnv(t),
+			expectedBody:   `{"error":{"code":"invalid_query","message":"One or more query parameters are invalid","details":[{"field":"sort","rule":"oneOf","message":"cannot sort by password"}],"requestID":"test-request"}}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/users", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/users", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users", nil),
+			req:            httptest.NewRequest("GET", "/api/v1/users", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 	}
@@ -122,6 +164,8 @@ func TestAllUsers(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
 			r.ServeHTTP(test.rec, test.req)
 
 			RunTest(&test, t)
@@ -136,9 +180,9 @@ func TestGetUser(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400}`,
+			req:            httptest.NewRequest("GET", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
@@ -146,62 +190,84 @@ func TestGetUser(t *testing.T) {
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because a user with the ID of 3 is not being found
 			name:           "NOT_FOUND",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users/3", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusNotFound)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("GET", "/api/v1/users/3", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusNotFound,
 		},
 		{
 			// breaks the test because "test" is not an integer
 			name:           "BAD_REQUEST",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users/test", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("GET", "/api/v1/users/test", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users/1", nil),
+			req:            httptest.NewRequest("GET", "/api/v1/users/1", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("GET", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("GET", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			name:           "NOT_MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/users/1", nil), "If-None-Match", `"1"`),
+			env:            seededEnv(t),
+			expectedBody:   "",
+			expectedHeader: "",
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			// the tag isn't the user's version, so they're sent again
+			name:           "MODIFIED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("GET", "/api/v1/users/1", nil), "If-None-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.GetUser(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.GetUser(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
@@ -217,9 +283,9 @@ func TestCreateUser(t *testing.T) {
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   `{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`,
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"john.ide@gmail.com","biweeklyIncome":1860.99,"currency":"USD","password":"correct horse"}`))),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":3,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"john.ide@gmail.com","biweeklyIncome":1860.99,"currency":"USD","payday":""}`,
 			expectedHeader: "application/json",
 			expectedStatus: http.StatusOK,
 		},
@@ -227,60 +293,70 @@ func TestCreateUser(t *testing.T) {
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/users", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/users", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the request body is set to produce an error
 			name:           "BAD_REQUEST_IOUTIL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", ErrReader(0)),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/users", ErrReader(0)),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "firstName" key in the request body is not a string
 			name:           "BAD_REQUEST_UNMARSHAL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", bytes.NewBuffer([]byte(`{"firstName":123,"lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":123,"lastName":"Ide","fullName":"John Ide","email":"john.ide@gmail.com","biweeklyIncome":1860.99,"currency":"USD","password":"correct horse"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "email" key in the request body is not a valid email
 			name:           "INVALID",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"invalid.email","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"invalid.email","biweeklyIncome":1860.99,"currency":"USD","password":"correct horse"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "email", Rule: "email", Message: "must be a valid email address"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the password is shorter than 8 characters
+			name:           "SHORT_PASSWORD",
+			rec:            httptest.NewRecorder(),
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"john.ide@gmail.com","biweeklyIncome":1860.99,"currency":"USD","password":"hunter2"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "password", Rule: "minLength", Message: "must be at least 8 characters"}),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
 		{
-			// breaks the test because the "email" key in the request body ("already-here@gmail.com") is set to cause a conflict
+			// breaks the test because the "email" key in the request body ("lptoth55@gmail.com") is already Luke's
 			name:           "SQLITE_CONFLICT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"already-here@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"lptoth55@gmail.com","biweeklyIncome":1860.99,"currency":"USD","password":"correct horse"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusConflict,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("POST", "/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
+			req:            httptest.NewRequest("POST", "/api/v1/users", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"john.ide@gmail.com","biweeklyIncome":1860.99,"currency":"USD","password":"correct horse"}`))),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 	}
@@ -288,6 +364,8 @@ func TestCreateUser(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
 			r.ServeHTTP(test.rec, test.req)
 
 			RunTest(&test, t)
@@ -302,120 +380,123 @@ func TestUpdateUser(t *testing.T) {
 		{
 			name:           "OK_NO_CONTENT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))),
+			env:            seededEnv(t),
 			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
 			expectedStatus: http.StatusNoContent,
 		},
 		{
-			name:           "OK_CREATED",
+			// breaks the test because callers can only update themselves
+			name:           "NOT_FOUND",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/3", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   "",
-			expectedHeader: "text/plain; charset=utf-8",
-			expectedStatus: http.StatusCreated,
+			req:            httptest.NewRequest("PUT", "/api/v1/users/3", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
 		},
 		{
 			// breaks the test because the BAD method is not allowed
 			name:           "BAD_METHOD",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("BAD", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusMethodNotAllowed)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("BAD", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
 		{
 			// breaks the test because the request body is set to produce an error
 			name:           "BAD_REQUEST_IOUTIL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", ErrReader(0)),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", ErrReader(0)),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "firstName" key in the request body is not a string
 			name:           "BAD_REQUEST_UNMARSHAL",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte(`{"firstName":123,"lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusBadRequest)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"firstName":123,"lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusBadRequest),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			// breaks the test because the "email" key in the request body is not a valid email
 			name:           "INVALID",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"invalid.email","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"invalid.email","biweeklyIncome":1500,"currency":"USD"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "email", Rule: "email", Message: "must be a valid email address"}),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
 		{
-			// breaks the test because the "email" key in the request body ("already-here@gmail.com") is set to cause a conflict
+			// breaks the test because the "email" key in the request body ("ide.johnc@gmail.com") is already John's
 			name:           "SQLITE_CONFLICT",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"already-here@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
-			expectedStatus: http.StatusConflict,
-		},
-		{
-			// breaks the test because the "email" key in the request body ("already-here@gmail.com") is set to cause a conflict
-			name:           "SQLITE_CONFLICT_CREATED",
-			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/3", bytes.NewBuffer([]byte(`{"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"already-here@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusConflict)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"ide.johnc@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusConflict,
 		},
 		{
 			// breaks the test because the env.DB is set to have a dbErr
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
-			// breaks the test because the env.DB is set to have a dbErr
-			name:           "DB_ERR_CREATED",
+			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/3", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"John","lastName":"Ide","fullName":"John Ide","email":"ide.johnc@gmail.com","biweeklyIncome":1860.99}`))),
-			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
-			expectedStatus: http.StatusInternalServerError,
+			req:            httptest.NewRequest("PUT", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
 		},
 		{
-			name:           "CTX_ERR",
+			name:           "OK_IF_MATCH",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("PUT", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))), "If-Match", `"1"`),
+			env:            seededEnv(t),
+			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
-			expectedStatus: http.StatusUnprocessableEntity,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the user is at version 1, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("PUT", "/api/v1/users/1", bytes.NewBuffer([]byte(`{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD"}`))), "If-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.UpdateUser(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.UpdateUser(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
@@ -424,15 +505,130 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+func TestPatchUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []TestCase{
+		{
+			name:           "OK_MERGE_PATCH",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `{"biweeklyIncome":"1500.00","currency":"USD"}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1500,"currency":"USD","payday":"2020-01-03"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "OK_JSON_PATCH",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `[{"op":"copy","from":"/firstName","path":"/fullName"}]`, "application/json-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because callers can only patch themselves
+			name:           "NOT_FOUND",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/3", `{"firstName":"Jon"}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// breaks the test because a user's ID can't be changed
+			name:           "READ_ONLY",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `[{"op":"replace","path":"/ID","value":2}]`, "application/json-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "ID", Rule: "readOnly", Message: "cannot be changed"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the patched email is not a valid email
+			name:           "INVALID",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `{"email":"invalid.email"}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity, models.FieldError{Field: "email", Rule: "email", Message: "must be a valid email address"}),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			// breaks the test because the "email" key in the patch ("ide.johnc@gmail.com") is already John's
+			name:           "SQLITE_CONFLICT",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `{"email":"ide.johnc@gmail.com"}`, "application/merge-patch+json"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			// breaks the test because the env.DB is set to have a dbErr
+			name:           "DB_ERR",
+			rec:            httptest.NewRecorder(),
+			req:            patchRequest("/api/v1/users/1", `{"firstName":"Jon"}`, "application/merge-patch+json"),
+			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(patchRequest("/api/v1/users/1", `{"biweeklyIncome":1400,"currency":"USD"}`, "application/merge-patch+json"), "If-Match", "*"),
+			env:            seededEnv(t),
+			expectedBody:   `{"ID":1,"firstName":"Luke","lastName":"Toth","fullName":"Luke Toth","email":"lptoth55@gmail.com","biweeklyIncome":1400,"currency":"USD","payday":"2020-01-03"}`,
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			// breaks the test because the user is at version 1, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(patchRequest("/api/v1/users/1", `{"biweeklyIncome":1500,"currency":"USD"}`, "application/merge-patch+json"), "If-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := routes.NewRouter(test.env)
+			prepare(test.req)
+			authorize(test.req)
+			r.ServeHTTP(test.rec, test.req)
+
+			RunTest(&test, t)
+		})
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	t.Parallel()
 
+	// accountless is a seeded store without John's Gym account, so there's
+	// nothing to stop John being deleted
+	accountless := func() *config.Env {
+		env := seededEnv(t)
+		if err := env.DB.DeleteAccount(context.Background(), 2, 0); err != nil {
+			t.Fatal(err)
+		}
+		return env
+	}
+
 	tests := []TestCase{
 		{
 			name:           "OK",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
+			req:            withToken(httptest.NewRequest("DELETE", "/api/v1/users/2", nil), "user-2-token"),
+			env:            accountless(),
 			expectedBody:   "",
 			expectedHeader: "text/plain; charset=utf-8",
 			expectedStatus: http.StatusNoContent,
@@ -440,40 +636,72 @@ func TestDeleteUser(t *testing.T) {
 		{
 			name:           "NOT_FOUND",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/users/3", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusNotFound)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/3", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			// breaks the test because user 2 still has accounts under the restrict policy
+			name:           "HAS_DEPENDENTS",
+			rec:            httptest.NewRecorder(),
+			req:            withToken(httptest.NewRequest("DELETE", "/api/v1/users/2", nil), "user-2-token"),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusConflict),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusConflict,
+		},
 		{
 			name:           "DB_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/users/1", nil),
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/1", nil),
 			env:            &config.Env{DB: &MockDB{dbErr: true}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusInternalServerError)),
-			expectedHeader: "text/plain; charset=utf-8",
+			expectedBody:   errorJSON(http.StatusInternalServerError),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusInternalServerError,
 		},
 		{
 			name:           "CTX_ERR",
 			rec:            httptest.NewRecorder(),
-			req:            httptest.NewRequest("DELETE", "/users/1", nil),
-			env:            &config.Env{DB: &MockDB{}, Log: config.Log},
-			expectedBody:   fmt.Sprintf("%s\n", http.StatusText(http.StatusUnprocessableEntity)),
-			expectedHeader: "text/plain; charset=utf-8",
+			req:            httptest.NewRequest("DELETE", "/api/v1/users/1", nil),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusUnprocessableEntity),
+			expectedHeader: "application/json",
 			expectedStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			name:           "OK_IF_MATCH",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(withToken(httptest.NewRequest("DELETE", "/api/v1/users/2", nil), "user-2-token"), "If-Match", `"1"`),
+			env:            accountless(),
+			expectedBody:   "",
+			expectedHeader: "text/plain; charset=utf-8",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			// breaks the test because the user is at version 1, not 2
+			name:           "PRECONDITION_FAILED",
+			rec:            httptest.NewRecorder(),
+			req:            withHeader(httptest.NewRequest("DELETE", "/api/v1/users/1", nil), "If-Match", `"2"`),
+			env:            seededEnv(t),
+			expectedBody:   errorJSON(http.StatusPreconditionFailed),
+			expectedHeader: "application/json",
+			expectedStatus: http.StatusPreconditionFailed,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "CTX_ERR" {
-				http.HandlerFunc(routes.DeleteUser(test.env)).ServeHTTP(test.rec, test.req)
+				prepare(test.req)
+				routes.RequestID(http.HandlerFunc(routes.DeleteUser(test.env))).ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
 			} else {
 				r := routes.NewRouter(test.env)
+				prepare(test.req)
+				authorize(test.req)
 				r.ServeHTTP(test.rec, test.req)
 
 				RunTest(&test, t)
@@ -481,3 +709,50 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+// mockBackup is the backup MockDB exports for user 1
+func mockBackup() *models.Backup {
+	return &models.Backup{
+		Version:      models.BackupVersion,
+		ExportedAt:   "2020-01-01T00:00:00Z",
+		Users:        []*models.User{{ID: 1, FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com", BiweeklyIncome: models.USD(140000), Currency: "USD", Payday: "2020-01-03"}},
+		Accounts:     []*models.Account{{ID: 1, UserID: 1, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), Currency: "USD", DueDate: "10"}},
+		Transactions: []*models.Transaction{{ID: 1, AccountID: 1, Amount: models.USD(72800), PostedDate: "2019-04-01", Payee: "Synchrony", Memo: "Phone", Status: models.StatusCleared}},
+	}
+}
+
+func (mdb *MockDB) Export(ctx context.Context, userID int) (*models.Backup, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	return mockBackup(), nil
+}
+
+func (mdb *MockDB) ExportAll(ctx context.Context) (*models.Backup, error) {
+	return mdb.Export(ctx, 0)
+}
+
+func (mdb *MockDB) Restore(ctx context.Context, b *models.Backup, mode string) (*models.RestoreReport, error) {
+	if mdb.dbErr {
+		return nil, errors.New("Database error")
+	}
+
+	report := &models.RestoreReport{Conflicts: make([]models.Conflict, 0)}
+	report.Unchanged.Users = len(b.Users)
+
+	// Account 1 exists under another name, so restoring it conflicts
+	for _, account := range b.Accounts {
+		if account.ID == 1 && account.Name != "Phone Payment" {
+			report.Conflicts = append(report.Conflicts, models.Conflict{Table: "accounts", ID: 1, Reason: "differs in name"})
+			if mode == models.ConflictFail {
+				return report, models.ErrRestoreConflict
+			}
+		} else {
+			report.Created.Accounts++
+		}
+	}
+	report.Created.Transactions = len(b.Transactions)
+
+	return report, nil
+}
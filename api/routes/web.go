@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dinero/api/config"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// hashedAsset matches the names the UI build gives files whose content never
+// changes, with a hash of the content before the extension like app.3f2a1b9c.js
+var hashedAsset = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[0-9a-z]+$`)
+
+// precompressed are the encodings the UI build compresses files with ahead of
+// time, as name.br and name.gz, in the order they're preferred
+var precompressed = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// WebUI serves the single page app in dist. Paths that aren't files get
+// index.html so the app can route them itself, except for paths under /api/
+// and paths with an extension, which are missing files. Hashed assets can be
+// cached for good, while index.html has to be revalidated so a new build is
+// picked up.
+func WebUI(env *config.Env, dist fs.FS) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+			respondError(w, r, http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			respondError(w, r, http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || !isFile(dist, name) {
+			if path.Ext(name) != "" {
+				respondError(w, r, http.StatusNotFound)
+				return
+			}
+			name = "index.html"
+		}
+
+		if hashedAsset.MatchString(name) {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+
+		// Serve a precompressed copy when the client takes its encoding. The
+		// response varies by Accept-Encoding either way, since another client
+		// could be sent a compressed copy.
+		w.Header().Add("Vary", "Accept-Encoding")
+		file, encoding := name, ""
+		for _, p := range precompressed {
+			if acceptsEncoding(r.Header.Get("Accept-Encoding"), p.encoding) && isFile(dist, name+p.extension) {
+				file, encoding = name+p.extension, p.encoding
+				break
+			}
+		}
+
+		content, err := fs.ReadFile(dist, file)
+		if err != nil {
+			env.Log.WithError(err).WithField("file", file).Error("Failed to read the web UI")
+			respondError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		sum := sha256.Sum256(content)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+
+		// ServeContent picks the Content-Type from the extension of name rather
+		// than the compressed file, and answers If-None-Match with the ETag
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+	}
+}
+
+// isFile reports whether name is a regular file in dist
+func isFile(dist fs.FS, name string) bool {
+	info, err := fs.Stat(dist, name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding:
+// naming it without q=0, or else allowing * without q=0
+func acceptsEncoding(header string, encoding string) bool {
+	star := false
+	for _, candidate := range strings.Split(header, ",") {
+		parts := strings.Split(candidate, ";")
+		accepted := true
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if q := strings.TrimPrefix(param, "q="); q != param {
+				accepted = strings.Trim(q, "0.") != ""
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case encoding:
+			return accepted
+		case "*":
+			star = accepted
+		}
+	}
+
+	return star
+}
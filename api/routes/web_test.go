@@ -0,0 +1,196 @@
+package routes_test
+
+import (
+	"dinero/api/config"
+	"dinero/api/routes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// dist is a built web UI with a hashed asset compressed both ways, and one
+// that isn't hashed or compressed
+var dist = fstest.MapFS{
+	"index.html":                {Data: []byte("<!DOCTYPE html>index")},
+	"index.html.gz":             {Data: []byte("gzipped index")},
+	"assets/app.3f2a1b9c.js":    {Data: []byte("app")},
+	"assets/app.3f2a1b9c.js.br": {Data: []byte("brotli app")},
+	"assets/app.3f2a1b9c.js.gz": {Data: []byte("gzipped app")},
+	"favicon.ico":               {Data: []byte("icon")},
+}
+
+func TestWebUI(t *testing.T) {
+	t.Parallel()
+
+	env := &config.Env{DB: &MockDB{}, Log: config.Log}
+
+	tests := []struct {
+		name                 string
+		req                  *http.Request
+		expectedBody         string
+		expectedStatus       int
+		expectedType         string
+		expectedEncoding     string
+		expectedCacheControl string
+	}{
+		{
+			name:                 "INDEX",
+			req:                  httptest.NewRequest("GET", "/", nil),
+			expectedBody:         "<!DOCTYPE html>index",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/html; charset=utf-8",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			// the app routes paths that aren't files itself
+			name:                 "HISTORY_FALLBACK",
+			req:                  httptest.NewRequest("GET", "/accounts/123", nil),
+			expectedBody:         "<!DOCTYPE html>index",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/html; charset=utf-8",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			name:                 "INDEX_GZIP",
+			req:                  withHeader(httptest.NewRequest("GET", "/login", nil), "Accept-Encoding", "gzip, br"),
+			expectedBody:         "gzipped index",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/html; charset=utf-8",
+			expectedEncoding:     "gzip",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			name:                 "HASHED_ASSET",
+			req:                  httptest.NewRequest("GET", "/assets/app.3f2a1b9c.js", nil),
+			expectedBody:         "app",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/javascript; charset=utf-8",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			// brotli is preferred when both are accepted
+			name:                 "HASHED_ASSET_BROTLI",
+			req:                  withHeader(httptest.NewRequest("GET", "/assets/app.3f2a1b9c.js", nil), "Accept-Encoding", "gzip, deflate, br"),
+			expectedBody:         "brotli app",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/javascript; charset=utf-8",
+			expectedEncoding:     "br",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:                 "HASHED_ASSET_BROTLI_REFUSED",
+			req:                  withHeader(httptest.NewRequest("GET", "/assets/app.3f2a1b9c.js", nil), "Accept-Encoding", "br;q=0, *"),
+			expectedBody:         "gzipped app",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "text/javascript; charset=utf-8",
+			expectedEncoding:     "gzip",
+			expectedCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:                 "UNHASHED_ASSET",
+			req:                  withHeader(httptest.NewRequest("GET", "/favicon.ico", nil), "Accept-Encoding", "gzip"),
+			expectedBody:         "icon",
+			expectedStatus:       http.StatusOK,
+			expectedType:         "image/vnd.microsoft.icon",
+			expectedCacheControl: "no-cache",
+		},
+		{
+			// a missing file isn't answered with the app
+			name:           "MISSING_ASSET",
+			req:            httptest.NewRequest("GET", "/assets/app.00000000.js", nil),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "application/json",
+		},
+		{
+			name:           "API_VERSION",
+			req:            httptest.NewRequest("GET", "/api/v2/accounts", nil),
+			expectedBody:   errorJSON(http.StatusNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "application/json",
+		},
+		{
+			name:           "BAD_METHOD",
+			req:            httptest.NewRequest("POST", "/accounts", nil),
+			expectedBody:   errorJSON(http.StatusMethodNotAllowed),
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedType:   "application/json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			prepare(test.req)
+			routes.RequestID(http.HandlerFunc(routes.WebUI(env, dist))).ServeHTTP(rec, test.req)
+
+			if rec.Code != test.expectedStatus {
+				t.Errorf("\nCode:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, test.expectedStatus)
+			}
+			if got := rec.Body.String(); got != test.expectedBody {
+				t.Errorf("\nBody:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expectedBody)
+			}
+			if got := rec.Header().Get("Content-Type"); got != test.expectedType {
+				t.Errorf("\nContent-Type:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expectedType)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != test.expectedEncoding {
+				t.Errorf("\nContent-Encoding:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expectedEncoding)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != test.expectedCacheControl {
+				t.Errorf("\nCache-Control:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expectedCacheControl)
+			}
+			if test.expectedStatus == http.StatusOK && rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("\nVary:\n\tGot: \t\t%s\n\tExpected: \t%s\n", rec.Header().Get("Vary"), "Accept-Encoding")
+			}
+		})
+	}
+}
+
+// TestWebUIRevalidates checks index.html can be revalidated with its ETag
+func TestWebUIRevalidates(t *testing.T) {
+	t.Parallel()
+
+	env := &config.Env{DB: &MockDB{}, Log: config.Log}
+	handler := http.HandlerFunc(routes.WebUI(env, dist))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	tag := rec.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("\nETag:\n\tGot: \t\tnone\n\tExpected: \ta tag\n")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withHeader(httptest.NewRequest("GET", "/", nil), "If-None-Match", tag))
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("\nCode:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, http.StatusNotModified)
+	}
+
+	// the gzipped copy is a different representation, so it has another tag
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withHeader(withHeader(httptest.NewRequest("GET", "/", nil), "If-None-Match", tag), "Accept-Encoding", "gzip"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("\nGzipped code:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, http.StatusOK)
+	}
+}
+
+// TestRouterServesWebUI checks NewRouter serves the embedded web UI next to the API
+func TestRouterServesWebUI(t *testing.T) {
+	t.Parallel()
+
+	r := routes.NewRouter(&config.Env{DB: &MockDB{}, Log: config.Log})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/accounts", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/assets/app.") {
+		t.Errorf("\nWeb UI:\n\tGot: \t\t%d %s\n\tExpected: \t%d and the app's index.html\n", rec.Code, rec.Body.String(), http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/accounts", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("\nAPI:\n\tGot: \t\t%d\n\tExpected: \t%d\n", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,82 @@
+package schedule
+
+import "time"
+
+// Calendar reports which days are holidays
+type Calendar interface {
+	IsHoliday(day time.Time) bool
+}
+
+// USFederal is the calendar of United States federal holidays, on the days they
+// are observed. Holidays that fall on a Saturday are observed the Friday before and
+// ones that fall on a Sunday the Monday after.
+var USFederal Calendar = usFederal{}
+
+type usFederal struct{}
+
+// IsHoliday reports whether day is an observed federal holiday
+func (usFederal) IsHoliday(day time.Time) bool {
+	day = Day(day)
+
+	// New Year's Day can be observed on December 31st of the year before
+	for _, year := range []int{day.Year(), day.Year() + 1} {
+		for _, holiday := range usFederalHolidays(year) {
+			if holiday.Equal(day) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// usFederalHolidays lists the observed federal holidays of a year
+func usFederalHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		observed(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)),   // New Year's Day
+		nthWeekday(year, time.January, time.Monday, 3),                     // Birthday of Martin Luther King, Jr.
+		nthWeekday(year, time.February, time.Monday, 3),                    // Washington's Birthday
+		lastWeekday(year, time.May, time.Monday),                           // Memorial Day
+		observed(time.Date(year, time.July, 4, 0, 0, 0, 0, time.UTC)),      // Independence Day
+		nthWeekday(year, time.September, time.Monday, 1),                   // Labor Day
+		nthWeekday(year, time.October, time.Monday, 2),                     // Columbus Day
+		observed(time.Date(year, time.November, 11, 0, 0, 0, 0, time.UTC)), // Veterans Day
+		nthWeekday(year, time.November, time.Thursday, 4),                  // Thanksgiving Day
+		observed(time.Date(year, time.December, 25, 0, 0, 0, 0, time.UTC)), // Christmas Day
+	}
+
+	// Juneteenth National Independence Day has been a holiday since 2021
+	if year >= 2021 {
+		holidays = append(holidays, observed(time.Date(year, time.June, 19, 0, 0, 0, 0, time.UTC)))
+	}
+
+	return holidays
+}
+
+// observed returns the day a fixed date holiday is observed on
+func observed(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, -1)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+
+	return day
+}
+
+// nthWeekday returns the nth weekday of a month, counting from 1
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+
+	return first.AddDate(0, 0, offset+(n-1)*7)
+}
+
+// lastWeekday returns the last weekday of a month
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+
+	return last.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,257 @@
+// Package schedule works out the concrete dates an Account's bills fall due on
+// from its AccountType, DueDate and AnchorDate.
+package schedule
+
+import (
+	"dinero/api/models"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// DateFormat is the layout dates are parsed and written in
+const DateFormat = "2006-01-02"
+
+// slack is how far outside a requested range due dates are looked for, so that a
+// bill moved into the range by a weekend or holiday is still found
+const slack = 10
+
+var (
+	// ErrNoAnchor is returned for weekly, biweekly and yearly accounts without an
+	// AnchorDate, since their DueDate alone doesn't say when they're due
+	ErrNoAnchor = errors.New("schedule: account has no anchor date")
+	// ErrBadAccount is returned for accounts with an unknown AccountType or a DueDate
+	// that isn't a day of the month
+	ErrBadAccount = errors.New("schedule: account has an invalid type or due date")
+)
+
+// Adjustment is what happens to a due date that falls on a weekend or holiday
+type Adjustment int
+
+const (
+	// NextBusinessDay moves due dates forward to the next business day
+	NextBusinessDay Adjustment = iota
+	// PreviousBusinessDay moves due dates back to the business day before
+	PreviousBusinessDay
+	// NoAdjustment leaves due dates where they fall
+	NoAdjustment
+)
+
+// Options configures how due dates are adjusted
+type Options struct {
+	Adjustment Adjustment
+	// Holidays are skipped along with weekends. Nil only skips weekends.
+	Holidays Calendar
+}
+
+// Occurrence is one payment of a bill
+type Occurrence struct {
+	// Date is the day the payment is due, after adjusting for weekends and holidays
+	Date time.Time
+	// Nominal is the day the payment is due by the account's terms
+	Nominal time.Time
+	Amount  models.Money
+}
+
+// MarshalJSON writes the dates of an Occurrence as YYYY-MM-DD
+func (o Occurrence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date    string       `json:"date"`
+		Nominal string       `json:"nominalDate"`
+		Amount  models.Money `json:"amount"`
+	}{
+		Date:    o.Date.Format(DateFormat),
+		Nominal: o.Nominal.Format(DateFormat),
+		Amount:  o.Amount,
+	})
+}
+
+// Day returns midnight UTC on the calendar day of t
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ParseDate parses a YYYY-MM-DD date
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(DateFormat, s)
+}
+
+// Between returns the payments of account due from from to to, inclusive, in date
+// order. Payments are included by their adjusted date, and each one is for the
+// account's CurrentPayment.
+//
+// Daily bills are due every day. Weekly and biweekly bills are due every 7 or 14
+// days from the AnchorDate, which may be in the past or the future. Monthly bills are
+// due on the DueDate of every month, or the last day of months that are too short.
+// Yearly bills are due once a year on the DueDate of the AnchorDate's month.
+func Between(account *models.Account, from, to time.Time, opts Options) ([]Occurrence, error) {
+	from, to = Day(from), Day(to)
+
+	// Daily bills are due on weekends and holidays too, so they're never moved
+	adjustment := opts.Adjustment
+	if account.AccountType == "daily" {
+		adjustment = NoAdjustment
+	}
+
+	start, end := from, to
+	if adjustment != NoAdjustment {
+		start, end = from.AddDate(0, 0, -slack), to.AddDate(0, 0, slack)
+	}
+
+	nominals, err := nominalDates(account, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]Occurrence, 0, len(nominals))
+	for _, nominal := range nominals {
+		date := adjust(nominal, adjustment, opts.Holidays)
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+
+		occurrences = append(occurrences, Occurrence{Date: date, Nominal: nominal, Amount: account.CurrentPayment})
+	}
+
+	return occurrences, nil
+}
+
+// nominalDates lists the days from start to end, inclusive, that account is due on
+// by its terms
+func nominalDates(account *models.Account, start, end time.Time) ([]time.Time, error) {
+	dates := make([]time.Time, 0)
+	if end.Before(start) {
+		return dates, nil
+	}
+
+	switch account.AccountType {
+	case "daily":
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			dates = append(dates, day)
+		}
+		return dates, nil
+
+	case "weekly", "biweekly":
+		anchor, err := anchorDate(account)
+		if err != nil {
+			return nil, err
+		}
+
+		period := 7
+		if account.AccountType == "biweekly" {
+			period = 14
+		}
+
+		// Start from the first multiple of period days from the anchor on or after start
+		offset := daysBetween(anchor, start)
+		steps := offset / period
+		if offset%period > 0 {
+			steps++
+		}
+		for day := anchor.AddDate(0, 0, steps*period); !day.After(end); day = day.AddDate(0, 0, period) {
+			dates = append(dates, day)
+		}
+		return dates, nil
+	}
+
+	dueDay, err := dueDay(account)
+	if err != nil {
+		return nil, err
+	}
+
+	switch account.AccountType {
+	case "monthly":
+		for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(end); month = month.AddDate(0, 1, 0) {
+			day := onDay(month.Year(), month.Month(), dueDay)
+			if !day.Before(start) && !day.After(end) {
+				dates = append(dates, day)
+			}
+		}
+		return dates, nil
+
+	case "yearly":
+		anchor, err := anchorDate(account)
+		if err != nil {
+			return nil, err
+		}
+
+		for year := start.Year(); year <= end.Year(); year++ {
+			day := onDay(year, anchor.Month(), dueDay)
+			if !day.Before(start) && !day.After(end) {
+				dates = append(dates, day)
+			}
+		}
+		return dates, nil
+	}
+
+	return nil, ErrBadAccount
+}
+
+// anchorDate parses an account's AnchorDate
+func anchorDate(account *models.Account) (time.Time, error) {
+	if account.AnchorDate == "" {
+		return time.Time{}, ErrNoAnchor
+	}
+
+	anchor, err := ParseDate(account.AnchorDate)
+	if err != nil {
+		return time.Time{}, ErrBadAccount
+	}
+
+	return anchor, nil
+}
+
+// dueDay parses an account's DueDate
+func dueDay(account *models.Account) (int, error) {
+	day, err := strconv.Atoi(account.DueDate)
+	if err != nil || day < 1 || day > 31 {
+		return 0, ErrBadAccount
+	}
+
+	return day, nil
+}
+
+// onDay returns the given day of a month, or the month's last day if it's shorter
+func onDay(year int, month time.Month, day int) time.Time {
+	// Day 0 of the next month is the last day of this one
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		day = last
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of days from a to b, both at midnight UTC
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// IsBusinessDay reports whether day is a weekday that isn't one of holidays
+func IsBusinessDay(day time.Time, holidays Calendar) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+
+	return holidays == nil || !holidays.IsHoliday(day)
+}
+
+// adjust moves day off weekends and holidays in the direction of adjustment
+func adjust(day time.Time, adjustment Adjustment, holidays Calendar) time.Time {
+	step := 0
+	switch adjustment {
+	case NextBusinessDay:
+		step = 1
+	case PreviousBusinessDay:
+		step = -1
+	default:
+		return day
+	}
+
+	for !IsBusinessDay(day, holidays) {
+		day = day.AddDate(0, 0, step)
+	}
+
+	return day
+}
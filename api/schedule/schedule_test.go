@@ -0,0 +1,195 @@
+package schedule_test
+
+import (
+	"dinero/api/models"
+	"dinero/api/schedule"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dates formats the adjusted dates of occurrences as a comma separated list
+func dates(occurrences []schedule.Occurrence) string {
+	formatted := make([]string, len(occurrences))
+	for i, o := range occurrences {
+		formatted[i] = o.Date.Format(schedule.DateFormat)
+	}
+
+	return strings.Join(formatted, ",")
+}
+
+func date(s string) time.Time {
+	d, _ := schedule.ParseDate(s)
+	return d
+}
+
+func TestBetween(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		account  models.Account
+		from     string
+		to       string
+		opts     schedule.Options
+		expected string
+	}{
+		{
+			name:     "DAILY",
+			account:  models.Account{AccountType: "daily", DueDate: "1"},
+			from:     "2020-02-28",
+			to:       "2020-03-02",
+			expected: "2020-02-28,2020-02-29,2020-03-01,2020-03-02",
+		},
+		{
+			// every Friday from the anchor, which is after the range
+			name:     "WEEKLY",
+			account:  models.Account{AccountType: "weekly", DueDate: "1", AnchorDate: "2020-03-27"},
+			from:     "2020-03-01",
+			to:       "2020-03-21",
+			expected: "2020-03-06,2020-03-13,2020-03-20",
+		},
+		{
+			// every other Friday from the anchor, which is before the range
+			name:     "BIWEEKLY",
+			account:  models.Account{AccountType: "biweekly", DueDate: "1", AnchorDate: "2020-01-03"},
+			from:     "2020-02-01",
+			to:       "2020-03-31",
+			expected: "2020-02-14,2020-02-28,2020-03-13,2020-03-27",
+		},
+		{
+			// the 31st falls on the last day of short months, and Saturday February 29th
+			// and Sunday May 31st move to the Monday after
+			name:     "MONTHLY_31ST",
+			account:  models.Account{AccountType: "monthly", DueDate: "31"},
+			from:     "2020-01-01",
+			to:       "2020-06-30",
+			expected: "2020-01-31,2020-03-02,2020-03-31,2020-04-30,2020-06-01,2020-06-30",
+		},
+		{
+			// Saturday February 29th moves back to Friday instead
+			name:     "MONTHLY_PREVIOUS",
+			account:  models.Account{AccountType: "monthly", DueDate: "31"},
+			from:     "2020-02-01",
+			to:       "2020-02-29",
+			opts:     schedule.Options{Adjustment: schedule.PreviousBusinessDay},
+			expected: "2020-02-28",
+		},
+		{
+			// Sunday May 31st stays put
+			name:     "MONTHLY_UNADJUSTED",
+			account:  models.Account{AccountType: "monthly", DueDate: "31"},
+			from:     "2020-05-01",
+			to:       "2020-05-31",
+			opts:     schedule.Options{Adjustment: schedule.NoAdjustment},
+			expected: "2020-05-31",
+		},
+		{
+			// a bill moved into the range by a weekend is still due in it
+			name:     "MOVED_INTO_RANGE",
+			account:  models.Account{AccountType: "monthly", DueDate: "30"},
+			from:     "2020-06-01",
+			to:       "2020-06-10",
+			expected: "2020-06-01",
+		},
+		{
+			// July 4th 2020 is a Saturday, observed on Friday the 3rd, and the 6th is the next business day
+			name:     "HOLIDAY",
+			account:  models.Account{AccountType: "monthly", DueDate: "3"},
+			from:     "2020-07-01",
+			to:       "2020-07-31",
+			opts:     schedule.Options{Holidays: schedule.USFederal},
+			expected: "2020-07-06",
+		},
+		{
+			// New Year's Day 2022 is a Saturday, observed on December 31st 2021
+			name:     "HOLIDAY_PREVIOUS_YEAR",
+			account:  models.Account{AccountType: "monthly", DueDate: "31"},
+			from:     "2021-12-01",
+			to:       "2021-12-31",
+			opts:     schedule.Options{Adjustment: schedule.PreviousBusinessDay, Holidays: schedule.USFederal},
+			expected: "2021-12-30",
+		},
+		{
+			// February 29th falls on the 28th outside leap years
+			name:     "YEARLY",
+			account:  models.Account{AccountType: "yearly", DueDate: "29", AnchorDate: "2020-02-01"},
+			from:     "2020-01-01",
+			to:       "2023-12-31",
+			opts:     schedule.Options{Adjustment: schedule.NoAdjustment},
+			expected: "2020-02-29,2021-02-28,2022-02-28,2023-02-28",
+		},
+		{
+			name:     "EMPTY_RANGE",
+			account:  models.Account{AccountType: "monthly", DueDate: "1"},
+			from:     "2020-03-02",
+			to:       "2020-03-01",
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			occurrences, err := schedule.Between(&test.account, date(test.from), date(test.to), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := dates(occurrences); got != test.expected {
+				t.Errorf("\nDates:\n\tGot: \t\t%s\n\tExpected: \t%s\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestBetweenErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		account  models.Account
+		expected error
+	}{
+		{"NO_ANCHOR_WEEKLY", models.Account{AccountType: "weekly", DueDate: "1"}, schedule.ErrNoAnchor},
+		{"NO_ANCHOR_YEARLY", models.Account{AccountType: "yearly", DueDate: "1"}, schedule.ErrNoAnchor},
+		{"BAD_ANCHOR", models.Account{AccountType: "biweekly", DueDate: "1", AnchorDate: "soon"}, schedule.ErrBadAccount},
+		{"BAD_TYPE", models.Account{AccountType: "hourly", DueDate: "1"}, schedule.ErrBadAccount},
+		{"BAD_DUE_DATE", models.Account{AccountType: "monthly", DueDate: "32"}, schedule.ErrBadAccount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := schedule.Between(&test.account, date("2020-01-01"), date("2020-12-31"), schedule.Options{})
+			if err != test.expected {
+				t.Errorf("\nError:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestUSFederal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		day      string
+		expected bool
+	}{
+		{"2020-01-20", true},  // Martin Luther King, Jr. Day
+		{"2020-05-25", true},  // Memorial Day
+		{"2020-07-03", true},  // Independence Day, observed
+		{"2020-07-04", false}, // Independence Day, on a Saturday
+		{"2020-11-26", true},  // Thanksgiving Day
+		{"2020-06-19", false}, // Juneteenth, before it was a holiday
+		{"2022-06-20", true},  // Juneteenth, observed
+		{"2021-12-31", true},  // New Year's Day 2022, observed
+		{"2020-03-17", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.day, func(t *testing.T) {
+			if got := schedule.USFederal.IsHoliday(date(test.day)); got != test.expected {
+				t.Errorf("\nHoliday:\n\tGot: \t\t%v\n\tExpected: \t%v\n", got, test.expected)
+			}
+		})
+	}
+}
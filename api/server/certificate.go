@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// certificate is a TLS key pair that's loaded again when its files change, so a
+// renewed certificate is served without restarting Dinero
+type certificate struct {
+	certFile string
+	keyFile  string
+	log      *logrus.Logger
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+	checked  time.Time
+}
+
+// get returns the key pair to serve, first loading it again if it's been at
+// least interval since the files were checked and either has changed. If the
+// new files can't be loaded, such as when a renewal has replaced only one of
+// them so far, the old key pair is kept and the files are tried again later.
+func (c *certificate) get(interval time.Duration) *tls.Certificate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) >= interval {
+		if err := c.reload(); err != nil {
+			c.log.WithError(err).Warn("Keeping the current TLS certificate")
+		}
+	}
+
+	return c.cert
+}
+
+// reload loads the key pair if either file changed since it was last loaded
+func (c *certificate) reload() error {
+	c.checked = time.Now()
+
+	var modified time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	if c.cert != nil && modified.Equal(c.modified) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert, c.modified = &cert, modified
+	c.log.WithField("cert", c.certFile).Info("Loaded TLS certificate")
+
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package server runs the API over HTTP or HTTPS, with the timeouts and size
+// limits from the configuration, and shuts it down without dropping requests.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"dinero/api/config"
+	stdlog "log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Server serves a handler until its context is cancelled
+type Server struct {
+	// CertCheckInterval is how often the TLS certificate files are checked for
+	// changes. A changed certificate is served from the next check on.
+	CertCheckInterval time.Duration
+
+	http            *http.Server
+	shutdownTimeout time.Duration
+	cert            *certificate
+	log             *logrus.Logger
+}
+
+// New sets up a Server for handler. With TLS configured the certificate is
+// loaded straight away, so a bad key pair stops Dinero from starting.
+func New(cfg *config.Config, handler http.Handler, log *logrus.Logger) (*Server, error) {
+	s := &Server{
+		CertCheckInterval: 10 * time.Second,
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			ErrorLog:          stdlog.New(log.WriterLevel(logrus.WarnLevel), "", 0),
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+		log:             log,
+	}
+
+	if cfg.TLS() {
+		s.cert = &certificate{certFile: cfg.TLSCert, keyFile: cfg.TLSKey, log: log}
+		if err := s.cert.reload(); err != nil {
+			return nil, err
+		}
+		s.http.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return s.cert.get(s.CertCheckInterval), nil
+			},
+		}
+	}
+
+	return s, nil
+}
+
+// Run listens on the configured address and serves until ctx is cancelled, see Serve
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Serve serves the connections l accepts until ctx is cancelled. It then stops
+// accepting connections and waits up to the shutdown timeout for requests in
+// flight to finish, closing whatever is left after that. It returns nil once
+// every request has finished.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	errs := make(chan error, 1)
+	go func() {
+		if s.http.TLSConfig != nil {
+			errs <- s.http.ServeTLS(l, "", "")
+		} else {
+			errs <- s.http.Serve(l)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(shutdown); err != nil {
+		s.http.Close()
+		return err
+	}
+
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
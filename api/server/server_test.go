@@ -0,0 +1,226 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dinero/api/config"
+	"dinero/api/server"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// quiet is a logger that discards everything
+func quiet() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	return log
+}
+
+// listen returns a listener on a free local port
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// serve starts s on l in the background, returning a channel that gets what Serve returns
+func serve(ctx context.Context, s *server.Server, l net.Listener) chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, l) }()
+	return done
+}
+
+// slowHandler signals started when a request arrives and answers it once release is closed
+func slowHandler(started chan struct{}, release chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+}
+
+func TestServeDrains(t *testing.T) {
+	t.Parallel()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	s, err := server.New(config.Default(), slowHandler(started, release), quiet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := serve(ctx, s, l)
+
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+
+	// Shut down while the request is in flight
+	<-started
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Error("\nNew connections:\n\tGot: \t\taccepted\n\tExpected: \trefused\n")
+	}
+
+	close(release)
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("\nIn-flight request:\n\tGot: \t\t%d\n\tExpected: \t%d\n", got, http.StatusOK)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("\nServe:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, nil)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	s, err := server.New(cfg, slowHandler(started, release), quiet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := serve(ctx, s, l)
+	go http.Get("http://" + l.Addr().String())
+
+	<-started
+	cancel()
+	if err := <-done; err != context.DeadlineExceeded {
+		t.Errorf("\nServe:\n\tGot: \t\t%v\n\tExpected: \t%v\n", err, context.DeadlineExceeded)
+	}
+}
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 with the given
+// serial number and its key into dir
+func writeCertificate(t *testing.T, dir string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "dinero"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files written in quick succession can share a modification time
+	modified := time.Now().Add(time.Duration(serial) * time.Second)
+	os.Chtimes(certFile, modified, modified)
+	os.Chtimes(keyFile, modified, modified)
+
+	return certFile, keyFile
+}
+
+// servedSerial connects to addr over TLS and returns the serial number of the certificate it serves
+func servedSerial(t *testing.T, addr string) int64 {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestCertificateReload(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "dinero-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.TLSCert, cfg.TLSKey = writeCertificate(t, dir, 1)
+	s, err := server.New(cfg, http.NotFoundHandler(), quiet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CertCheckInterval = 0
+
+	l := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := serve(ctx, s, l)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if got := servedSerial(t, l.Addr().String()); got != 1 {
+		t.Errorf("\nSerial:\n\tGot: \t\t%d\n\tExpected: \t%d\n", got, 1)
+	}
+
+	// A renewed certificate is served without restarting
+	writeCertificate(t, dir, 2)
+	if got := servedSerial(t, l.Addr().String()); got != 2 {
+		t.Errorf("\nSerial after renewal:\n\tGot: \t\t%d\n\tExpected: \t%d\n", got, 2)
+	}
+
+	// A broken renewal keeps the last good certificate
+	ioutil.WriteFile(cfg.TLSKey, []byte("not a key"), 0600)
+	modified := time.Now().Add(3 * time.Second)
+	os.Chtimes(cfg.TLSKey, modified, modified)
+	if got := servedSerial(t, l.Addr().String()); got != 2 {
+		t.Errorf("\nSerial after broken renewal:\n\tGot: \t\t%d\n\tExpected: \t%d\n", got, 2)
+	}
+}
+
+func TestNewBadCertificate(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.TLSCert, cfg.TLSKey = "missing.pem", "missing-key.pem"
+	if _, err := server.New(cfg, http.NotFoundHandler(), quiet()); err == nil {
+		t.Error("\nNew:\n\tGot: \t\tno error\n\tExpected: \tmissing certificate error\n")
+	}
+}
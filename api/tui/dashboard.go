@@ -0,0 +1,506 @@
+package tui
+
+import (
+	"context"
+	"dinero/api/models"
+	"dinero/api/schedule"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// upcomingDays is how far ahead the dashboard lists due dates
+	upcomingDays = 30
+	// minWidth and minHeight are the smallest the dashboard is drawn, however
+	// small the terminal is
+	minWidth  = 60
+	minHeight = 8
+)
+
+// ANSI escape codes for styling text
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+)
+
+// pane is a list the dashboard can move through
+type pane int
+
+const (
+	usersPane pane = iota
+	accountsPane
+)
+
+// column is a field of the accounts table, which is edited in place
+type column struct {
+	title string
+	width int
+	get   func(a *models.Account) string
+	set   func(a *models.Account, s string) error
+}
+
+// moneyColumn is a column holding the amount field returns, which is typed in the
+// account's currency
+func moneyColumn(title string, field func(a *models.Account) *models.Money) column {
+	return column{
+		title: title,
+		width: 11,
+		get:   func(a *models.Account) string { return field(a).String() },
+		set: func(a *models.Account, s string) error {
+			m, err := models.ParseMoney(s)
+			if err != nil {
+				return errors.New("must be an amount like 1234.56")
+			}
+			*field(a) = models.NewMoney(m.Amount, a.Currency)
+			return nil
+		},
+	}
+}
+
+// columns are the account fields the dashboard shows, in order
+var columns = []column{
+	{
+		title: "NAME",
+		width: 18,
+		get:   func(a *models.Account) string { return a.Name },
+		set:   func(a *models.Account, s string) error { a.Name = s; return nil },
+	},
+	{
+		title: "TYPE",
+		width: 8,
+		get:   func(a *models.Account) string { return a.AccountType },
+		set:   func(a *models.Account, s string) error { a.AccountType = s; return nil },
+	},
+	{
+		title: "DUE",
+		width: 3,
+		get:   func(a *models.Account) string { return a.DueDate },
+		set:   func(a *models.Account, s string) error { a.DueDate = s; return nil },
+	},
+	moneyColumn("FULL AMOUNT", func(a *models.Account) *models.Money { return &a.FullAmount }),
+	moneyColumn("MINIMUM", func(a *models.Account) *models.Money { return &a.MinimumPayment }),
+	moneyColumn("PAYMENT", func(a *models.Account) *models.Money { return &a.CurrentPayment }),
+	{
+		title: "APR",
+		width: 6,
+		get:   func(a *models.Account) string { return a.APR.String() },
+		set: func(a *models.Account, s string) error {
+			r, err := models.ParseRate(s)
+			if err != nil {
+				return errors.New("must be a percentage like 24.99")
+			}
+			a.APR = r
+			return nil
+		},
+	},
+	{
+		title: "ANCHOR",
+		width: 10,
+		get:   func(a *models.Account) string { return a.AnchorDate },
+		set:   func(a *models.Account, s string) error { a.AnchorDate = s; return nil },
+	},
+}
+
+// belowMinimum reports whether the payment being made on an account doesn't
+// cover its minimum payment
+func belowMinimum(a *models.Account) bool {
+	return a.CurrentPayment.Cmp(a.MinimumPayment) < 0
+}
+
+// Dashboard lists users, the accounts of the selected user and when they're
+// next due, and edits accounts in place. It's driven by key presses and drawn
+// as lines of text, so it can run without a terminal.
+type Dashboard struct {
+	ctx   context.Context
+	store models.Store
+	now   func() time.Time
+
+	users    []*models.User
+	accounts []*models.Account
+	user     int
+	account  int
+	focus    pane
+
+	// editing is set while the selected account is being edited, with the
+	// changes so far in draft and the field being typed into in input
+	editing bool
+	draft   models.Account
+	field   int
+	input   string
+
+	status string
+	failed bool
+}
+
+// New returns a Dashboard on store, with the dates of today taken from now
+func New(ctx context.Context, store models.Store, now func() time.Time) *Dashboard {
+	return &Dashboard{ctx: ctx, store: store, now: now}
+}
+
+// Load reads the users, and the accounts of the selected user, from the store
+func (d *Dashboard) Load() error {
+	users, err := d.store.AllUsers(d.ctx)
+	if err != nil {
+		return err
+	}
+	d.users = users
+	d.user = clamp(d.user, len(d.users))
+
+	return d.loadAccounts()
+}
+
+// loadAccounts reads the accounts of the selected user from the store
+func (d *Dashboard) loadAccounts() error {
+	d.accounts = nil
+	if len(d.users) > 0 {
+		accounts, err := d.store.UserAccounts(d.ctx, d.users[d.user].ID)
+		if err != nil {
+			return err
+		}
+		d.accounts = accounts
+	}
+	d.account = clamp(d.account, len(d.accounts))
+
+	return nil
+}
+
+// clamp keeps an index into a list of n items inside it
+func clamp(i int, n int) int {
+	if i >= n {
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+// setStatus shows a message below the lists, in red if it's for an error
+func (d *Dashboard) setStatus(failed bool, format string, args ...interface{}) {
+	d.failed = failed
+	d.status = fmt.Sprintf(format, args...)
+}
+
+// setError shows err below the lists
+func (d *Dashboard) setError(err error) {
+	d.setStatus(true, "%s", strings.TrimPrefix(err.Error(), "error: "))
+}
+
+// Update handles a key press, and reports whether it quits the dashboard
+func (d *Dashboard) Update(k Key) bool {
+	if k.Code == KeyCtrlC {
+		return true
+	}
+	if d.editing {
+		d.updateEdit(k)
+		return false
+	}
+
+	switch {
+	case k.Code == KeyRune && k.Rune == 'q':
+		return true
+	case k.Code == KeyUp || k.Code == KeyRune && k.Rune == 'k':
+		d.move(-1)
+	case k.Code == KeyDown || k.Code == KeyRune && k.Rune == 'j':
+		d.move(1)
+	case k.Code == KeyLeft || k.Code == KeyRune && k.Rune == 'h':
+		d.focus = usersPane
+	case k.Code == KeyRight || k.Code == KeyRune && k.Rune == 'l':
+		d.focus = accountsPane
+	case k.Code == KeyTab:
+		d.focus = 1 - d.focus
+	case k.Code == KeyEnter || k.Code == KeyRune && k.Rune == 'e':
+		if d.focus == accountsPane && len(d.accounts) > 0 {
+			d.editing = true
+			d.draft = *d.accounts[d.account]
+			d.field = 0
+			d.input = columns[0].get(&d.draft)
+			d.setStatus(false, "Editing %s", d.draft.Name)
+		}
+	case k.Code == KeyRune && k.Rune == 'r':
+		if err := d.Load(); err != nil {
+			d.setError(err)
+		} else {
+			d.setStatus(false, "Reloaded")
+		}
+	}
+
+	return false
+}
+
+// move moves the selection in the focused list
+func (d *Dashboard) move(by int) {
+	if d.focus == accountsPane {
+		d.account = clamp(d.account+by, len(d.accounts))
+		return
+	}
+
+	user := clamp(d.user+by, len(d.users))
+	if user == d.user {
+		return
+	}
+	d.user, d.account = user, 0
+	if err := d.loadAccounts(); err != nil {
+		d.setError(err)
+	}
+}
+
+// updateEdit handles a key press while an account is being edited
+func (d *Dashboard) updateEdit(k Key) {
+	switch k.Code {
+	case KeyEscape:
+		d.editing = false
+		d.setStatus(false, "Discarded the changes to %s", d.accounts[d.account].Name)
+	case KeyTab, KeyRight:
+		if d.commitField() {
+			d.editField((d.field + 1) % len(columns))
+		}
+	case KeyBackTab, KeyLeft:
+		if d.commitField() {
+			d.editField((d.field + len(columns) - 1) % len(columns))
+		}
+	case KeyBackspace:
+		if d.input != "" {
+			_, size := utf8.DecodeLastRuneInString(d.input)
+			d.input = d.input[:len(d.input)-size]
+		}
+	case KeyRune:
+		d.input += string(k.Rune)
+	case KeyEnter:
+		if d.commitField() {
+			d.save()
+		}
+	}
+}
+
+// editField moves editing to the field at index
+func (d *Dashboard) editField(index int) {
+	d.field = index
+	d.input = columns[index].get(&d.draft)
+}
+
+// commitField copies the input into the draft, reporting whether it could be
+func (d *Dashboard) commitField() bool {
+	col := columns[d.field]
+	if err := col.set(&d.draft, strings.TrimSpace(d.input)); err != nil {
+		d.setStatus(true, "%s %s", strings.ToLower(col.title), err)
+		return false
+	}
+	return true
+}
+
+// save validates the draft and writes it to the store if it's valid. The write
+// fails if the account was changed by someone else since it was loaded.
+func (d *Dashboard) save() {
+	if err := d.draft.Validate(); err != nil {
+		d.setError(err)
+		return
+	}
+
+	err := d.store.UpdateAccount(d.ctx, d.draft.ID, &d.draft)
+	if err == models.ErrVersionConflict {
+		d.setStatus(true, "%s was changed elsewhere; press esc and r to reload it", d.draft.Name)
+		return
+	} else if err != nil {
+		d.setError(err)
+		return
+	}
+
+	d.editing = false
+	d.setStatus(false, "Saved %s", d.draft.Name)
+	if err = d.loadAccounts(); err != nil {
+		d.setError(err)
+	}
+}
+
+// due is one upcoming payment of an account
+type due struct {
+	date    time.Time
+	account *models.Account
+	amount  models.Money
+}
+
+// upcoming returns the payments of the selected user's accounts due in the next
+// upcomingDays days, sorted by day, along with the accounts that couldn't be
+// scheduled
+func (d *Dashboard) upcoming() ([]due, []*models.Account) {
+	from := schedule.Day(d.now())
+	to := from.AddDate(0, 0, upcomingDays)
+	opts := schedule.Options{Adjustment: schedule.NextBusinessDay, Holidays: schedule.USFederal}
+
+	var dues []due
+	var unscheduled []*models.Account
+	for _, account := range d.accounts {
+		occurrences, err := schedule.Between(account, from, to, opts)
+		if err != nil {
+			unscheduled = append(unscheduled, account)
+			continue
+		}
+		for _, o := range occurrences {
+			dues = append(dues, due{date: o.Date, account: account, amount: o.Amount})
+		}
+	}
+
+	sort.SliceStable(dues, func(i, j int) bool { return dues[i].date.Before(dues[j].date) })
+	return dues, unscheduled
+}
+
+// fit pads or cuts s to width characters
+func fit(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:width])
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// style wraps s in the ANSI codes, or leaves it alone without any
+func style(s string, codes ...string) string {
+	if len(codes) == 0 {
+		return s
+	}
+	return strings.Join(codes, "") + s + styleReset
+}
+
+// scroll returns the first of rows lines to show so the selected line is in view
+func scroll(selected int, rows int) int {
+	if selected < rows {
+		return 0
+	}
+	return selected - rows + 1
+}
+
+// accountRow lays out the cells of an account, with the field being edited
+// showing what's been typed
+func (d *Dashboard) accountRow(a *models.Account, editing bool) string {
+	cells := make([]string, len(columns))
+	for i, col := range columns {
+		value := col.get(a)
+		if editing && i == d.field {
+			value = d.input + "_"
+		}
+		cells[i] = fit(value, col.width)
+	}
+	return strings.Join(cells, "  ")
+}
+
+// View draws the dashboard as height lines of width characters, not counting
+// the ANSI codes styling them
+func (d *Dashboard) View(width int, height int) []string {
+	const usersWidth = 22
+	if width < minWidth {
+		width = minWidth
+	}
+	if height < minHeight {
+		height = minHeight
+	}
+	accountsWidth := width - usersWidth - 3
+
+	dues, unscheduled := d.upcoming()
+	upcomingRows := len(dues) + len(unscheduled)
+	if upcomingRows == 0 {
+		upcomingRows = 1
+	}
+	if max := height / 3; upcomingRows > max {
+		upcomingRows = max
+	}
+	// The title, the upcoming header, the status and the help take a line each
+	listRows := height - 4 - upcomingRows
+	if listRows < 2 {
+		listRows = 2
+	}
+
+	lines := make([]string, 0, height)
+	today := schedule.Day(d.now()).Format(schedule.DateFormat)
+	lines = append(lines, style(fit(" Dinero", width-len(today)-1)+today+" ", styleReverse))
+
+	// Users on the left and the selected user's accounts on the right
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = fit(col.title, col.width)
+	}
+	lines = append(lines, style(fit(" USERS", usersWidth), styleBold)+" │ "+style(fit("  "+strings.Join(header, "  "), accountsWidth), styleBold))
+
+	userStart, accountStart := scroll(d.user, listRows-1), scroll(d.account, listRows-1)
+	for row := 0; row < listRows-1; row++ {
+		left := fit("", usersWidth)
+		if i := userStart + row; i < len(d.users) {
+			var codes []string
+			if i == d.user {
+				codes = append(codes, styleReverse)
+				if d.focus != usersPane {
+					codes = []string{styleBold}
+				}
+			}
+			left = style(fit(" "+d.users[i].FullName, usersWidth), codes...)
+		}
+
+		right := fit("", accountsWidth)
+		if i := accountStart + row; i < len(d.accounts) {
+			a := d.accounts[i]
+			selected := i == d.account && d.focus == accountsPane
+			if selected && d.editing {
+				a = &d.draft
+			}
+			marker := "  "
+			var codes []string
+			if belowMinimum(a) {
+				marker = "! "
+				codes = append(codes, styleRed)
+			}
+			if selected {
+				codes = append(codes, styleReverse)
+			}
+			right = style(fit(marker+d.accountRow(a, selected && d.editing), accountsWidth), codes...)
+		}
+
+		lines = append(lines, left+" │ "+right)
+	}
+
+	// Upcoming payments, sorted by day
+	lines = append(lines, style(fit(fmt.Sprintf(" UPCOMING  next %d days", upcomingDays), width), styleBold, styleReverse))
+	switch {
+	case len(d.users) == 0:
+		lines = append(lines, fit(" No users yet", width))
+	case len(dues) == 0 && len(unscheduled) == 0:
+		lines = append(lines, fit(" Nothing due", width))
+	}
+	for i := 0; i < upcomingRows && i < len(dues)+len(unscheduled); i++ {
+		if i < len(dues) {
+			due := dues[i]
+			line := fit(fmt.Sprintf(" %s  %s  %11s", due.date.Format("Mon Jan 02"), fit(due.account.Name, 18), due.amount), width)
+			if belowMinimum(due.account) {
+				line = style(line, styleRed)
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		account := unscheduled[i-len(dues)]
+		lines = append(lines, style(fit(fmt.Sprintf(" %-10s  %s  needs an anchor date or a valid due date", "", fit(account.Name, 18)), width), styleDim))
+	}
+
+	// Pad out to the bottom two lines
+	for len(lines) < height-2 {
+		lines = append(lines, fit("", width))
+	}
+	lines = lines[:height-2]
+
+	status := fit(" "+d.status, width)
+	if d.failed {
+		status = style(status, styleRed)
+	}
+	help := " ↑↓ move  ←→ users/accounts  enter edit  r reload  q quit  ! payment below minimum"
+	if d.editing {
+		help = " type to change  tab/←→ next/previous field  enter save  esc discard"
+	}
+
+	return append(lines, status, style(fit(help, width), styleDim))
+}
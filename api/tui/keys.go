@@ -0,0 +1,151 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode is a key on the keyboard that isn't a character
+type KeyCode int
+
+const (
+	// KeyRune is a character key, in the Rune of the Key
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyEscape
+	KeyTab
+	KeyBackTab
+	KeyBackspace
+	KeyCtrlC
+)
+
+// Key is a key press
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// escapes are the sequences terminals send in raw mode for the keys that aren't
+// characters
+var escapes = map[string]KeyCode{
+	"\x1b[A": KeyUp,
+	"\x1b[B": KeyDown,
+	"\x1b[C": KeyRight,
+	"\x1b[D": KeyLeft,
+	"\x1bOA": KeyUp,
+	"\x1bOB": KeyDown,
+	"\x1bOC": KeyRight,
+	"\x1bOD": KeyLeft,
+	"\x1b[Z": KeyBackTab,
+}
+
+// ParseKeys splits what a terminal in raw mode sent into key presses. Escape
+// sequences it doesn't know, or that are cut off, are dropped, and an escape on
+// its own is the Escape key.
+func ParseKeys(b []byte) []Key {
+	keys, _ := parseKeys(b, true)
+	return keys
+}
+
+// KeyParser splits the reads from a terminal in raw mode into key presses. A
+// read can end partway through a key, like an escape sequence or a multibyte
+// character, so what's left over is kept and parsed with the next read.
+type KeyParser struct {
+	pending []byte
+}
+
+// Parse returns the keys in b, following on from what was left over from the
+// read before
+func (p *KeyParser) Parse(b []byte) []Key {
+	keys, rest := parseKeys(append(p.pending, b...), false)
+	p.pending = append([]byte(nil), rest...)
+	return keys
+}
+
+// Pending reports whether the last read ended partway through a key
+func (p *KeyParser) Pending() bool {
+	return len(p.pending) > 0
+}
+
+// Flush returns the keys in what was left over, once nothing more has been
+// read for a while: an escape on its own is the Escape key rather than the
+// start of a sequence
+func (p *KeyParser) Flush() []Key {
+	keys, _ := parseKeys(p.pending, true)
+	p.pending = nil
+	return keys
+}
+
+// parseKeys splits b into key presses. Unless final, it stops at a key that b
+// ends partway through, and returns what's left from there.
+func parseKeys(b []byte, final bool) ([]Key, []byte) {
+	var keys []Key
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			n, complete := sequenceLength(b)
+			if !complete && !final {
+				return keys, b
+			}
+			if n == 1 {
+				keys = append(keys, Key{Code: KeyEscape})
+			} else if code, ok := escapes[string(b[:n])]; ok {
+				keys = append(keys, Key{Code: code})
+			}
+			b = b[n:]
+			continue
+		}
+
+		switch b[0] {
+		case '\r', '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case '\t':
+			keys = append(keys, Key{Code: KeyTab})
+		case 0x7f, 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+		case 0x03:
+			keys = append(keys, Key{Code: KeyCtrlC})
+		default:
+			if !utf8.FullRune(b) && !final {
+				return keys, b
+			}
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' {
+				keys = append(keys, Key{Code: KeyRune, Rune: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+
+	return keys, nil
+}
+
+// sequenceLength returns the length of the escape sequence b starts with: a
+// CSI sequence like "\x1b[1;5A" up to its final letter, an SS3 sequence like
+// "\x1bOA", or 1 for an escape on its own. It also reports whether the sequence
+// is complete, which it isn't when b ends before its final letter, or right
+// after the escape.
+func sequenceLength(b []byte) (int, bool) {
+	if len(b) < 2 {
+		return 1, false
+	}
+
+	switch b[1] {
+	case 'O':
+		if len(b) < 3 {
+			return 2, false
+		}
+		return 3, true
+	case '[':
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1, true
+			}
+		}
+		return len(b), false
+	}
+
+	return 1, true
+}
@@ -0,0 +1,140 @@
+// Package tui is a full-screen terminal dashboard of users, their accounts and
+// when those are next due, which edits accounts in place.
+package tui
+
+import (
+	"context"
+	"dinero/api/models"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Escape codes that switch to a separate screen with the cursor hidden, and back
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	exitScreen  = "\x1b[?25h\x1b[?1049l"
+)
+
+// resizeCheck is how often the terminal size is checked while no keys are pressed
+const resizeCheck = 250 * time.Millisecond
+
+// escapeWait is how long to wait for the rest of a key that a read ended partway
+// through, after which an escape on its own is taken as the Escape key
+const escapeWait = 50 * time.Millisecond
+
+// ErrNotTerminal is returned by Run when its input isn't a terminal
+var ErrNotTerminal = errors.New("tui: input is not a terminal")
+
+// Run shows a Dashboard of store full-screen until q or Ctrl-C is pressed. in
+// must be a terminal, which is put in raw mode and restored before returning.
+func Run(ctx context.Context, store models.Store, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !terminal.IsTerminal(fd) {
+		return ErrNotTerminal
+	}
+
+	d := New(ctx, store, time.Now)
+	if err := d.Load(); err != nil {
+		return err
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+	io.WriteString(out, enterScreen)
+	defer io.WriteString(out, exitScreen)
+
+	// Reads block, so they happen on their own and are handed over. The reader
+	// stops when Run returns: straight away where in takes a read deadline, and
+	// otherwise after the read it's blocked in, with nothing waiting for it.
+	done := make(chan struct{})
+	reads, errs, stopped := readInput(in, done)
+	defer func() {
+		close(done)
+		if in.SetReadDeadline(time.Now()) == nil {
+			<-stopped
+			in.SetReadDeadline(time.Time{})
+		}
+	}()
+
+	ticker := time.NewTicker(resizeCheck)
+	defer ticker.Stop()
+
+	var parser KeyParser
+	var flush <-chan time.Time
+	var drawn string
+	for {
+		width, height, err := terminal.GetSize(fd)
+		if err != nil {
+			return err
+		}
+
+		// Only draw when something changed, so the screen doesn't flicker
+		screen := "\x1b[H" + strings.Join(d.View(width, height), "\r\n")
+		if screen != drawn {
+			if _, err = io.WriteString(out, screen); err != nil {
+				return err
+			}
+			drawn = screen
+		}
+
+		var pressed []Key
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-errs:
+			return err
+		case b := <-reads:
+			pressed = parser.Parse(b)
+			flush = nil
+			if parser.Pending() {
+				flush = time.After(escapeWait)
+			}
+		case <-flush:
+			pressed = parser.Flush()
+			flush = nil
+		case <-ticker.C:
+		}
+
+		for _, k := range pressed {
+			if d.Update(k) {
+				return nil
+			}
+		}
+	}
+}
+
+// readInput reads from in until a read fails or done is closed, handing over
+// what's read and then the error. stopped is closed once it has finished.
+func readInput(in io.Reader, done <-chan struct{}) (<-chan []byte, <-chan error, <-chan struct{}) {
+	reads := make(chan []byte)
+	errs := make(chan error, 1)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case reads <- append([]byte(nil), buf[:n]...):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return reads, errs, stopped
+}
@@ -0,0 +1,213 @@
+package tui_test
+
+import (
+	"context"
+	"dinero/api/models"
+	"dinero/api/tui"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ansi matches the escape codes styling the dashboard
+var ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// screen draws d and returns its lines without styling, trimmed on the right
+func screen(d *tui.Dashboard) []string {
+	lines := d.View(100, 20)
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(ansi.ReplaceAllString(line, ""), " ")
+	}
+	return lines
+}
+
+// find returns the first line of lines containing s, or -1
+func find(lines []string, s string) int {
+	for i, line := range lines {
+		if strings.Contains(line, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// press sends keys to d as a terminal would
+func press(d *tui.Dashboard, keys string) {
+	for _, k := range tui.ParseKeys([]byte(keys)) {
+		d.Update(k)
+	}
+}
+
+// seed returns a dashboard of a store with two users, the first of whom has
+// accounts due on the 20th, 5th and 12th of the month
+func seed(t *testing.T) (*tui.Dashboard, *models.MemoryStore) {
+	ctx := context.Background()
+	store := models.NewMemoryStore()
+	luke, _ := store.CreateUser(ctx, models.User{FirstName: "Luke", LastName: "Toth", FullName: "Luke Toth", Email: "lptoth55@gmail.com"})
+	store.CreateUser(ctx, models.User{FirstName: "John", LastName: "Ide", FullName: "John Ide", Email: "ide.johnc@gmail.com"})
+	store.CreateAccount(ctx, models.Account{UserID: luke.ID, Name: "Rent", AccountType: "monthly", MinimumPayment: models.USD(120000), CurrentPayment: models.USD(120000), FullAmount: models.USD(120000), DueDate: "20"})
+	store.CreateAccount(ctx, models.Account{UserID: luke.ID, Name: "Credit Card", AccountType: "monthly", MinimumPayment: models.USD(3500), CurrentPayment: models.USD(2000), FullAmount: models.USD(240000), DueDate: "5"})
+	store.CreateAccount(ctx, models.Account{UserID: luke.ID, Name: "Phone Payment", AccountType: "monthly", MinimumPayment: models.USD(4283), CurrentPayment: models.USD(10000), FullAmount: models.USD(72800), DueDate: "12"})
+
+	// The 5th and 12th of July 2020 are Sundays
+	now := func() time.Time { return time.Date(2020, 7, 1, 9, 0, 0, 0, time.UTC) }
+	d := tui.New(ctx, store, now)
+	if err := d.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	return d, store
+}
+
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []tui.Key
+	}{
+		{name: "ARROWS", input: "\x1b[A\x1b[B\x1bOC\x1b[D", expected: []tui.Key{{Code: tui.KeyUp}, {Code: tui.KeyDown}, {Code: tui.KeyRight}, {Code: tui.KeyLeft}}},
+		{name: "TEXT", input: "a1é", expected: []tui.Key{{Rune: 'a'}, {Rune: '1'}, {Rune: 'é'}}},
+		{name: "CONTROL", input: "\r\t\x1b[Z\x7f\x03", expected: []tui.Key{{Code: tui.KeyEnter}, {Code: tui.KeyTab}, {Code: tui.KeyBackTab}, {Code: tui.KeyBackspace}, {Code: tui.KeyCtrlC}}},
+		{name: "ESCAPE", input: "\x1b", expected: []tui.Key{{Code: tui.KeyEscape}}},
+		{name: "UNKNOWN_SEQUENCE", input: "\x1b[1;5Aq", expected: []tui.Key{{Rune: 'q'}}},
+	}
+
+	for _, test := range tests {
+		if got := tui.ParseKeys([]byte(test.input)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("\n%s\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestKeyParser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		reads    []string
+		expected []tui.Key
+		pending  bool
+	}{
+		{name: "WHOLE_READS", reads: []string{"a", "\x1b[A"}, expected: []tui.Key{{Rune: 'a'}, {Code: tui.KeyUp}}},
+		{name: "SPLIT_CSI", reads: []string{"q\x1b[", "B"}, expected: []tui.Key{{Rune: 'q'}, {Code: tui.KeyDown}}},
+		{name: "SPLIT_AFTER_ESCAPE", reads: []string{"\x1b", "OC"}, expected: []tui.Key{{Code: tui.KeyRight}}},
+		{name: "SPLIT_RUNE", reads: []string{"\xc3", "\xa9"}, expected: []tui.Key{{Rune: 'é'}}},
+		{name: "PENDING", reads: []string{"a\x1b[1;"}, expected: []tui.Key{{Rune: 'a'}}, pending: true},
+	}
+
+	for _, test := range tests {
+		var parser tui.KeyParser
+		var got []tui.Key
+		for _, read := range test.reads {
+			got = append(got, parser.Parse([]byte(read))...)
+		}
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("\n%s\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", test.name, got, test.expected)
+		}
+		if parser.Pending() != test.pending {
+			t.Errorf("\n%s pending:\n\tGot: \t\t%v\n\tExpected: \t%v\n", test.name, parser.Pending(), test.pending)
+		}
+	}
+
+	// An escape that nothing follows is the Escape key once it's flushed
+	var parser tui.KeyParser
+	if got := parser.Parse([]byte("\x1b")); len(got) != 0 {
+		t.Errorf("\nBefore flush:\n\tGot: \t\t%+v\n\tExpected: \tno keys yet\n", got)
+	}
+	if got, expected := parser.Flush(), []tui.Key{{Code: tui.KeyEscape}}; !reflect.DeepEqual(got, expected) || parser.Pending() {
+		t.Errorf("\nFlush:\n\tGot: \t\t%+v\n\tExpected: \t%+v\n", got, expected)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	t.Parallel()
+
+	d, _ := seed(t)
+	lines := screen(d)
+
+	if find(lines, "Luke Toth") < 0 || find(lines, "John Ide") < 0 {
+		t.Errorf("\nUsers:\n\tGot: \t\t%q\n\tExpected: \tLuke Toth and John Ide\n", lines)
+	}
+
+	// Due dates are listed by day rather than by account, moved off weekends
+	fifth, twelfth, twentieth := find(lines, "Mon Jul 06  Credit Card"), find(lines, "Mon Jul 13  Phone Payment"), find(lines, "Mon Jul 20  Rent")
+	if fifth < 0 || twelfth < 0 || twentieth < 0 || !(fifth < twelfth && twelfth < twentieth) {
+		t.Errorf("\nUpcoming:\n\tGot: \t\t%q\n\tExpected: \tCredit Card, Phone Payment then Rent\n", lines)
+	}
+
+	// The account paying less than its minimum is marked and drawn in red
+	raw := d.View(100, 20)
+	marked := find(lines, "! Credit Card")
+	if marked < 0 || !strings.Contains(raw[marked], "\x1b[31m! Credit Card") {
+		t.Errorf("\nBelow minimum:\n\tGot: \t\t%q\n\tExpected: \tCredit Card marked in red\n", raw)
+	}
+	if rent := find(lines, "  Rent"); rent < 0 || strings.Contains(raw[rent], "! Rent") {
+		t.Errorf("\nAbove minimum:\n\tGot: \t\t%q\n\tExpected: \tRent unmarked\n", lines)
+	}
+
+	// Moving to the second user shows their accounts, of which there are none
+	press(d, "\x1b[B")
+	if lines = screen(d); find(lines, "Rent") >= 0 || find(lines, "Nothing due") < 0 {
+		t.Errorf("\nSecond user:\n\tGot: \t\t%q\n\tExpected: \tno accounts\n", lines)
+	}
+}
+
+func TestDashboardEdit(t *testing.T) {
+	t.Parallel()
+
+	d, store := seed(t)
+	ctx := context.Background()
+
+	// Raise the credit card's payment above its minimum: select it, skip to the
+	// payment field and type over it
+	press(d, "\x1b[C\x1b[B\r\t\t\t\t\t"+strings.Repeat("\x7f", 10)+"50\r")
+	card, _ := store.GetAccount(ctx, 2)
+	if card.CurrentPayment != models.USD(5000) {
+		t.Errorf("\nSaved payment:\n\tGot: \t\t%s\n\tExpected: \t%s\n", card.CurrentPayment, models.USD(5000))
+	}
+	if lines := screen(d); find(lines, "Saved Credit Card") < 0 || find(lines, "! Credit Card") >= 0 {
+		t.Errorf("\nAfter saving:\n\tGot: \t\t%q\n\tExpected: \tsaved and no longer marked\n", lines)
+	}
+
+	// Edits that fail Account.Validate aren't saved
+	press(d, "\r\t"+strings.Repeat("\x7f", 10)+"fortnightly\r")
+	if lines := screen(d); find(lines, "accountType must be one of") < 0 {
+		t.Errorf("\nInvalid edit:\n\tGot: \t\t%q\n\tExpected: \tthe validation error\n", lines)
+	}
+	if card, _ = store.GetAccount(ctx, 2); card.AccountType != "monthly" {
+		t.Errorf("\nInvalid edit saved:\n\tGot: \t\t%s\n\tExpected: \t%s\n", card.AccountType, "monthly")
+	}
+
+	// Escape discards the draft
+	press(d, "\x1b")
+	if lines := screen(d); find(lines, "Discarded the changes to Credit Card") < 0 || find(lines, "fortnightly") >= 0 {
+		t.Errorf("\nDiscarded edit:\n\tGot: \t\t%q\n\tExpected: \tthe draft discarded\n", lines)
+	}
+
+	// Amounts that don't parse stay in the field being edited
+	press(d, "\r\t\t\t\x7f\x7fx\t")
+	if lines := screen(d); find(lines, "full amount must be an amount like 1234.56") < 0 {
+		t.Errorf("\nBad amount:\n\tGot: \t\t%q\n\tExpected: \tthe parse error\n", lines)
+	}
+	press(d, "\x1b")
+
+	// An account changed elsewhere since it was loaded isn't overwritten
+	press(d, "\r")
+	changed := *card
+	changed.Name = "Visa"
+	if err := store.UpdateAccount(ctx, 2, &changed); err != nil {
+		t.Fatal(err)
+	}
+	press(d, "\r")
+	if lines := screen(d); find(lines, "Credit Card was changed elsewhere") < 0 {
+		t.Errorf("\nConflicting edit:\n\tGot: \t\t%q\n\tExpected: \tthe conflict\n", lines)
+	}
+	if card, _ = store.GetAccount(ctx, 2); card.Name != "Visa" {
+		t.Errorf("\nConflicting edit saved:\n\tGot: \t\t%s\n\tExpected: \t%s\n", card.Name, "Visa")
+	}
+}
@@ -0,0 +1,33 @@
+// Builds the app in src into dist for the web package to embed. Run it with
+// "go generate ./web" after changing src.
+const crypto = require('crypto');
+const fs = require('fs');
+const path = require('path');
+const zlib = require('zlib');
+
+const src = path.join(__dirname, 'src');
+const dist = path.join(__dirname, 'dist');
+
+fs.rmSync(dist, { recursive: true, force: true });
+fs.mkdirSync(path.join(dist, 'assets'), { recursive: true });
+
+// Name each asset with a hash of its content, so it can be cached for good
+let index = fs.readFileSync(path.join(src, 'index.html'), 'utf8');
+for (const name of ['app.js', 'app.css']) {
+  const content = fs.readFileSync(path.join(src, name));
+  const hash = crypto.createHash('sha256').update(content).digest('hex').slice(0, 8);
+  const ext = path.extname(name);
+  const hashed = `assets/${path.basename(name, ext)}.${hash}${ext}`;
+  fs.writeFileSync(path.join(dist, hashed), content);
+  index = index.split(`/${name}`).join(`/${hashed}`);
+}
+fs.writeFileSync(path.join(dist, 'index.html'), index);
+
+// Compress every file ahead of time, so the server never has to
+for (const name of ['index.html', ...fs.readdirSync(path.join(dist, 'assets')).map((f) => `assets/${f}`)]) {
+  const content = fs.readFileSync(path.join(dist, name));
+  fs.writeFileSync(path.join(dist, `${name}.br`), zlib.brotliCompressSync(content, {
+    params: { [zlib.constants.BROTLI_PARAM_QUALITY]: zlib.constants.BROTLI_MAX_QUALITY },
+  }));
+  fs.writeFileSync(path.join(dist, `${name}.gz`), zlib.gzipSync(content, { level: 9 }));
+}
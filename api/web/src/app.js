@@ -0,0 +1,168 @@
+// Dinero's web UI: log in, list your accounts and look at one. The server
+// answers every path that isn't a file with this app, so it routes them here.
+(function () {
+  'use strict';
+
+  var api = '/api/v1';
+  var app = document.getElementById('app');
+  var logout = document.getElementById('logout');
+
+  // money formats an amount of an account in the account's currency
+  function money(account, amount) {
+    return new Intl.NumberFormat('en-US', { style: 'currency', currency: account.currency || 'USD' }).format(amount);
+  }
+
+  // request calls the API, sending the session cookie, and resolves with the
+  // decoded body. Without a session it goes to the login page.
+  function request(method, path, body) {
+    var init = { method: method, credentials: 'same-origin', headers: {} };
+    if (body !== undefined) {
+      init.headers['Content-Type'] = 'application/json';
+      init.body = JSON.stringify(body);
+    }
+
+    return fetch(api + path, init).then(function (res) {
+      if (res.status === 401 && path !== '/auth/login') {
+        navigate('/login');
+        throw new Error('Log in to continue');
+      }
+      if (res.status === 204) {
+        return null;
+      }
+      return res.json().then(function (data) {
+        if (!res.ok) {
+          throw new Error(data.error ? data.error.message : res.statusText);
+        }
+        return data;
+      });
+    });
+  }
+
+  // el makes an element with the given attributes and children
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (name) {
+      node.setAttribute(name, attrs[name]);
+    });
+    (children || []).forEach(function (child) {
+      node.append(child);
+    });
+    return node;
+  }
+
+  function render(node) {
+    app.replaceChildren(node);
+  }
+
+  function showError(err) {
+    render(el('p', { class: 'error' }, [err.message]));
+  }
+
+  function login() {
+    logout.hidden = true;
+    var email = el('input', { type: 'email', name: 'email', placeholder: 'Email', required: '' });
+    var password = el('input', { type: 'password', name: 'password', placeholder: 'Password', required: '' });
+    var message = el('p', { class: 'error' });
+    var form = el('form', {}, [el('h1', {}, ['Log in']), email, password, el('button', { type: 'submit' }, ['Log in']), message]);
+
+    form.addEventListener('submit', function (event) {
+      event.preventDefault();
+      request('POST', '/auth/login', { email: email.value, password: password.value })
+        .then(function () {
+          navigate('/');
+        })
+        .catch(function (err) {
+          message.textContent = err.message;
+        });
+    });
+
+    render(form);
+  }
+
+  function accounts() {
+    request('GET', '/accounts').then(function (list) {
+      logout.hidden = false;
+      var rows = list.map(function (account) {
+        var row = el('tr', { class: account.currentPayment < account.minimumPayment ? 'short' : '' }, [
+          el('td', {}, [account.name]),
+          el('td', {}, [account.accountType]),
+          el('td', { class: 'money' }, [money(account, account.fullAmount)]),
+          el('td', { class: 'money' }, [money(account, account.currentPayment)]),
+          el('td', {}, [account.dueDate]),
+        ]);
+        row.addEventListener('click', function () {
+          navigate('/accounts/' + account.ID);
+        });
+        return row;
+      });
+
+      var head = el('tr', {}, ['Name', 'Type', 'Amount', 'Payment', 'Due'].map(function (title) {
+        return el('th', {}, [title]);
+      }));
+
+      render(el('section', {}, [
+        el('h1', {}, ['Accounts']),
+        rows.length ? el('table', {}, [el('thead', {}, [head]), el('tbody', {}, rows)]) : el('p', {}, ['No accounts yet.']),
+      ]));
+    }).catch(showError);
+  }
+
+  function account(id) {
+    request('GET', '/accounts/' + id).then(function (account) {
+      logout.hidden = false;
+      var fields = [
+        ['Type', account.accountType],
+        ['Full amount', money(account, account.fullAmount)],
+        ['Minimum payment', money(account, account.minimumPayment)],
+        ['Current payment', money(account, account.currentPayment)],
+        ['APR', account.apr + '%'],
+        ['Due', account.dueDate],
+        ['Website', account.URL],
+      ];
+
+      var list = el('dl');
+      fields.forEach(function (field) {
+        list.append(el('dt', {}, [field[0]]), el('dd', {}, [field[1] || '']));
+      });
+
+      render(el('section', {}, [el('a', { href: '/' }, ['← Accounts']), el('h1', {}, [account.name]), list]));
+    }).catch(showError);
+  }
+
+  function route() {
+    var path = location.pathname;
+    var match = path.match(/^\/accounts\/(\d+)$/);
+    if (path === '/login') {
+      login();
+    } else if (path === '/' || path === '/accounts') {
+      accounts();
+    } else if (match) {
+      account(match[1]);
+    } else {
+      render(el('p', {}, ['Page not found.']));
+    }
+  }
+
+  function navigate(path) {
+    history.pushState(null, '', path);
+    route();
+  }
+
+  // Follow links within the app without reloading the page
+  document.addEventListener('click', function (event) {
+    var link = event.target.closest('a');
+    if (link && link.origin === location.origin && !link.hasAttribute('data-external')) {
+      event.preventDefault();
+      navigate(link.pathname);
+    }
+  });
+
+  logout.addEventListener('click', function () {
+    request('POST', '/auth/logout').then(function () {
+      navigate('/login');
+    });
+  });
+
+  window.addEventListener('popstate', route);
+  route();
+})();
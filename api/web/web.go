@@ -0,0 +1,23 @@
+// Package web is the Dinero web UI, built into the dinero binary. The app is
+// in src, and build.js builds it into dist: the assets named with a hash of
+// their content, and each file compressed with brotli and gzip ahead of time.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:generate node build.js
+
+//go:embed dist
+var dist embed.FS
+
+// Dist returns the built web UI, with index.html at its root
+func Dist() fs.FS {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}